@@ -0,0 +1,144 @@
+package generate
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"shogunc/internal/types"
+	"slices"
+	"strings"
+)
+
+// ErrMsg is an error rendered with its position and source excerpt
+type ErrMsg string
+
+func (m ErrMsg) Error() string {
+	return string(m)
+}
+
+// ErrorLogger collects the errors and warnings found across every file and
+// renders them against the source they came from
+type ErrorLogger struct {
+	sources     map[string][]string // file -> lines
+	diagnostics []*types.Diagnostic
+}
+
+func NewErrorLogger() *ErrorLogger {
+	return &ErrorLogger{sources: make(map[string][]string)}
+}
+
+func (e *ErrorLogger) AddSource(file string, contents []byte) {
+	e.sources[file] = strings.Split(string(contents), "\n")
+}
+
+// Report records err as an error, joined errors are recorded one by one
+func (e *ErrorLogger) Report(err error) {
+	if err == nil {
+		return
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			e.Report(err)
+		}
+		return
+	}
+
+	var diag *types.Diagnostic
+	if !errors.As(err, &diag) {
+		diag = &types.Diagnostic{Msg: err.Error()}
+	}
+	e.diagnostics = append(e.diagnostics, diag)
+}
+
+func (e *ErrorLogger) Warnf(span types.Span, format string, args ...any) {
+	e.diagnostics = append(e.diagnostics, types.Warnf(span, format, args...))
+}
+
+func (e *ErrorLogger) Diagnostics() []*types.Diagnostic {
+	return e.diagnostics
+}
+
+func (e *ErrorLogger) HasErrors() bool {
+	for _, diag := range e.diagnostics {
+		if diag.Severity == types.SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err renders every diagnostic followed by a summary, or nil when nothing
+// was reported
+func (e *ErrorLogger) Err() error {
+	if len(e.diagnostics) == 0 {
+		return nil
+	}
+
+	// Group by file in the order files were reported, then by position
+	fileOrder := make(map[string]int)
+	for _, diag := range e.diagnostics {
+		if _, ok := fileOrder[diag.Pos.File]; !ok {
+			fileOrder[diag.Pos.File] = len(fileOrder)
+		}
+	}
+	sorted := slices.Clone(e.diagnostics)
+	slices.SortStableFunc(sorted, func(a, b *types.Diagnostic) int {
+		if c := cmp.Compare(fileOrder[a.Pos.File], fileOrder[b.Pos.File]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Pos.Offset, b.Pos.Offset)
+	})
+
+	var errCount, warnCount int
+	var sb strings.Builder
+	for _, diag := range sorted {
+		if diag.Severity == types.SeverityWarning {
+			warnCount++
+		} else {
+			errCount++
+		}
+		sb.WriteString(string(e.Format(diag)))
+		sb.WriteString("\n")
+	}
+	sb.WriteString(fmt.Sprintf("%d error(s), %d warning(s)", errCount, warnCount))
+
+	return ErrMsg(sb.String())
+}
+
+// Format renders diag as file:line:col: severity: message followed by the
+// offending source line with the span underlined
+func (e *ErrorLogger) Format(diag *types.Diagnostic) ErrMsg {
+	var sb strings.Builder
+	if loc := diag.Pos.String(); loc != "" {
+		sb.WriteString(loc + ": ")
+	}
+	sb.WriteString(fmt.Sprintf("%s: %s", diag.Severity, diag.Msg))
+
+	lines := e.sources[diag.Pos.File]
+	if !diag.Pos.IsValid() || diag.Pos.Line > len(lines) {
+		return ErrMsg(sb.String())
+	}
+	line := strings.TrimRight(lines[diag.Pos.Line-1], "\r")
+	gutter := fmt.Sprintf("%d", diag.Pos.Line)
+	sb.WriteString(fmt.Sprintf("\n %s | %s\n", gutter, line))
+	sb.WriteString(fmt.Sprintf(" %s | ", strings.Repeat(" ", len(gutter))))
+
+	// Keep tabs so the caret lines up with the source, columns count runes
+	runes := []rune(line)
+	start := min(diag.Pos.Column-1, len(runes))
+	for _, ch := range runes[:start] {
+		if ch == '\t' {
+			sb.WriteRune('\t')
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+
+	width := 1
+	if diag.End.Line == diag.Pos.Line && diag.End.Column > diag.Pos.Column {
+		width = diag.End.Column - diag.Pos.Column
+	}
+	sb.WriteString(strings.Repeat("^", width))
+
+	return ErrMsg(sb.String())
+}
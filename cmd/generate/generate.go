@@ -2,10 +2,12 @@ package generate
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"go/format"
 	"go/token"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -13,18 +15,11 @@ import (
 	"shogunc/internal/parser"
 	"shogunc/internal/types"
 	"strings"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
 )
 
-// Note: Debug feature
-type ErrMsg string
-
-type ErrorLogger struct {
-	ErrMsg   ErrMsg
-	Position int
-}
-
 type Driver string
 
 const (
@@ -54,6 +49,7 @@ type Generator struct {
 	Imports     []string
 	TagRegex    *regexp.Regexp
 	OutputCache *strings.Builder
+	ErrorLogger *ErrorLogger
 }
 
 func NewGenerator() *Generator {
@@ -72,10 +68,26 @@ func NewGenerator() *Generator {
 		Imports:     []string{"context", "time"},
 		TagRegex:    regexp.MustCompile(`--\s*name:\s*(\w+)\s*:(\w+)`),
 		OutputCache: &strings.Builder{},
+		ErrorLogger: NewErrorLogger(),
 	}
 }
 
+// Execute generates code for the schema and every query file. Problems are
+// collected along the way and reported together at the end, it fails when
+// any of them is an error. Warnings alone are printed to stderr.
 func (g *Generator) Execute(cwd string) error {
+	g.ErrorLogger.Report(g.execute(cwd))
+	if g.ErrorLogger.HasErrors() {
+		return g.ErrorLogger.Err()
+	}
+
+	if warnings := g.ErrorLogger.Err(); warnings != nil {
+		fmt.Fprintln(os.Stderr, warnings)
+	}
+	return nil
+}
+
+func (g *Generator) execute(cwd string) error {
 	// In development mode, try to change to ./tmp/ directory
 	if os.Getenv("DEVELOPMENT") == "true" {
 		tmpDir := filepath.Join(cwd, "tmp")
@@ -146,17 +158,18 @@ func (g *Generator) loadConfig() error {
 	return nil
 }
 
+// LoadSchema parses the schema and generates its types. Problems in the schema
+// are reported to the ErrorLogger, only failures that stop generation are returned.
 func (g *Generator) LoadSchema() error {
 	fileContents, err := os.ReadFile(g.Config.Sql.Schema)
 	if err != nil {
 		return err
 	}
+	g.ErrorLogger.AddSource(g.Config.Sql.Schema, fileContents)
 
-	lexer := parser.NewLexer(string(fileContents))
-	ast := parser.NewAst(lexer)
-	if err := ast.ParseSchema(); err != nil {
-		return err
-	}
+	lexer := parser.NewLexerAt(string(fileContents), types.Position{File: g.Config.Sql.Schema, Line: 1, Column: 1})
+	ast := parser.NewAst(lexer).SetDialect(parser.DialectFor(string(g.Config.Sql.Driver)))
+	g.ErrorLogger.Report(ast.ParseSchema())
 
 	var genContent strings.Builder
 
@@ -179,7 +192,8 @@ func (g *Generator) LoadSchema() error {
 
 			selectableType, err := codegen.GenerateTableType(t)
 			if err != nil {
-				return fmt.Errorf("[GENERATE] failed generating table type %v", err)
+				g.ErrorLogger.Report(err)
+				continue
 			}
 
 			var buf strings.Builder
@@ -190,7 +204,8 @@ func (g *Generator) LoadSchema() error {
 
 			insertableType, err := codegen.GenerateInsertableTableType(t)
 			if err != nil {
-				return fmt.Errorf("[GENERATE] failed generating insertable table type %v", err)
+				g.ErrorLogger.Report(err)
+				continue
 			}
 			buf.Reset()
 
@@ -205,7 +220,8 @@ func (g *Generator) LoadSchema() error {
 			}
 			typeDecl, constDecl, err := codegen.GenerateEnumType(t)
 			if err != nil {
-				return fmt.Errorf("[GENERATE] failed generating enum type %v", err)
+				g.ErrorLogger.Report(err)
+				continue
 			}
 
 			var buf strings.Builder
@@ -232,6 +248,8 @@ func (g *Generator) LoadSchema() error {
 	return nil
 }
 
+// LoadSqlFiles generates code for every query file. Bad queries are reported
+// to the ErrorLogger and skipped, only failures that stop generation are returned.
 func (g *Generator) LoadSqlFiles() error {
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -256,73 +274,46 @@ func (g *Generator) LoadSqlFiles() error {
 		}
 
 		fullPath := filepath.Join(directory, entry.Name())
-		file, err := os.Open(fullPath)
+		contents, err := os.ReadFile(fullPath)
 		if err != nil {
 			return fmt.Errorf("[GENERATE] failed to open %s: %v", fullPath, err)
 		}
-		defer file.Close()
+		// Report positions relative to the config so they match what users wrote
+		displayPath := filepath.Join(g.Config.Sql.Queries, entry.Name())
+		g.ErrorLogger.AddSource(displayPath, contents)
 
-		err = g.parseSqlFile(file, entry.Name())
+		err = g.parseSqlFile(contents, displayPath)
 		if err != nil {
-			return fmt.Errorf("[GENERATE] failed to parse %s: %v", fullPath, err)
+			return fmt.Errorf("[GENERATE] failed to parse %s: %w", fullPath, err)
 		}
 	}
 	return nil
 }
 
-func (g *Generator) parseSqlFile(file *os.File, fileName string) error {
-	queryBlocks, err := g.extractSqlBlocks(file, file.Name())
-	if err != nil {
-		return err
-	}
+func (g *Generator) parseSqlFile(contents []byte, fileName string) error {
+	queryBlocks, err := g.extractSqlBlocks(bytes.NewReader(contents), fileName)
+	g.ErrorLogger.Report(err)
+	failed := err != nil
 
 	var genContent strings.Builder
+	imports := make(map[string]bool)
 	for _, qb := range queryBlocks {
-		lexer := parser.NewLexer(qb.SQL)
-		ast := parser.NewAst(lexer)
-		if err := ast.Parse(); err != nil {
-			return fmt.Errorf("[GENERATE] failed parsing %s: %w", qb.Name, err)
-		}
-
-		dataType := g.inferDataType(qb.SQL)
-		if dataType == nil {
-			return fmt.Errorf("[GENERATE] failed infering type for %s\n SQL: %s", qb.Name, qb.SQL)
-		}
-
-		if len(ast.Statements) == 0 {
-			return errors.New("[GENERATE] no SQL statements to parse")
-		}
-
-		funcGen := codegen.NewGoGenerator(g.Types, &qb)
-		for _, statement := range ast.Statements {
-			funcDecl, paramStruct, err := funcGen.Generate(statement)
-			if err != nil {
-				return err
-			}
-
-			// Convert AST nodes to Go code strings
-			if paramStruct != nil {
-				var buf strings.Builder
-				if err := format.Node(&buf, token.NewFileSet(), paramStruct); err != nil {
-					return fmt.Errorf("failed to format param struct: %w", err)
-				}
-				genContent.WriteString(buf.String() + "\n\n")
-			}
-			if funcDecl != nil {
-				var buf strings.Builder
-				if err := format.Node(&buf, token.NewFileSet(), funcDecl); err != nil {
-					return fmt.Errorf("failed to format function: %w", err)
-				}
-				genContent.WriteString(buf.String() + "\n\n")
-			}
+		// A bad query is reported and generation resumes at the next -- name: tag
+		if err := g.generateQuery(&qb, &genContent, imports); err != nil {
+			g.ErrorLogger.Report(err)
+			failed = true
 		}
 	}
+	if failed {
+		return nil
+	}
 	if genContent.String() == "" {
-		return errors.New("[GENERATE] failed generating code")
+		g.ErrorLogger.Warnf(types.Span{Pos: types.Position{File: fileName}}, "[GENERATE] no queries found, expected -- name: <Name> :<type> tags")
+		return nil
 	}
 
 	// Generate separate file for this SQL file
-	outputFileName := strings.TrimSuffix(fileName, ".sql") + ".sql.go"
+	outputFileName := strings.TrimSuffix(filepath.Base(fileName), ".sql") + ".sql.go"
 	outputPath := filepath.Join(g.Config.Sql.Output, outputFileName)
 
 	// Create package header
@@ -335,6 +326,10 @@ func (g *Generator) parseSqlFile(file *os.File, fileName string) error {
 	// For SQL query files, include necessary imports
 	fullContent.WriteString("import (\n")
 	fullContent.WriteString(fmt.Sprintf("\t%q\n", "context"))
+	// Slice params are expanded with strings.Replace on sqlite
+	if imports["strings"] {
+		fullContent.WriteString(fmt.Sprintf("\t%q\n", "strings"))
+	}
 	fullContent.WriteString(fmt.Sprintf("\t%q\n", "time"))
 	fullContent.WriteString(")\n\n")
 
@@ -348,35 +343,143 @@ func (g *Generator) parseSqlFile(file *os.File, fileName string) error {
 	return os.WriteFile(outputPath, []byte(fullContent.String()), 0644)
 }
 
-func (g *Generator) extractSqlBlocks(file *os.File, fileName string) ([]types.QueryBlock, error) {
+// generateQuery writes the code of one query block to genContent and adds
+// the packages it uses to imports
+func (g *Generator) generateQuery(qb *types.QueryBlock, genContent *strings.Builder, imports map[string]bool) error {
+	lexer := parser.NewLexerAt(qb.SQL, qb.Pos)
+	ast := parser.NewAst(lexer).SetDialect(parser.DialectFor(string(g.Config.Sql.Driver)))
+	if err := ast.Parse(); err != nil {
+		return err
+	}
+
+	if len(ast.Statements) == 0 {
+		return types.Errorf(types.Span{Pos: qb.Pos}, "[GENERATE] no SQL statements to parse")
+	}
+
+	if table, span, ok := statementTable(ast.Statements[0]); ok {
+		if _, exists := g.Types[table]; !exists {
+			return types.Errorf(span, "[GENERATE] failed infering type for %s, table %s not found in schema", qb.Name, table)
+		}
+	}
+
+	funcGen := codegen.NewGoGenerator(g.Types, qb).SetDialect(parser.DialectFor(string(g.Config.Sql.Driver)))
+	for _, statement := range ast.Statements {
+		rowStruct, err := funcGen.GenerateRowStruct(statement)
+		if err != nil {
+			return err
+		}
+		funcDecl, paramStruct, err := funcGen.Generate(statement)
+		if err != nil {
+			return err
+		}
+
+		// Convert AST nodes to Go code strings
+		if rowStruct != nil {
+			var buf strings.Builder
+			if err := format.Node(&buf, token.NewFileSet(), rowStruct); err != nil {
+				return fmt.Errorf("failed to format row struct: %w", err)
+			}
+			genContent.WriteString(buf.String() + "\n\n")
+		}
+		if paramStruct != nil {
+			var buf strings.Builder
+			if err := format.Node(&buf, token.NewFileSet(), paramStruct); err != nil {
+				return fmt.Errorf("failed to format param struct: %w", err)
+			}
+			genContent.WriteString(buf.String() + "\n\n")
+		}
+		if funcDecl != nil {
+			var buf strings.Builder
+			if err := format.Node(&buf, token.NewFileSet(), funcDecl); err != nil {
+				return fmt.Errorf("failed to format function: %w", err)
+			}
+			genContent.WriteString(buf.String() + "\n\n")
+		}
+	}
+	for _, pkg := range funcGen.Imports() {
+		imports[pkg] = true
+	}
+
+	return nil
+}
+
+// extractSqlBlocks splits a query file on its -- name: tags. Bad tags are
+// collected into the returned error and their SQL is skipped.
+func (g *Generator) extractSqlBlocks(file io.Reader, fileName string) ([]types.QueryBlock, error) {
 	scanner := bufio.NewScanner(file)
 	var blocks []types.QueryBlock
+	var errs []error
+	warnedUntagged := false
 
 	var current *types.QueryBlock
 	var sqlBuilder strings.Builder
+	lineNum, offset := 0, 0
 
 	// TODO: clean this logic up
 	for scanner.Scan() {
 		line := scanner.Text()
+		lineNum++
+		lineStart := types.Position{File: fileName, Line: lineNum, Column: 1, Offset: offset}
+		offset += len(line) + 1
+
 		// Match on shogunc tag
-		if matches := g.TagRegex.FindStringSubmatch(line); matches != nil {
+		if matches := g.TagRegex.FindStringSubmatchIndex(line); matches != nil {
 			if current != nil {
 				current.SQL = sqlBuilder.String()
 				blocks = append(blocks, *current)
-				sqlBuilder.Reset()
 			}
-			// Initialize Tag with name & type
+			current = nil
+			sqlBuilder.Reset()
+
+			name, queryType := line[matches[2]:matches[3]], types.Type(line[matches[4]:matches[5]])
+			switch queryType {
+			case types.ONE, types.MANY, types.EXEC:
+			default:
+				typePos := lineStart
+				typePos.Column += utf8.RuneCountInString(line[:matches[4]])
+				typePos.Offset += matches[4]
+				typeEnd := typePos
+				typeEnd.Column += utf8.RuneCountInString(string(queryType))
+				typeEnd.Offset += len(queryType)
+				errs = append(errs, types.Errorf(types.Span{Pos: typePos, End: typeEnd}, "[GENERATE] unknown query type :%s, wanted :one, :many or :exec", queryType))
+				continue
+			}
+
+			// Initialize Tag with name & type, SQL starts on the next line
+			sqlPos := types.Position{File: fileName, Line: lineNum + 1, Column: 1, Offset: offset}
 			current = &types.QueryBlock{
-				Name:     strings.ToUpper(matches[1][:1]) + matches[1][1:],
-				Type:     types.Type(matches[2]),
+				Name:     strings.ToUpper(name[:1]) + name[1:],
+				Type:     queryType,
 				Filename: fileName,
+				Pos:      sqlPos,
 			}
 			continue
 		}
 
+		if comment, ok := strings.CutPrefix(strings.TrimSpace(line), "--"); ok && strings.HasPrefix(strings.TrimSpace(comment), "name:") {
+			lineEnd := lineStart
+			lineEnd.Column += utf8.RuneCountInString(line)
+			lineEnd.Offset += len(line)
+			errs = append(errs, types.Errorf(types.Span{Pos: lineStart, End: lineEnd}, "[GENERATE] malformed query tag, wanted -- name: <Name> :<type>"))
+			if current != nil {
+				current.SQL = sqlBuilder.String()
+				blocks = append(blocks, *current)
+			}
+			current = nil
+			sqlBuilder.Reset()
+			continue
+		}
+
 		if current != nil {
 			sqlBuilder.WriteString(line)
 			sqlBuilder.WriteRune('\n')
+		} else if trimmed := strings.TrimSpace(line); len(errs) == 0 && !warnedUntagged &&
+			trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			lineEnd := lineStart
+			lineEnd.Column += utf8.RuneCountInString(line)
+			lineEnd.Offset += len(line)
+			g.ErrorLogger.Warnf(types.Span{Pos: lineStart, End: lineEnd}, "[GENERATE] SQL before the first -- name: tag is ignored")
+			warnedUntagged = true
 		}
 	}
 
@@ -390,7 +493,7 @@ func (g *Generator) extractSqlBlocks(file *os.File, fileName string) ([]types.Qu
 		return nil, err
 	}
 
-	return blocks, nil
+	return blocks, errors.Join(errs...)
 }
 
 func (g Generator) writeOutput() error {
@@ -408,52 +511,23 @@ func (g Generator) writeOutput() error {
 	return os.WriteFile(schemaOutputPath, []byte(g.OutputCache.String()), 0644)
 }
 
-func (g *Generator) inferDataType(sql string) any {
-	upperSQL := strings.ToUpper(sql)
-	tokens := strings.Fields(upperSQL) // SQL Capitalize syntax
-	if len(tokens) == 0 {
-		return nil
-	}
-
-	var tableName string
-
-	switch tokens[0] {
-	case "SELECT":
-		tableName = g.findTableAfterKeyword(tokens, "FROM")
-	case "INSERT":
-		if len(tokens) >= 3 && tokens[1] == "INTO" {
-			tableName = strings.Trim(tokens[2], ";,()")
-		}
-	case "UPDATE":
-		if len(tokens) >= 2 {
-			tableName = strings.Trim(tokens[1], ";,()")
+// statementTable returns the schema table a statement reads or writes and
+// where it was written, named the way the schema keys it. A SELECT from a CTE
+// or a derived table has none, its tables are checked as its columns are
+// typed.
+func statementTable(stmt parser.Node) (string, types.Span, bool) {
+	switch stmt := stmt.(type) {
+	case *types.SelectStatement:
+		if len(stmt.With) > 0 || stmt.TableSelect != nil {
+			return "", types.Span{}, false
 		}
-	case "DELETE":
-		tableName = g.findTableAfterKeyword(tokens, "FROM")
-	}
-
-	if tableName != "" {
-		// Convert back to lowercase for lookup since table names are stored in lowercase
-		lowerTableName := strings.ToLower(tableName)
-
-		// Try lowercase match first
-		if dataType, exists := g.Types[lowerTableName]; exists {
-			return dataType
-		}
-		// Try with quotes (for quoted table names in schema)
-		if dataType, exists := g.Types[`"`+lowerTableName+`"`]; exists {
-			return dataType
-		}
-	}
-
-	return nil
-}
-
-func (g *Generator) findTableAfterKeyword(tokens []string, keyword string) string {
-	for i, token := range tokens {
-		if token == keyword && i+1 < len(tokens) {
-			return strings.Trim(tokens[i+1], ";,()")
-		}
-	}
-	return ""
+		return stmt.TableName, stmt.TableSpan, true
+	case *types.InsertStatement:
+		return stmt.TableName, stmt.TableSpan, true
+	case *types.UpdateStatement:
+		return stmt.TableName, stmt.TableSpan, true
+	case *types.DeleteStatement:
+		return stmt.TableName, stmt.TableSpan, true
+	}
+	return "", types.Span{}, false
 }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"shogunc/internal/parser"
+	"shogunc/internal/types"
 	"strings"
 	"testing"
 )
@@ -44,7 +46,11 @@ func TestGenerator_Execute(t *testing.T) {
 	}
 
 	query := `-- name: GetUser :one
-SELECT * FROM users WHERE id = $1;`
+SELECT * FROM users WHERE id = $1;
+
+-- name: ListActiveUsers :many
+WITH active AS (SELECT id, email FROM users WHERE status = 'active')
+SELECT id, email FROM active;`
 	if err := os.WriteFile(filepath.Join(queriesDir, "user.sql"), []byte(query), 0644); err != nil {
 		t.Fatal(err)
 	}
@@ -118,4 +124,322 @@ SELECT * FROM users WHERE id = $1;`
 	if !strings.Contains(string(userOut), "func GetUser") {
 		t.Errorf("Expected generated user output to contain 'func GetUser'\nOutput: %s", string(userOut))
 	}
+	if !strings.Contains(string(userOut), "func ListActiveUsers") {
+		t.Errorf("Expected generated user output to contain 'func ListActiveUsers'\nOutput: %s", string(userOut))
+	}
+}
+
+func TestGenerator_Execute_CollectsDiagnostics(t *testing.T) {
+	originalCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(originalCwd)
+
+	tmp := t.TempDir()
+
+	schema := `CREATE TABLE IF NOT EXISTS "users" (
+    "id"    UUID PRIMARY KEY,
+    "age"   ,
+    "email" VARCHAR NOT NULL
+);
+`
+	if err := os.WriteFile(filepath.Join(tmp, "schema.sql"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	queriesDir := filepath.Join(tmp, "queries")
+	if err := os.MkdirAll(queriesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"emails.sql": `-- name: GetUser :one
+SELECT * FROM users WHERE id = $1;
+
+-- name: GetEmail :one
+SELECT email
+FROM WHERE id = $1;
+
+-- name: ListUsers :all
+SELECT * FROM users;`,
+		"users.sql": `-- name: GetUserById :one
+SELECT * FROM users WHERE id = $1;`,
+		"notes.sql": `SELECT * FROM users;`,
+	}
+	for name, query := range files {
+		if err := os.WriteFile(filepath.Join(queriesDir, name), []byte(query), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := fmt.Sprintf(`
+  sql:
+    schema: schema.sql
+    queries: queries
+    driver: sqlite3
+    output: %s/output
+  `, tmp)
+	if err := os.WriteFile(filepath.Join(tmp, "shogunc.yml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(tmp); err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewGenerator().Execute(tmp)
+	if err == nil {
+		t.Fatal("Expected Execute to fail")
+	}
+
+	expected := "schema.sql:3:13: error: [PARSER_TABLE] expected datatype, got: , field_idx: 1\n" +
+		" 3 |     \"age\"   ,\n" +
+		"   |             ^\n" +
+		"queries/emails.sql:6:6: error: expected table name (IDENT), got WHERE\n" +
+		" 6 | FROM WHERE id = $1;\n" +
+		"   |      ^^^^^\n" +
+		"queries/emails.sql:8:21: error: [GENERATE] unknown query type :all, wanted :one, :many or :exec\n" +
+		" 8 | -- name: ListUsers :all\n" +
+		"   |                     ^^^\n" +
+		"queries/notes.sql:1:1: warning: [GENERATE] SQL before the first -- name: tag is ignored\n" +
+		" 1 | SELECT * FROM users;\n" +
+		"   | ^^^^^^^^^^^^^^^^^^^^\n" +
+		"queries/notes.sql: warning: [GENERATE] no queries found, expected -- name: <Name> :<type> tags\n" +
+		"3 error(s), 2 warning(s)"
+	if err.Error() != expected {
+		t.Errorf("Expected error:\n%s\ngot:\n%s", expected, err.Error())
+	}
+
+	// Files without errors are still generated
+	if _, err := os.Stat(filepath.Join(tmp, "output", "users.sql.go")); err != nil {
+		t.Errorf("Expected users.sql.go to be generated, got error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "output", "emails.sql.go")); err == nil {
+		t.Error("Expected emails.sql.go to be skipped")
+	}
+}
+
+func TestExtractSqlBlocks_UnknownType(t *testing.T) {
+	gen := NewGenerator()
+	query := "-- name: GetUser :one\nSELECT * FROM users;\n\n-- name: ListUsers :all\nSELECT * FROM users;\n"
+
+	_, err := gen.extractSqlBlocks(strings.NewReader(query), "queries/users.sql")
+	if err == nil {
+		t.Fatal("Expected error for unknown query type")
+	}
+
+	expected := "queries/users.sql:4:21: [GENERATE] unknown query type :all, wanted :one, :many or :exec"
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err.Error())
+	}
+}
+
+func TestErrorLogger_FormatUnicode(t *testing.T) {
+	logger := NewErrorLogger()
+	logger.AddSource("queries/cafés.sql", []byte("SELECT * FROM cafés WHERE nombre = 'José' AND ¿ = 1;\n"))
+
+	lexer := parser.NewLexerAt("SELECT * FROM cafés WHERE nombre = 'José' AND ¿ = 1;", types.Position{File: "queries/cafés.sql", Line: 1, Column: 1})
+	var illegal parser.Token
+	for tok := lexer.NextToken(); tok.Type != parser.EOF; tok = lexer.NextToken() {
+		if tok.Type == parser.ILLEGAL {
+			illegal = tok
+		}
+	}
+
+	got := string(logger.Format(types.Errorf(illegal.Span, "unexpected %s", illegal.Literal)))
+	expected := "queries/cafés.sql:1:47: error: unexpected ¿\n" +
+		" 1 | SELECT * FROM cafés WHERE nombre = 'José' AND ¿ = 1;\n" +
+		"   |                                               ^"
+	if got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+// executeProject writes schema and queries into a temp project for driver,
+// runs Execute in it and returns the output directory
+func executeProject(t *testing.T, driver Driver, schema string, queries map[string]string) (string, error) {
+	t.Helper()
+
+	originalCwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(originalCwd) })
+
+	tmp := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmp, "schema.sql"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	queriesDir := filepath.Join(tmp, "queries")
+	if err := os.MkdirAll(queriesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, query := range queries {
+		if err := os.WriteFile(filepath.Join(queriesDir, name), []byte(query), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := fmt.Sprintf(`
+  sql:
+    schema: schema.sql
+    queries: queries
+    driver: %s
+    output: %s/output
+  `, driver, tmp)
+	if err := os.WriteFile(filepath.Join(tmp, "shogunc.yml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tmp); err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(tmp, "output"), NewGenerator().Execute(tmp)
+}
+
+func TestGenerator_Execute_QuotedTable(t *testing.T) {
+	schema := `CREATE TABLE "Order" (
+    "id"     INT PRIMARY KEY,
+    "status" TEXT NOT NULL,
+    "Note"   TEXT
+);
+`
+	queries := map[string]string{
+		"order.sql": `-- name: CreateOrder :exec
+INSERT INTO "Order" (id, "Note") VALUES ($1, $2);
+
+-- name: GetOrder :one
+SELECT * FROM "Order" WHERE id = $1;
+
+-- name: ListOrderStatuses :many
+SELECT o.status FROM "Order" o;
+
+-- name: SetOrderStatus :exec
+UPDATE "Order" SET status = $1 WHERE id = $2;
+
+-- name: DeleteOrder :exec
+DELETE FROM "Order" WHERE id = $1;
+
+-- name: ListOrdersByNote :many
+SELECT o.id FROM "Order" o WHERE o."Note" = $1 OR "Note" = $2;`,
+	}
+
+	output, err := executeProject(t, POSTGRES, schema, queries)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	out, err := os.ReadFile(filepath.Join(output, "order.sql.go"))
+	if err != nil {
+		t.Fatalf("Expected order.sql.go to be generated, got error: %v", err)
+	}
+	for _, fn := range []string{"func CreateOrder", "func GetOrder", "func ListOrderStatuses", "func SetOrderStatus", "func DeleteOrder"} {
+		if !strings.Contains(string(out), fn) {
+			t.Errorf("Expected generated output to contain %q\nOutput: %s", fn, out)
+		}
+	}
+	insert := "query := \"INSERT INTO \\\"Order\\\" (id,\\\"Note\\\") VALUES ($1,$2);\""
+	if !strings.Contains(string(out), insert) {
+		t.Errorf("Expected generated output to contain %s\nOutput: %s", insert, out)
+	}
+
+	// Params on a quoted mixed-case column are typed from the column
+	for _, field := range []string{"Note string `json:\"note\"`", "Note2 string `json:\"note_2\"`"} {
+		if !strings.Contains(strings.Join(strings.Fields(string(out)), " "), field) {
+			t.Errorf("Expected generated output to contain %s\nOutput: %s", field, out)
+		}
+	}
+
+	// A table the schema does not have is still reported, at its name
+	_, err = executeProject(t, POSTGRES, schema, map[string]string{
+		"order.sql": "-- name: GetOrder :one\nSELECT * FROM \"order\" WHERE id = $1;",
+	})
+	if err == nil || !strings.Contains(err.Error(), "queries/order.sql:2:15: error: [GENERATE] failed infering type for GetOrder, table order not found in schema") {
+		t.Errorf("Expected unknown table error, got %v", err)
+	}
+}
+
+func TestGenerator_Execute_CommentedQuery(t *testing.T) {
+	schema := "CREATE TABLE users (id INT PRIMARY KEY, email TEXT NOT NULL);\n"
+	queries := map[string]string{
+		"users.sql": `-- name: GetUser :one
+-- Fetch one user by id
+SELECT * FROM users WHERE id = $1;
+
+-- name: GetEmail :one
+/* header */ SELECT email FROM users WHERE id = $1;
+
+-- name: DeleteUser :exec
+-- Remove a user
+DELETE FROM users WHERE id = $1;`,
+	}
+
+	output, err := executeProject(t, POSTGRES, schema, queries)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	out, err := os.ReadFile(filepath.Join(output, "users.sql.go"))
+	if err != nil {
+		t.Fatalf("Expected users.sql.go to be generated, got error: %v", err)
+	}
+	for _, want := range []string{"func GetUser", "func GetEmail", "func DeleteUser", "query := `SELECT email FROM users WHERE id = $1;`"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Expected generated output to contain %q\nOutput: %s", want, out)
+		}
+	}
+}
+
+func TestGenerator_Execute_StringsImport(t *testing.T) {
+	schema := "CREATE TABLE strings (id INT PRIMARY KEY, body TEXT NOT NULL);\n"
+	tests := []struct {
+		name    string
+		query   string
+		imports bool
+	}{
+		{"table named strings", "-- name: GetBody :one\nSELECT strings.body FROM strings WHERE strings.id = $1;", false},
+		{"slice param", "-- name: ListBodies :many\nSELECT body FROM strings WHERE id IN (sqlc.slice(ids));", true},
+		{"slice param in delete", "-- name: DeleteStrings :exec\nDELETE FROM strings WHERE id IN (sqlc.slice(ids));", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := executeProject(t, SQLITE, schema, map[string]string{"strings.sql": tt.query})
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			out, err := os.ReadFile(filepath.Join(output, "strings.sql.go"))
+			if err != nil {
+				t.Fatalf("Expected strings.sql.go to be generated, got error: %v", err)
+			}
+			if got := strings.Contains(string(out), "\t\"strings\"\n"); got != tt.imports {
+				t.Errorf("Expected strings import %v, got %v\nOutput: %s", tt.imports, got, out)
+			}
+		})
+	}
+}
+
+func TestGenerator_Execute_ExpressionErrors(t *testing.T) {
+	schema := "CREATE TABLE users (id INT PRIMARY KEY, age INT, email TEXT NOT NULL);\n"
+	queries := map[string]string{
+		"users.sql": `-- name: GetUser :one
+SELECT id,
+       json_extract(email, '$.name') AS name
+FROM users WHERE id = $1;
+
+-- name: GetLabel :one
+SELECT id, CASE WHEN age > 65 THEN age ELSE email END AS label FROM users;`,
+	}
+
+	_, err := executeProject(t, POSTGRES, schema, queries)
+	if err == nil {
+		t.Fatal("Expected Execute to fail")
+	}
+	for _, want := range []string{
+		"queries/users.sql:3:8: error: [SELECT] cannot infer the type of json_extract(email, '$.name'), json_extract is not a known function",
+		"queries/users.sql:7:12: error: [SELECT] cannot infer the type of CASE WHEN age > 65 THEN age ELSE email END, its results are int and string",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got:\n%v", want, err)
+		}
+	}
 }
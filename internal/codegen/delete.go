@@ -0,0 +1,54 @@
+package codegen
+
+import (
+	"fmt"
+	"go/ast"
+	"shogunc/internal/parser"
+	"shogunc/internal/types"
+
+	"github.com/hector3211/shogun"
+)
+
+type DeleteGenerator struct {
+	schemaTypes map[string]any
+	queryblock  *types.QueryBlock
+	dialect     parser.Dialect
+	imports     map[string]bool // Packages the generated code uses
+}
+
+func NewDeleteGenerator(types map[string]any, queryBlock *types.QueryBlock) *DeleteGenerator {
+	return &DeleteGenerator{schemaTypes: types, queryblock: queryBlock, imports: make(map[string]bool)}
+}
+
+func (g *DeleteGenerator) SetDialect(dialect parser.Dialect) *DeleteGenerator {
+	g.dialect = dialect
+	return g
+}
+
+func (g DeleteGenerator) GenerateDeleteFunc(astStmt *types.DeleteStatement) (*ast.FuncDecl, *ast.GenDecl, error) {
+	fieldMap, err := inferDataType(g.schemaTypes, astStmt.TableName)
+	if err != nil {
+		return nil, nil, types.WrapError(astStmt.TableSpan, err)
+	}
+
+	var paramStruct *ast.GenDecl
+	var paramTypeName string
+	params := newQueryParams(g.dialect, types.ExprBinds(astStmt.Where), fieldMap)
+	if len(params.fields) > 0 {
+		paramTypeName = fmt.Sprintf("%sParams", g.queryblock.Name)
+		paramStruct = paramStructDecl(paramTypeName, params.fields)
+	}
+
+	args := params.args()
+	args.addImports(g.imports)
+	function := generateExecFunc(g.queryblock.Name, paramTypeName, g.generateDeleteQuery(astStmt, params), args)
+	return function, paramStruct, nil
+}
+
+func (g DeleteGenerator) generateDeleteQuery(astStmt *types.DeleteStatement, params queryParams) string {
+	queryBuilder := shogun.NewDeleteBuilder().Delete(types.QuoteIdent(astStmt.TableName, astStmt.TableQuoted))
+	if astStmt.Where != nil {
+		queryBuilder.Where(params.sql(astStmt.Where))
+	}
+	return queryBuilder.Build()
+}
@@ -0,0 +1,87 @@
+package codegen
+
+import (
+	"shogunc/internal/parser"
+	"shogunc/internal/types"
+	"strings"
+	"testing"
+)
+
+// TestGenerateDeleteFunc tests the WHERE expression is emitted as written
+func TestGenerateDeleteFunc(t *testing.T) {
+	schemaTypes := map[string]any{
+		"sessions": &parser.Table{
+			Name: "sessions",
+			Fields: []parser.Field{
+				{Name: "user_id", DataType: parser.Token{Literal: "UUID"}},
+				{Name: "expires_at", DataType: parser.Token{Literal: "TIMESTAMP"}},
+			},
+		},
+	}
+
+	queryBlock := &types.QueryBlock{Name: "DeleteSessions", Type: types.EXEC}
+	funcSrc, structSrc := generateFromSQL(t, schemaTypes, queryBlock, parser.SQLite,
+		"DELETE FROM sessions WHERE user_id = ? AND NOT (expires_at > ?);")
+
+	expectedStruct := "type DeleteSessionsParams struct {\n" +
+		"\tUserId\t\tstring\t\t`json:\"user_id\"`\n" +
+		"\tExpiresAt\ttime.Time\t`json:\"expires_at\"`\n" +
+		"}"
+	if structSrc != expectedStruct {
+		t.Errorf("Expected struct:\n%s\ngot:\n%s", expectedStruct, structSrc)
+	}
+
+	expectedFunc := "func DeleteSessions(q *Queries, ctx context.Context, params DeleteSessionsParams) error {\n" +
+		"\tquery := `DELETE FROM sessions WHERE user_id = ?1 AND NOT (expires_at > ?2);`\n" +
+		"\terr := q.db.Exec(ctx, query, params.UserId, params.ExpiresAt)\n" +
+		"\treturn err\n" +
+		"}"
+	if funcSrc != expectedFunc {
+		t.Errorf("Expected function:\n%s\ngot:\n%s", expectedFunc, funcSrc)
+	}
+}
+
+// TestGenerateDeleteFunc_NoWhere tests a DELETE without params takes none
+func TestGenerateDeleteFunc_NoWhere(t *testing.T) {
+	schemaTypes := map[string]any{
+		"sessions": &parser.Table{
+			Name:   "sessions",
+			Fields: []parser.Field{{Name: "user_id", DataType: parser.Token{Literal: "UUID"}}},
+		},
+	}
+
+	queryBlock := &types.QueryBlock{Name: "DeleteAllSessions", Type: types.EXEC}
+	funcSrc, structSrc := generateFromSQL(t, schemaTypes, queryBlock, parser.Postgres, "DELETE FROM sessions;")
+
+	if structSrc != "" {
+		t.Errorf("Expected no params struct, got:\n%s", structSrc)
+	}
+	expectedFunc := "func DeleteAllSessions(q *Queries, ctx context.Context) error {\n" +
+		"\tquery := `DELETE FROM sessions;`\n" +
+		"\terr := q.db.Exec(ctx, query)\n" +
+		"\treturn err\n" +
+		"}"
+	if funcSrc != expectedFunc {
+		t.Errorf("Expected function:\n%s\ngot:\n%s", expectedFunc, funcSrc)
+	}
+}
+
+// TestGenerateDeleteFunc_QuotedTable tests a quoted table name is written
+// back quoted
+func TestGenerateDeleteFunc_QuotedTable(t *testing.T) {
+	schemaTypes := map[string]any{
+		"Order": &parser.Table{
+			Name:   "Order",
+			Fields: []parser.Field{{Name: "id", DataType: parser.Token{Literal: "INT"}}},
+		},
+	}
+
+	queryBlock := &types.QueryBlock{Name: "DeleteOrder", Type: types.EXEC}
+	funcSrc, _ := generateFromSQL(t, schemaTypes, queryBlock, parser.Postgres,
+		`DELETE FROM "Order" WHERE id = $1;`)
+
+	query := "query := `DELETE FROM \"Order\" WHERE id = $1;`"
+	if !strings.Contains(funcSrc, query) {
+		t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+	}
+}
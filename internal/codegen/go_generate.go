@@ -7,31 +7,84 @@ import (
 	"shogunc/internal/parser"
 	"shogunc/internal/types"
 	"shogunc/utils"
+	"slices"
 	"strings"
 )
 
 type GoGenerator struct {
 	schemaTypes map[string]any
 	queryblock  *types.QueryBlock
+	dialect     parser.Dialect
+	imports     map[string]bool // Packages the generated code uses
 }
 
 func NewGoGenerator(types map[string]any, queryBlock *types.QueryBlock) *GoGenerator {
-	return &GoGenerator{schemaTypes: types, queryblock: queryBlock}
+	return &GoGenerator{schemaTypes: types, queryblock: queryBlock, imports: make(map[string]bool)}
+}
+
+// SetDialect picks the placeholder syntax written into the generated SQL
+func (g *GoGenerator) SetDialect(dialect parser.Dialect) *GoGenerator {
+	g.dialect = dialect
+	return g
+}
+
+// Imports lists the packages beyond context and time used by the code
+// generated so far, sorted
+func (g GoGenerator) Imports() []string {
+	imports := make([]string, 0, len(g.imports))
+	for pkg := range g.imports {
+		imports = append(imports, pkg)
+	}
+	slices.Sort(imports)
+	return imports
 }
 
 func (g GoGenerator) Generate(astStmt any) (*ast.FuncDecl, *ast.GenDecl, error) {
+	funcDecl, paramStruct, err := g.generate(astStmt)
+	if err != nil {
+		return nil, nil, types.WrapError(types.Span{Pos: g.queryblock.Pos}, err)
+	}
+	return funcDecl, paramStruct, nil
+}
+
+// GenerateRowStruct declares the <QueryName>Row struct a SELECT returns when
+// it does not return its table's struct, it is nil otherwise
+func (g GoGenerator) GenerateRowStruct(astStmt any) (*ast.GenDecl, error) {
+	selectStmt, ok := astStmt.(*types.SelectStatement)
+	if !ok {
+		return nil, nil
+	}
+
+	rowStruct, err := NewSelectGenerator(g.schemaTypes, g.queryblock).SetDialect(g.dialect).GenerateRowStruct(selectStmt)
+	if err != nil {
+		return nil, types.WrapError(types.Span{Pos: g.queryblock.Pos}, err)
+	}
+	return rowStruct, nil
+}
+
+func (g GoGenerator) generate(astStmt any) (*ast.FuncDecl, *ast.GenDecl, error) {
 	switch g.queryblock.Type {
 	case types.ONE, types.MANY:
 		if selectStmt, ok := astStmt.(*types.SelectStatement); ok {
-			selectGen := NewSelectGenerator(g.schemaTypes, g.queryblock)
+			selectGen := NewSelectGenerator(g.schemaTypes, g.queryblock).SetDialect(g.dialect)
+			selectGen.imports = g.imports
 			isMany := g.queryblock.Type == types.MANY
 			return selectGen.GenerateSelectFunc(selectStmt, isMany)
 		}
 		return nil, nil, fmt.Errorf("[GO_GENERATOR][ONE/MANY] no statement found")
 	case types.EXEC:
-		if insertStmt, ok := astStmt.(*types.InsertStatement); ok {
-			insertGen := NewInsertGenerator(g.schemaTypes, g.queryblock)
-			return insertGen.GenerateInsertFunc(insertStmt)
+		switch stmt := astStmt.(type) {
+		case *types.InsertStatement:
+			insertGen := NewInsertGenerator(g.schemaTypes, g.queryblock).SetDialect(g.dialect)
+			return insertGen.GenerateInsertFunc(stmt)
+		case *types.UpdateStatement:
+			updateGen := NewUpdateGenerator(g.schemaTypes, g.queryblock).SetDialect(g.dialect)
+			updateGen.imports = g.imports
+			return updateGen.GenerateUpdateFunc(stmt)
+		case *types.DeleteStatement:
+			deleteGen := NewDeleteGenerator(g.schemaTypes, g.queryblock).SetDialect(g.dialect)
+			deleteGen.imports = g.imports
+			return deleteGen.GenerateDeleteFunc(stmt)
 		}
 		return nil, nil, fmt.Errorf("[GO_GENERATOR][EXEC] no statement found")
 	default:
@@ -39,6 +92,209 @@ func (g GoGenerator) Generate(astStmt any) (*ast.FuncDecl, *ast.GenDecl, error)
 	}
 }
 
+// paramField is one field of a generated params struct
+type paramField struct {
+	name  string // Go field name
+	tag   string // json tag
+	typ   string
+	pos   int
+	slice bool // Bound to a Go slice, typ is the slice type
+}
+
+// collectParams returns one field per param position, sorted by position.
+// Named params are named after the param and the rest after their column,
+// a column used by several params gets the position appended after the first.
+// A param tied to neither a name nor a column is called ParamN and typed any,
+// a param with its own Type such as LIMIT's int64 keeps it.
+// Slice params get a slice of the column type. A param compared with a
+// qualified column is typed from a table.column key when fieldMap has one.
+func collectParams(binds []types.Bind, fieldMap map[string]string) []paramField {
+	var params []paramField
+	seen := make(map[int]bool)
+	names := make(map[string]bool)
+	for _, bind := range binds {
+		if bind.Position == 0 || seen[bind.Position] {
+			continue
+		}
+		seen[bind.Position] = true
+
+		source := bind.Name
+		if source == "" {
+			source = bind.Column
+		}
+		name := utils.ToProperPascalCase(source)
+		tag := utils.ToSnakeCase(utils.ToPascalCase(source))
+		if source == "" {
+			name = fmt.Sprintf("Param%d", bind.Position)
+			tag = fmt.Sprintf("param_%d", bind.Position)
+		}
+		if names[name] {
+			name = fmt.Sprintf("%s%d", name, bind.Position)
+			tag = fmt.Sprintf("%s_%d", tag, bind.Position)
+		}
+		names[name] = true
+
+		typ, ok := fieldMap[bind.Column]
+		if qualified, found := fieldMap[bind.Table+"."+bind.Column]; bind.Table != "" && found {
+			typ, ok = qualified, true
+		}
+		if !ok {
+			typ = "any"
+		}
+		if bind.Type != "" {
+			typ = bind.Type
+		}
+		if bind.Slice {
+			typ = "[]" + typ
+		}
+
+		params = append(params, paramField{
+			name:  name,
+			tag:   tag,
+			typ:   typ,
+			pos:   bind.Position,
+			slice: bind.Slice,
+		})
+	}
+
+	// Sort params by position
+	for i := range len(params) - 1 {
+		for j := i + 1; j < len(params); j++ {
+			if params[i].pos > params[j].pos {
+				params[i], params[j] = params[j], params[i]
+			}
+		}
+	}
+
+	return params
+}
+
+// paramStructFields builds the fields of a params struct
+func paramStructFields(params []paramField) []*ast.Field {
+	var fields []*ast.Field
+	for _, param := range params {
+		fields = append(fields, &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(param.name)}, // Proper PascalCase for Go field name
+			Type:  ast.NewIdent(param.typ),
+			Tag: &ast.BasicLit{
+				Kind:  token.STRING,
+				Value: fmt.Sprintf("`json:\"%s\"`", param.tag), // Use snake_case for JSON tag
+			},
+		})
+	}
+	return fields
+}
+
+// paramArgs passes each params field to the query in position order
+func paramArgs(params []paramField) []ast.Expr {
+	var args []ast.Expr
+	for _, param := range params {
+		args = append(args, &ast.SelectorExpr{
+			X:   ast.NewIdent("params"),
+			Sel: ast.NewIdent(param.name),
+		})
+	}
+	return args
+}
+
+// paramStructDecl declares the params struct typeName
+func paramStructDecl(typeName string, params []paramField) *ast.GenDecl {
+	return &ast.GenDecl{
+		Tok: token.TYPE,
+		Specs: []ast.Spec{
+			&ast.TypeSpec{
+				Name: ast.NewIdent(typeName),
+				Type: &ast.StructType{
+					Fields: &ast.FieldList{List: paramStructFields(params)},
+				},
+			},
+		},
+	}
+}
+
+// generateExecFunc builds a func that runs sql with Exec and only returns
+// its error, it takes a params argument when paramTypeName is set
+func generateExecFunc(name, paramTypeName, sql string, args queryArgs) *ast.FuncDecl {
+	params := []*ast.Field{
+		{
+			Names: []*ast.Ident{ast.NewIdent("q")},
+			Type:  &ast.StarExpr{X: ast.NewIdent("Queries")},
+		},
+		{
+			Names: []*ast.Ident{ast.NewIdent("ctx")},
+			Type: &ast.SelectorExpr{
+				X:   ast.NewIdent("context"),
+				Sel: ast.NewIdent("Context"),
+			},
+		},
+	}
+	if paramTypeName != "" {
+		params = append(params, &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent("params")},
+			Type:  ast.NewIdent(paramTypeName),
+		})
+	}
+
+	// query := "..."
+	queryStmt := &ast.AssignStmt{
+		Lhs: []ast.Expr{ast.NewIdent("query")},
+		Tok: token.DEFINE,
+		Rhs: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: sqlStringLit(sql)}},
+	}
+
+	// err := q.db.Exec(ctx, query, params...)
+	execStmt := &ast.AssignStmt{
+		Lhs: []ast.Expr{ast.NewIdent("err")},
+		Tok: token.DEFINE,
+		Rhs: []ast.Expr{args.call(&ast.SelectorExpr{
+			X:   &ast.SelectorExpr{X: ast.NewIdent("q"), Sel: ast.NewIdent("db")},
+			Sel: ast.NewIdent("Exec"),
+		})},
+	}
+
+	body := append([]ast.Stmt{queryStmt}, args.prep...)
+	body = append(body, execStmt, &ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("err")}})
+
+	return &ast.FuncDecl{
+		Name: ast.NewIdent(name),
+		Type: &ast.FuncType{
+			Params:  &ast.FieldList{List: params},
+			Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("error")}}},
+		},
+		Body: &ast.BlockStmt{List: body},
+	}
+}
+
+// inferDataType maps each column of the table typeName to its Go type
+func inferDataType(schemaTypes map[string]any, typeName string) (map[string]string, error) {
+	dataMap := make(map[string]string)
+
+	for k, v := range schemaTypes {
+		if k == typeName {
+			switch inferredType := v.(type) {
+			case *parser.Table:
+				for _, field := range inferredType.Fields {
+					goType, err := parser.SqlToGoType(field.DataType)
+					if err != nil || goType == "" {
+						return map[string]string{}, fmt.Errorf("failed to convert field %s: %v", field.Name, err)
+					}
+					dataMap[field.Name] = goType
+				}
+			case *parser.Enum:
+				dataMap[inferredType.Name] = "string"
+			}
+			break // Found the type
+		}
+	}
+
+	// If no table was found, return an error
+	if len(dataMap) == 0 {
+		return nil, fmt.Errorf("table '%s' not found in schema", typeName)
+	}
+
+	return dataMap, nil
+}
+
 // Generate Types ---------------------------------------------------------------------
 func GenerateEnumType(enumType *parser.Enum) (*ast.GenDecl, *ast.GenDecl, error) {
 	typeDecl := &ast.GenDecl{
@@ -88,7 +344,7 @@ func GenerateTableType(tableType *parser.Table) (*ast.GenDecl, error) {
 	for _, f := range tableType.Fields {
 		goType, err := parser.SqlToGoType(f.DataType)
 		if err != nil {
-			return nil, fmt.Errorf("[BUILDER] failed parsing %s %v to GO type", f.DataType.Literal, f.DataType.Type)
+			return nil, types.Errorf(f.DataType.Span, "[BUILDER] failed parsing %s %v to GO type", f.DataType.Literal, f.DataType.Type)
 		}
 
 		fieldName := utils.ToProperPascalCase(f.Name) // Use proper PascalCase (no underscores)
@@ -133,7 +389,7 @@ func GenerateInsertableTableType(tableType *parser.Table) (*ast.GenDecl, error)
 	for _, f := range tableType.Fields {
 		goType, err := parser.SqlToGoType(f.DataType)
 		if err != nil {
-			return nil, fmt.Errorf("[BUILDER] failed parsing %s to GO type", f.DataType.Literal)
+			return nil, types.Errorf(f.DataType.Span, "[BUILDER] failed parsing %s to GO type", f.DataType.Literal)
 		}
 
 		var fieldType ast.Expr
@@ -7,6 +7,7 @@ import (
 	"shogunc/internal/parser"
 	"shogunc/internal/types"
 	"shogunc/utils"
+	"strconv"
 	"strings"
 
 	"github.com/hector3211/shogun"
@@ -15,16 +16,26 @@ import (
 type InsertGenerator struct {
 	schemaTypes map[string]any
 	queryblock  *types.QueryBlock
+	dialect     parser.Dialect
 }
 
 func NewInsertGenerator(types map[string]any, queryBlock *types.QueryBlock) *InsertGenerator {
 	return &InsertGenerator{schemaTypes: types, queryblock: queryBlock}
 }
 
+func (g *InsertGenerator) SetDialect(dialect parser.Dialect) *InsertGenerator {
+	g.dialect = dialect
+	return g
+}
+
 func (g InsertGenerator) GenerateInsertFunc(astStmt *types.InsertStatement) (*ast.FuncDecl, *ast.GenDecl, error) {
+	astStmt, err := g.resolveColumns(astStmt)
+	if err != nil {
+		return nil, nil, types.WrapError(astStmt.TableSpan, err)
+	}
 	paramStruct, paramTypeName, err := g.generateInsertParamStruct(astStmt)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, types.WrapError(astStmt.TableSpan, err)
 	}
 
 	// Determine return type based on RETURNING clause
@@ -81,62 +92,42 @@ func (g InsertGenerator) GenerateInsertFunc(astStmt *types.InsertStatement) (*as
 	return function, paramStruct, nil
 }
 
-func (g InsertGenerator) generateInsertParamStruct(astStmt *types.InsertStatement) (*ast.GenDecl, string, error) {
-	fieldMap, err := g.inferDataType(astStmt.TableName) // Extract data type and its column types
-	if err != nil {
-		return nil, "", err
+// resolveColumns fills in the column list of an INSERT written without one
+// from the schema table, in the order the table declares them
+func (g InsertGenerator) resolveColumns(astStmt *types.InsertStatement) (*types.InsertStatement, error) {
+	if len(astStmt.Columns) > 0 {
+		return astStmt, nil
 	}
-
-	type paramInfo struct {
-		name string
-		typ  string
-		pos  int
+	table, ok := g.schemaTypes[astStmt.TableName].(*parser.Table)
+	if !ok {
+		return astStmt, fmt.Errorf("table %s not found in schema", astStmt.TableName)
 	}
-
-	var paramList []paramInfo
-	for _, condition := range astStmt.Values {
-		if condition.Position != 0 {
-			// Normalize column name to lowercase for lookup
-			normalizedColumn := strings.ToLower(condition.Column)
-			goType := fieldMap[normalizedColumn]
-			paramName := utils.ToPascalCase(condition.Column)
-			paramList = append(paramList, paramInfo{
-				name: paramName,
-				typ:  goType,
-				pos:  condition.Position,
-			})
-		}
+	if len(astStmt.Values) != len(table.Fields) {
+		return astStmt, fmt.Errorf("VALUES has %d values for the %d columns of %s", len(astStmt.Values), len(table.Fields), astStmt.TableName)
 	}
 
-	typeName := fmt.Sprintf("%sParams", g.queryblock.Name)
-
-	if len(paramList) == 0 {
-		return nil, "", nil
+	stmt := *astStmt
+	stmt.Values = make([]types.Bind, len(astStmt.Values))
+	for i, field := range table.Fields {
+		stmt.Columns = append(stmt.Columns, field.Name)
+		stmt.ColumnQuoted = append(stmt.ColumnQuoted, field.Quoted)
+		stmt.Values[i] = astStmt.Values[i]
+		stmt.Values[i].Column = field.Name
 	}
+	return &stmt, nil
+}
 
-	// Sort params by position
-	for i := range len(paramList) - 1 {
-		for j := i + 1; j < len(paramList); j++ {
-			if paramList[i].pos > paramList[j].pos {
-				paramList[i], paramList[j] = paramList[j], paramList[i]
-			}
-		}
+func (g InsertGenerator) generateInsertParamStruct(astStmt *types.InsertStatement) (*ast.GenDecl, string, error) {
+	fieldMap, err := g.inferDataType(astStmt.TableName) // Extract data type and its column types
+	if err != nil {
+		return nil, "", err
 	}
 
-	var fields []*ast.Field
-	for _, param := range paramList {
-		fieldName := utils.ToProperPascalCase(param.name)
-		jsonTag := utils.ToSnakeCase(param.name)
-		field := &ast.Field{
-			Names: []*ast.Ident{ast.NewIdent(fieldName)}, // Proper PascalCase for Go field name
-			Type:  ast.NewIdent(param.typ),
-			Tag: &ast.BasicLit{
-				Kind:  token.STRING,
-				Value: fmt.Sprintf("`json:\"%s\"`", jsonTag), // Use snake_case for JSON tag
-			},
-		}
+	typeName := fmt.Sprintf("%sParams", g.queryblock.Name)
 
-		fields = append(fields, field)
+	params := collectParams(astStmt.Values, fieldMap)
+	if len(params) == 0 {
+		return nil, "", nil
 	}
 
 	paramStructDecl := &ast.GenDecl{
@@ -145,7 +136,7 @@ func (g InsertGenerator) generateInsertParamStruct(astStmt *types.InsertStatemen
 			&ast.TypeSpec{
 				Name: ast.NewIdent(typeName),
 				Type: &ast.StructType{
-					Fields: &ast.FieldList{List: fields},
+					Fields: &ast.FieldList{List: paramStructFields(params)},
 				},
 			},
 		},
@@ -183,27 +174,35 @@ func (g InsertGenerator) generateFunctionBody(astStmt *types.InsertStatement, ha
 }
 
 func (g InsertGenerator) generateInsertQuery(astStmt *types.InsertStatement) ast.Stmt {
+	columns := make([]string, len(astStmt.Columns))
+	for i, column := range astStmt.Columns {
+		columns[i] = types.QuoteIdent(column, i < len(astStmt.ColumnQuoted) && astStmt.ColumnQuoted[i])
+	}
 	queryBuilder := shogun.NewInsertBuilder().
-		Insert(astStmt.TableName).
-		Columns(astStmt.Columns...)
+		Insert(types.QuoteIdent(astStmt.TableName, astStmt.TableQuoted)).
+		Columns(columns...)
 
 	var values []any
+	var quoted, raw []string
 	for idx, v := range astStmt.Values {
 		if astStmt.Columns[idx] == v.Column {
-			if v.Position != 0 {
-				// Create proper bind parameter syntax ($1, $2, etc.)
-				value := fmt.Sprintf("$%d", v.Position)
-				values = append(values, value)
-			} else {
-				values = append(values, v.Value)
+			// Create proper bind parameter syntax ($1, $2, etc.)
+			value := g.dialect.Placeholder(v.Position)
+			if v.Position == 0 {
+				value = v.LiteralSQL()
 			}
+			values = append(values, value)
+			quoted = append(quoted, fmt.Sprintf("'%s'", value))
+			raw = append(raw, value)
 		}
 	}
 	queryBuilder.Values(values...)
 	sql := queryBuilder.Build()
 
-	// Clean up any quoted bind parameters that the shogun library might add
-	sql = utils.CleanBindParam(sql)
+	// shogun quotes every value, put back the values as they were written
+	sql = strings.Replace(sql,
+		fmt.Sprintf("(%s)", strings.Join(quoted, ",")),
+		fmt.Sprintf("(%s)", strings.Join(raw, ",")), 1)
 
 	// Todo: handle "onclonfict"
 	return &ast.AssignStmt{
@@ -211,7 +210,7 @@ func (g InsertGenerator) generateInsertQuery(astStmt *types.InsertStatement) ast
 		Tok: token.DEFINE,
 		Rhs: []ast.Expr{&ast.BasicLit{
 			Kind:  token.STRING,
-			Value: fmt.Sprintf("\"%s\"", sql),
+			Value: strconv.Quote(sql),
 		}},
 	}
 }
@@ -328,48 +327,8 @@ func (g InsertGenerator) generateInsertDbQuery(astStmt *types.InsertStatement) [
 }
 
 func (g InsertGenerator) generateSelectParamArgs(astStmt *types.InsertStatement) []ast.Expr {
-	type paramInfo struct {
-		name string
-		typ  string
-		pos  int
-	}
-
-	var paramList []paramInfo
-
-	for _, condition := range astStmt.Values {
-		if condition.Position != 0 {
-			columnNameToLower := strings.ToLower(condition.Column)
-			fieldMap, _ := g.inferDataType(astStmt.TableName)
-			goType := fieldMap[columnNameToLower]
-			paramName := utils.ToProperPascalCase(condition.Column)
-
-			paramList = append(paramList, paramInfo{
-				name: paramName,
-				typ:  goType,
-				pos:  condition.Position,
-			})
-		}
-	}
-
-	// Sort by position
-	for i := range len(paramList) - 1 {
-		for j := i + 1; j < len(paramList); j++ {
-			if paramList[i].pos > paramList[j].pos {
-				paramList[i], paramList[j] = paramList[j],
-					paramList[i]
-			}
-		}
-	}
-
-	var args []ast.Expr
-	for _, param := range paramList {
-		args = append(args, &ast.SelectorExpr{
-			X:   ast.NewIdent("params"),
-			Sel: ast.NewIdent(param.name),
-		})
-	}
-
-	return args
+	fieldMap, _ := g.inferDataType(astStmt.TableName)
+	return paramArgs(collectParams(astStmt.Values, fieldMap))
 }
 
 func (g InsertGenerator) generateScanArgs(astStmt *types.InsertStatement) []ast.Expr {
@@ -438,30 +397,5 @@ func (g InsertGenerator) generateReturnStmt(hasReturning bool) *ast.ReturnStmt {
 }
 
 func (g InsertGenerator) inferDataType(typeName string) (map[string]string, error) {
-	dataMap := make(map[string]string)
-
-	for k, v := range g.schemaTypes {
-		if k == typeName {
-			switch inferredType := v.(type) {
-			case *parser.Table:
-				for _, field := range inferredType.Fields {
-					goType, err := parser.SqlToGoType(field.DataType)
-					if err != nil || goType == "" {
-						return map[string]string{}, fmt.Errorf("failed to convert field %s: %v", field.Name, err)
-					}
-					dataMap[field.Name] = goType
-				}
-			case *parser.Enum:
-				dataMap[inferredType.Name] = "string"
-			}
-			break // Found the type
-		}
-	}
-
-	// If no table was found, return an error
-	if len(dataMap) == 0 {
-		return nil, fmt.Errorf("table '%s' not found in schema", typeName)
-	}
-
-	return dataMap, nil
+	return inferDataType(g.schemaTypes, typeName)
 }
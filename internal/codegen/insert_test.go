@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"shogunc/internal/parser"
 	"shogunc/internal/types"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -96,6 +97,43 @@ func TestGenerateInsertFunc(t *testing.T) {
 	}
 }
 
+// TestGenerateInsertFunc_NoColumns tests an INSERT without a column list takes the table's columns
+func TestGenerateInsertFunc_NoColumns(t *testing.T) {
+	schemaTypes := map[string]any{
+		"users": &parser.Table{
+			Name: "users",
+			Fields: []parser.Field{
+				{Name: "id", DataType: parser.Token{Type: parser.UUID, Literal: "UUID"}},
+				{Name: "Email", Quoted: true, DataType: parser.Token{Type: parser.TEXT, Literal: "TEXT"}},
+			},
+		},
+	}
+	generator := NewInsertGenerator(schemaTypes, &types.QueryBlock{Name: "CreateUser", Type: types.EXEC}).SetDialect(parser.Postgres)
+
+	insertStmt := &types.InsertStatement{
+		TableName: "users",
+		Values:    []types.Bind{{Position: 1}, {Position: 2}},
+	}
+	funcDecl, paramStruct, err := generator.GenerateInsertFunc(insertStmt)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got, want := renderNode(t, funcDecl), `"INSERT INTO users (id,\"Email\") VALUES ($1,$2);"`; !strings.Contains(got, want) {
+		t.Errorf("Expected query %s, got:\n%s", want, got)
+	}
+	if got := strings.Join(strings.Fields(renderNode(t, paramStruct)), " "); !strings.Contains(got, "Id string") || !strings.Contains(got, "Email string") {
+		t.Errorf("Expected params typed from the table columns, got:\n%s", got)
+	}
+	if len(insertStmt.Columns) != 0 {
+		t.Errorf("Expected the statement to be left as parsed, got columns %v", insertStmt.Columns)
+	}
+
+	insertStmt.Values = insertStmt.Values[:1]
+	if _, _, err := generator.GenerateInsertFunc(insertStmt); err == nil || !strings.Contains(err.Error(), "VALUES has 1 values for the 2 columns of users") {
+		t.Errorf("Expected a value count error, got %v", err)
+	}
+}
+
 // TestGenerateInsertFunc_NoParams tests INSERT function generation with no parameters
 func TestGenerateInsertFunc_NoParams(t *testing.T) {
 	schemaTypes := map[string]any{
@@ -394,6 +432,35 @@ func TestGenerateInsertQuery(t *testing.T) {
 	}
 }
 
+// TestGenerateInsertQuery_Literals tests literal values keep their kind in the SQL
+func TestGenerateInsertQuery_Literals(t *testing.T) {
+	generator := NewInsertGenerator(make(map[string]any), &types.QueryBlock{Name: "CreateProduct", Type: types.EXEC})
+
+	name, price, stock, active := `O'Brien "Jr"`, "9.99", "-5", "true"
+	insertStmt := &types.InsertStatement{
+		TableName: "products",
+		Columns:   []string{"id", "name", "price", "stock", "active"},
+		Values: []types.Bind{
+			{Column: "id", Position: 1},
+			{Column: "name", Value: &name, Kind: types.StringLiteral},
+			{Column: "price", Value: &price, Kind: types.FloatLiteral},
+			{Column: "stock", Value: &stock, Kind: types.IntLiteral},
+			{Column: "active", Value: &active, Kind: types.BoolLiteral},
+		},
+	}
+
+	stmt := generator.generateInsertQuery(insertStmt).(*ast.AssignStmt)
+	sql, err := strconv.Unquote(stmt.Rhs[0].(*ast.BasicLit).Value)
+	if err != nil {
+		t.Fatalf("query is not a valid Go string: %v", err)
+	}
+
+	expected := `INSERT INTO products (id,name,price,stock,active) VALUES ($1,'O''Brien "Jr"',9.99,-5,TRUE);`
+	if sql != expected {
+		t.Errorf("Expected %q, got %q", expected, sql)
+	}
+}
+
 // TestGenerateResultDecl tests result variable declaration
 func TestGenerateResultDecl(t *testing.T) {
 	schemaTypes := make(map[string]any)
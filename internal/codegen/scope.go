@@ -0,0 +1,757 @@
+package codegen
+
+import (
+	"fmt"
+	"shogunc/internal/parser"
+	"shogunc/internal/types"
+	"strings"
+)
+
+// relation is a table a query reads from, nullable when it is on the
+// nullable side of an outer join
+type relation struct {
+	name     string // Alias, or the table name without one
+	columns  []relationColumn
+	nullable bool
+}
+
+// relationColumn is a column of a relation typed from the schema, or from the
+// query of a derived table
+type relationColumn struct {
+	name     string
+	typ      string // Go type without a pointer
+	nullable bool
+	err      error // Set when the column's type has no Go type, reported once it is used
+}
+
+// scope holds the tables of a query's FROM clause, its columns are resolved
+// against them and then against the scopes of the queries it is nested in
+type scope struct {
+	relations   []relation
+	using       map[string]bool             // Columns joined with USING, shared by both sides
+	ctes        map[string][]relationColumn // Columns of the query's CTEs, found before tables of the schema
+	dialect     parser.Dialect              // Picks the function catalog calls are typed from
+	schemaTypes map[string]any
+	parent      *scope // Scope of the query a subquery is nested in, nil at the top
+}
+
+// resultColumn is one column of a query's result
+type resultColumn struct {
+	name     string // Column name
+	table    string // Table the column comes from
+	typ      string // Go type without a pointer
+	nullable bool
+}
+
+// goType is the Go type of the column, a pointer when it can be NULL
+func (c resultColumn) goType() string {
+	return pointerType(c.typ, c.nullable)
+}
+
+// newScope collects the CTEs of a query and the tables of FROM and its
+// joins. A LEFT JOIN makes the joined table nullable, a RIGHT JOIN every
+// table before it and a FULL JOIN both sides.
+func newScope(schemaTypes map[string]any, stmt *types.SelectStatement, dialect parser.Dialect) (*scope, error) {
+	s := &scope{dialect: dialect, schemaTypes: schemaTypes}
+	if err := s.build(stmt); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// child returns the scope of a subquery nested in s, its columns can refer to
+// the tables of s
+func (s *scope) child(stmt *types.SelectStatement) (*scope, error) {
+	child := &scope{dialect: s.dialect, schemaTypes: s.schemaTypes, parent: s}
+	if err := child.build(stmt); err != nil {
+		return nil, err
+	}
+	return child, nil
+}
+
+func (s *scope) build(stmt *types.SelectStatement) error {
+	s.using = make(map[string]bool)
+	s.ctes = make(map[string][]relationColumn)
+	for _, cte := range stmt.With {
+		if err := s.addCTE(cte, stmt.Recursive); err != nil {
+			return err
+		}
+	}
+
+	if err := s.add(stmt.Table(), false); err != nil {
+		return err
+	}
+	for _, join := range stmt.Joins {
+		if join.Type == types.RightJoin || join.Type == types.FullJoin {
+			for i := range s.relations {
+				s.relations[i].nullable = true
+			}
+		}
+		nullable := join.Type == types.LeftJoin || join.Type == types.FullJoin
+		if err := s.add(join.Table, nullable); err != nil {
+			return err
+		}
+		for _, column := range join.Using {
+			s.using[column.Name] = true
+		}
+	}
+	return nil
+}
+
+// addCTE types the columns of a CTE from its query, which sees the CTEs
+// written before it. Columns named after the CTE take those names. The query
+// of a recursive CTE is typed from its first query, the queries of the
+// compound after it can read the CTE.
+func (s *scope) addCTE(cte types.CTE, recursive bool) error {
+	inner, err := s.child(cte.Select)
+	if err != nil {
+		return err
+	}
+	result, err := inner.resultColumns(cte.Select.Columns)
+	if err != nil {
+		return err
+	}
+	if recursive {
+		if s.ctes[cte.Name], err = cteColumns(cte, result); err != nil {
+			return err
+		}
+	}
+
+	result, err = inner.compoundColumns(cte.Select, result)
+	if err != nil {
+		return err
+	}
+	s.ctes[cte.Name], err = cteColumns(cte, result)
+	return err
+}
+
+// cteColumns turns the result of a CTE's query into the columns the CTE has
+func cteColumns(cte types.CTE, result []resultColumn) ([]relationColumn, error) {
+	if len(cte.Columns) > 0 && len(cte.Columns) != len(result) {
+		return nil, types.Errorf(cte.Span, "[SELECT] %s names %d columns but its query returns %d", cte.Name, len(cte.Columns), len(result))
+	}
+
+	columns := make([]relationColumn, len(result))
+	for i, column := range result {
+		columns[i] = relationColumn{name: column.name, typ: column.typ, nullable: column.nullable}
+		if len(cte.Columns) > 0 {
+			columns[i].name = cte.Columns[i].Name
+		}
+	}
+	return columns, nil
+}
+
+// cte finds the columns of a CTE of s or of a query s is nested in
+func (s *scope) cte(name string) ([]relationColumn, bool) {
+	for scope := s; scope != nil; scope = scope.parent {
+		if columns, ok := scope.ctes[name]; ok {
+			return columns, true
+		}
+	}
+	return nil, false
+}
+
+func (s *scope) add(ref types.TableRef, nullable bool) error {
+	if _, ok := s.relation(ref.RefName()); ok {
+		return types.Errorf(ref.Span, "[SELECT] table %s appears more than once in FROM, give it an alias", ref.RefName())
+	}
+
+	rel := relation{name: ref.RefName(), nullable: nullable}
+	if ref.Select != nil {
+		columns, err := s.derivedColumns(ref.Select)
+		if err != nil {
+			return err
+		}
+		rel.columns = columns
+		s.relations = append(s.relations, rel)
+		return nil
+	}
+	if columns, ok := s.cte(ref.Name); ok {
+		rel.columns = columns
+		s.relations = append(s.relations, rel)
+		return nil
+	}
+
+	table, ok := s.schemaTypes[ref.Name].(*parser.Table)
+	if !ok {
+		return types.Errorf(ref.Span, "[SELECT] table '%s' not found in schema", ref.Name)
+	}
+	for _, field := range table.Fields {
+		typ, err := parser.SqlToGoType(field.DataType)
+		rel.columns = append(rel.columns, relationColumn{
+			name:     field.Name,
+			typ:      typ,
+			nullable: !field.NotNull && !field.IsPrimary,
+			err:      err,
+		})
+	}
+	s.relations = append(s.relations, rel)
+	return nil
+}
+
+// derivedColumns types the columns a derived table returns, its query sees
+// the CTEs and the tables of the queries around it but not its siblings in
+// FROM
+func (s *scope) derivedColumns(stmt *types.SelectStatement) ([]relationColumn, error) {
+	outer := &scope{ctes: s.ctes, dialect: s.dialect, schemaTypes: s.schemaTypes, parent: s.parent}
+	inner, err := outer.child(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := inner.queryColumns(stmt)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]relationColumn, len(result))
+	for i, column := range result {
+		columns[i] = relationColumn{name: column.name, typ: column.typ, nullable: column.nullable}
+	}
+	return columns, nil
+}
+
+func (s *scope) relation(name string) (relation, bool) {
+	for _, rel := range s.relations {
+		if rel.name == name {
+			return rel, true
+		}
+	}
+	return relation{}, false
+}
+
+// resolve finds the table and column a column refers to, an unqualified
+// column has to belong to exactly one table unless it was joined with USING.
+// A column no table of s has is looked up in the queries s is nested in.
+func (s *scope) resolve(ref *types.ColumnRef) (relation, relationColumn, error) {
+	if ref.Table != "" {
+		rel, ok := s.relation(ref.Table)
+		if !ok {
+			if s.parent != nil {
+				return s.parent.resolve(ref)
+			}
+			return relation{}, relationColumn{}, fmt.Errorf("[SELECT] unknown table %s in %s", ref.Table, ref.SQL(nil))
+		}
+		column, ok := rel.column(ref.Column)
+		if !ok {
+			return relation{}, relationColumn{}, fmt.Errorf("[SELECT] column %s not found in table %s", ref.Column, rel.name)
+		}
+		return rel, column, nil
+	}
+
+	var visible []string
+	for scope := s; scope != nil; scope = scope.parent {
+		var found []relation
+		var columns []relationColumn
+		for _, rel := range scope.relations {
+			if column, ok := rel.column(ref.Column); ok {
+				found = append(found, rel)
+				columns = append(columns, column)
+			}
+		}
+		switch {
+		case len(found) == 0:
+			if len(scope.relations) > 0 {
+				visible = append(visible, scope.tableNames())
+			}
+			continue
+		case len(found) > 1 && !scope.using[ref.Column]:
+			return relation{}, relationColumn{}, fmt.Errorf("[SELECT] column %s is ambiguous, qualify it with one of %s", ref.Column, scope.tableNames())
+		}
+		return found[0], columns[0], nil
+	}
+	return relation{}, relationColumn{}, fmt.Errorf("[SELECT] column %s not found in %s", ref.Column, strings.Join(visible, ", "))
+}
+
+func (s *scope) tableNames() string {
+	names := make([]string, len(s.relations))
+	for i, rel := range s.relations {
+		names[i] = rel.name
+	}
+	return strings.Join(names, ", ")
+}
+
+func (r relation) column(name string) (relationColumn, bool) {
+	for _, column := range r.columns {
+		if column.name == name {
+			return column, true
+		}
+	}
+	return relationColumn{}, false
+}
+
+// resultColumns types each column of a SELECT list, * and t.* are expanded
+// to the columns of their tables. An aliased column is named after its alias.
+func (s *scope) resultColumns(columns []types.SelectColumn) ([]resultColumn, error) {
+	var result []resultColumn
+	for _, column := range columns {
+		ref, ok := column.Expr.(*types.ColumnRef)
+		if !ok {
+			col, err := s.exprColumn(column)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, col)
+			continue
+		}
+
+		if ref.Column != "*" {
+			rel, relColumn, err := s.resolve(ref)
+			if err != nil {
+				return nil, err
+			}
+			col, err := newResultColumn(rel, relColumn)
+			if err != nil {
+				return nil, err
+			}
+			if column.Alias != "" {
+				col.name = column.Alias
+			}
+			result = append(result, col)
+			continue
+		}
+
+		expanded, err := s.expandStar(ref.Table)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, expanded...)
+	}
+	return result, nil
+}
+
+// queryColumns types the columns stmt returns, s being the scope of stmt
+func (s *scope) queryColumns(stmt *types.SelectStatement) ([]resultColumn, error) {
+	result, err := s.resultColumns(stmt.Columns)
+	if err != nil {
+		return nil, err
+	}
+	return s.compoundColumns(stmt, result)
+}
+
+// compoundColumns checks the queries of a compound against result, the
+// columns of the first query. Each query is typed in a scope of its own and
+// has to return as many columns of compatible types. The result keeps the
+// types of the first query and can be NULL where the rows it keeps can.
+func (s *scope) compoundColumns(stmt *types.SelectStatement, result []resultColumn) ([]resultColumn, error) {
+	if len(stmt.Compounds) == 0 {
+		return result, nil
+	}
+
+	result = append([]resultColumn(nil), result...)
+	outer := &scope{ctes: s.ctes, dialect: s.dialect, schemaTypes: s.schemaTypes, parent: s.parent}
+	for _, compound := range stmt.Compounds {
+		branch, err := outer.child(compound.Select)
+		if err != nil {
+			return nil, err
+		}
+		columns, err := branch.branchColumns(compound.Select.Columns)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) != len(result) {
+			return nil, types.Errorf(compound.Span, "[SELECT] query after %s returns %d columns, the first query returns %d", compound.Op, len(columns), len(result))
+		}
+
+		for i, column := range columns {
+			if column.typ != "" && !compatibleTypes(result[i].typ, column.typ) {
+				return nil, types.Errorf(compound.Span, "[SELECT] column %s is %s in the first query but %s after %s", result[i].name, result[i].typ, column.typ, compound.Op)
+			}
+			switch {
+			case strings.HasPrefix(compound.Op, "UNION"):
+				result[i].nullable = result[i].nullable || column.nullable
+			case strings.HasPrefix(compound.Op, "INTERSECT"):
+				result[i].nullable = result[i].nullable && column.nullable
+			}
+		}
+	}
+	return result, nil
+}
+
+// branchColumns types the columns of a query after UNION, INTERSECT or
+// EXCEPT, where a NULL takes the type of the first query's column
+func (s *scope) branchColumns(columns []types.SelectColumn) ([]resultColumn, error) {
+	var result []resultColumn
+	for _, column := range columns {
+		if literal, ok := column.Expr.(*types.Literal); ok && literal.Kind == types.NullLiteral {
+			result = append(result, resultColumn{nullable: true})
+			continue
+		}
+		typed, err := s.resultColumns([]types.SelectColumn{column})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, typed...)
+	}
+	return result, nil
+}
+
+// compatibleTypes reports whether columns of Go types a and b can be combined
+// by a compound, numbers combine with each other and any with anything
+func compatibleTypes(a, b string) bool {
+	if a == b || a == "any" || b == "any" {
+		return true
+	}
+	return numericTypes[a] && numericTypes[b]
+}
+
+var numericTypes = map[string]bool{"int": true, "int64": true, "float64": true}
+
+// expandStar returns the columns of table.* or of every table for *, a
+// column joined with USING is only returned once
+func (s *scope) expandStar(table string) ([]resultColumn, error) {
+	relations := s.relations
+	if table != "" {
+		rel, ok := s.relation(table)
+		if !ok {
+			return nil, fmt.Errorf("[SELECT] unknown table %s in %s.*", table, table)
+		}
+		relations = []relation{rel}
+	}
+
+	var result []resultColumn
+	seen := make(map[string]bool)
+	for _, rel := range relations {
+		for _, column := range rel.columns {
+			if s.using[column.name] && seen[column.name] {
+				continue
+			}
+			seen[column.name] = true
+
+			col, err := newResultColumn(rel, column)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, col)
+		}
+	}
+	return result, nil
+}
+
+// newResultColumn types column of rel, it can be NULL when the column or its
+// table can
+func newResultColumn(rel relation, column relationColumn) (resultColumn, error) {
+	typ, nullable, err := columnType(rel, column)
+	if err != nil {
+		return resultColumn{}, err
+	}
+	return resultColumn{name: column.name, table: rel.name, typ: typ, nullable: nullable}, nil
+}
+
+// columnType returns the Go type of column and whether it can be NULL
+func columnType(rel relation, column relationColumn) (string, bool, error) {
+	if column.err != nil {
+		return "", false, column.err
+	}
+	return column.typ, rel.nullable || column.nullable, nil
+}
+
+// pointerType is typ or a pointer to it when it can be NULL, any already
+// holds nil
+func pointerType(typ string, nullable bool) string {
+	if nullable && typ != "any" {
+		return "*" + typ
+	}
+	return typ
+}
+
+// exprColumn types a selected expression that is not a plain column, it is
+// named after its alias or the function it calls
+func (s *scope) exprColumn(column types.SelectColumn) (resultColumn, error) {
+	typ, nullable, err := s.exprType(column.Expr)
+	if err != nil {
+		return resultColumn{}, err
+	}
+
+	name := column.Alias
+	if name == "" {
+		name = exprName(column.Expr)
+	}
+	if name == "" {
+		return resultColumn{}, fmt.Errorf("[SELECT] name %s with AS", column.Expr.SQL(parser.Generic.Placeholder))
+	}
+	return resultColumn{name: name, typ: typ, nullable: nullable}, nil
+}
+
+// exprName is the name a database gives a selected expression without an
+// alias, a call is named after its function and a cast after its column
+func exprName(e types.Expr) string {
+	switch e := e.(type) {
+	case *types.FuncCall:
+		return strings.ToLower(e.Name)
+	case *types.CastExpr:
+		if ref, ok := e.Expr.(*types.ColumnRef); ok {
+			return ref.Column
+		}
+	}
+	return ""
+}
+
+// exprType returns the Go type of e and whether it can be NULL, calls are
+// typed from the dialect's function catalog
+func (s *scope) exprType(e types.Expr) (string, bool, error) {
+	switch e := e.(type) {
+	case *types.ColumnRef:
+		rel, field, err := s.resolve(e)
+		if err != nil {
+			return "", false, err
+		}
+		return columnType(rel, field)
+	case *types.ParenExpr:
+		return s.exprType(e.Inner)
+	case *types.BinaryExpr:
+		return s.binaryType(e)
+	case *types.UnaryExpr:
+		return s.unaryType(e)
+	case *types.FuncCall:
+		return s.callType(e)
+	case *types.CastExpr:
+		return s.castType(e)
+	case *types.CaseExpr:
+		return s.caseType(e)
+	case *types.SubqueryExpr:
+		return s.subqueryType(e)
+	case *types.ExistsExpr:
+		if _, err := s.child(e.Select); err != nil {
+			return "", false, err
+		}
+		return "bool", false, nil
+	case *types.Literal:
+		switch e.Kind {
+		case types.StringLiteral:
+			return "string", false, nil
+		case types.IntLiteral:
+			return "int", false, nil
+		case types.FloatLiteral:
+			return "float64", false, nil
+		case types.BoolLiteral:
+			return "bool", false, nil
+		}
+	}
+	return "", false, fmt.Errorf("[SELECT] cannot infer the type of %s", e.SQL(parser.Generic.Placeholder))
+}
+
+// binaryType types arithmetic from its numbers, where a float or int64 side
+// widens the result, || as a string and any other operator as a bool. A
+// param or NULL side takes the type of the other, the result is NULL when
+// either side can be except for IS DISTINCT FROM.
+func (s *scope) binaryType(expr *types.BinaryExpr) (string, bool, error) {
+	sql := expr.SQL(parser.Generic.Placeholder)
+	left, leftNullable, err := s.operandType(expr.Left)
+	if err != nil {
+		return "", false, err
+	}
+	right, rightNullable, err := s.operandType(expr.Right)
+	if err != nil {
+		return "", false, err
+	}
+	nullable := leftNullable || rightNullable
+
+	switch expr.Op {
+	case "+", "-", "*", "/", "%":
+		if left == "" && right == "" {
+			return "", false, fmt.Errorf("[SELECT] cannot infer the type of %s", sql)
+		}
+		for _, typ := range []string{left, right} {
+			if typ != "" && !numericTypes[typ] {
+				return "", false, fmt.Errorf("[SELECT] cannot infer the type of %s, %s needs numbers, got %s", sql, expr.Op, typ)
+			}
+		}
+		switch {
+		case left == "float64" || right == "float64":
+			return "float64", nullable, nil
+		case left == "int64" || right == "int64":
+			return "int64", nullable, nil
+		}
+		return "int", nullable, nil
+	case "||":
+		return "string", nullable, nil
+	case "IS DISTINCT FROM", "IS NOT DISTINCT FROM":
+		return "bool", false, nil
+	}
+	return "bool", nullable, nil
+}
+
+// unaryType types NOT as a bool and a sign as the number it is put on
+func (s *scope) unaryType(expr *types.UnaryExpr) (string, bool, error) {
+	typ, nullable, err := s.operandType(expr.Operand)
+	if err != nil {
+		return "", false, err
+	}
+	if expr.Op == "NOT" {
+		return "bool", nullable, nil
+	}
+	if !numericTypes[typ] {
+		return "", false, fmt.Errorf("[SELECT] cannot infer the type of %s, %s needs a number", expr.SQL(parser.Generic.Placeholder), expr.Op)
+	}
+	return typ, nullable, nil
+}
+
+// operandType types a side of an operator, it is empty for a param, which is
+// typed from the query, and for NULL
+func (s *scope) operandType(e types.Expr) (string, bool, error) {
+	switch e := e.(type) {
+	case *types.Param:
+		return "", false, nil
+	case *types.Literal:
+		if e.Kind == types.NullLiteral {
+			return "", true, nil
+		}
+	}
+	return s.exprType(e)
+}
+
+// callType types a call from its signature, a function without a result
+// type of its own returns the type of its first argument that is not NULL.
+// A window function has to be called with OVER, and only it or an aggregate
+// can be.
+func (s *scope) callType(call *types.FuncCall) (string, bool, error) {
+	sql := call.SQL(parser.Generic.Placeholder)
+	fn, ok := s.dialect.Function(call.Name)
+	if !ok {
+		return "", false, types.Errorf(call.Span, "[SELECT] cannot infer the type of %s, %s is not a known function", sql, strings.ToLower(call.Name))
+	}
+
+	args := len(call.Args)
+	if call.Star {
+		args = 1
+	}
+	if !fn.Accepts(args) {
+		return "", false, types.Errorf(call.Span, "[SELECT] %s cannot be called with %d arguments in %s", fn.Name, args, sql)
+	}
+	switch {
+	case fn.Window && call.Over == nil:
+		return "", false, types.Errorf(call.Span, "[SELECT] %s needs an OVER clause in %s", fn.Name, sql)
+	case call.Over != nil && !fn.Window && !fn.Aggregate:
+		return "", false, types.Errorf(call.Span, "[SELECT] %s is not a window or aggregate function in %s", fn.Name, sql)
+	}
+
+	typ := fn.Type
+	anyNull, allNull := false, true
+	if typ == "" || fn.Nulls == parser.NullIfAnyArg || fn.Nulls == parser.NullIfAllArgs {
+		for _, arg := range call.Args {
+			if literal, ok := arg.(*types.Literal); ok && literal.Kind == types.NullLiteral {
+				anyNull = true
+				continue
+			}
+			// A param is typed from the call, as the offset of lag(score, $1)
+			if _, ok := arg.(*types.Param); ok {
+				allNull = false
+				continue
+			}
+			argType, nullable, err := s.exprType(arg)
+			if err != nil {
+				return "", false, err
+			}
+			if typ == "" {
+				typ = argType
+			}
+			anyNull = anyNull || nullable
+			allNull = allNull && nullable
+		}
+	}
+	if typ == "" {
+		return "", false, types.Errorf(call.Span, "[SELECT] cannot infer the type of %s", sql)
+	}
+
+	switch fn.Nulls {
+	case parser.NeverNull:
+		return typ, false, nil
+	case parser.Nullable:
+		return typ, true, nil
+	case parser.NullIfAllArgs:
+		return typ, allNull, nil
+	default:
+		return typ, anyNull, nil
+	}
+}
+
+// subqueryType types a scalar subquery from its only column, it is NULL when
+// the subquery finds no row
+func (s *scope) subqueryType(subquery *types.SubqueryExpr) (string, bool, error) {
+	child, err := s.child(subquery.Select)
+	if err != nil {
+		return "", false, err
+	}
+	columns, err := child.queryColumns(subquery.Select)
+	if err != nil {
+		return "", false, err
+	}
+	if len(columns) != 1 {
+		return "", false, fmt.Errorf("[SELECT] subquery %s returns %d columns, a scalar subquery returns one", subquery.SQL(parser.Generic.Placeholder), len(columns))
+	}
+	return columns[0].typ, true, nil
+}
+
+// castType types CAST(x AS type) and x::type from the type, which is one of
+// the dialect's or an enum of the schema. It is NULL when x is.
+func (s *scope) castType(cast *types.CastExpr) (string, bool, error) {
+	name, _, _ := strings.Cut(cast.Type, "(")
+	typ, err := s.dialect.GoType(name)
+	if cast.TypeQuoted || err != nil {
+		enumName := name
+		if !cast.TypeQuoted {
+			enumName = strings.ToLower(name)
+		}
+		enum, ok := s.schemaTypes[enumName].(*parser.Enum)
+		if !ok {
+			return "", false, types.Errorf(cast.TypeSpan, "[SELECT] cannot infer the type of %s, %s has no Go type", cast.SQL(parser.Generic.Placeholder), types.QuoteIdent(name, cast.TypeQuoted))
+		}
+		typ = enum.Name
+	}
+	_, nullable, err := s.exprType(cast.Expr)
+	return typ, nullable, err
+}
+
+// caseType types a CASE from its first result that is not NULL, the others
+// have to be compatible with it. It is NULL when a result can be or when it
+// has no ELSE.
+func (s *scope) caseType(expr *types.CaseExpr) (string, bool, error) {
+	var results []types.Expr
+	for _, when := range expr.Whens {
+		results = append(results, when.Result)
+	}
+	if expr.Else != nil {
+		results = append(results, expr.Else)
+	}
+
+	typ, nullable := "", expr.Else == nil
+	for _, result := range results {
+		if literal, ok := result.(*types.Literal); ok && literal.Kind == types.NullLiteral {
+			nullable = true
+			continue
+		}
+		resultType, resultNullable, err := s.exprType(result)
+		if err != nil {
+			return "", false, err
+		}
+		if typ != "" && !compatibleTypes(typ, resultType) {
+			return "", false, types.Errorf(expr.Span, "[SELECT] cannot infer the type of %s, its results are %s and %s", expr.SQL(parser.Generic.Placeholder), typ, resultType)
+		}
+		if typ == "" {
+			typ = resultType
+		}
+		nullable = nullable || resultNullable
+	}
+	if typ == "" {
+		return "", false, types.Errorf(expr.Span, "[SELECT] cannot infer the type of %s", expr.SQL(parser.Generic.Placeholder))
+	}
+	return typ, nullable, nil
+}
+
+// fieldTypes maps each column to its Go type for typing params, qualified
+// as table.column and bare where the first table with the column wins
+func (s *scope) fieldTypes() map[string]string {
+	fieldMap := make(map[string]string)
+	for _, rel := range s.relations {
+		for _, column := range rel.columns {
+			if column.err != nil {
+				continue
+			}
+			fieldMap[rel.name+"."+column.name] = column.typ
+			if _, ok := fieldMap[column.name]; !ok {
+				fieldMap[column.name] = column.typ
+			}
+		}
+	}
+	return fieldMap
+}
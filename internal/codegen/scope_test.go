@@ -0,0 +1,519 @@
+package codegen
+
+import (
+	"shogunc/internal/parser"
+	"shogunc/internal/types"
+	"strings"
+	"testing"
+)
+
+// TestScopeOuterJoinNullability tests columns from the nullable side of an
+// outer join become pointers
+func TestScopeOuterJoinNullability(t *testing.T) {
+	tests := []struct {
+		join string
+		row  string
+	}{
+		{
+			join: "JOIN posts ON posts.user_id = users.id",
+			row:  "Email string `json:\"email\" db:\"email\"` Title string `json:\"title\" db:\"title\"`",
+		},
+		{
+			join: "LEFT OUTER JOIN posts ON posts.user_id = users.id",
+			row:  "Email string `json:\"email\" db:\"email\"` Title *string `json:\"title\" db:\"title\"`",
+		},
+		{
+			join: "RIGHT JOIN posts ON posts.user_id = users.id",
+			row:  "Email *string `json:\"email\" db:\"email\"` Title string `json:\"title\" db:\"title\"`",
+		},
+		{
+			join: "FULL JOIN posts ON posts.user_id = users.id",
+			row:  "Email *string `json:\"email\" db:\"email\"` Title *string `json:\"title\" db:\"title\"`",
+		},
+		{
+			join: "CROSS JOIN posts",
+			row:  "Email string `json:\"email\" db:\"email\"` Title string `json:\"title\" db:\"title\"`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.join, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListPosts", Type: types.MANY}
+			rowSrc, err := generateRowStruct(t, joinSchema(), queryBlock, "SELECT email, title FROM users "+tt.join+";")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := "type ListPostsRow struct { " + tt.row + " }"
+			if rowSrc != expected {
+				t.Errorf("Expected %s, got %s", expected, rowSrc)
+			}
+		})
+	}
+}
+
+// TestScopeStar tests * expands to every joined table and a USING column is
+// returned once
+func TestScopeStar(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "ListPosts", Type: types.MANY}
+	rowSrc, err := generateRowStruct(t, joinSchema(), queryBlock, "SELECT * FROM users JOIN posts USING (id);")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "type ListPostsRow struct { " +
+		"Id int `json:\"id\" db:\"id\"` " +
+		"Email string `json:\"email\" db:\"email\"` " +
+		"UserId int `json:\"user_id\" db:\"user_id\"` " +
+		"Title string `json:\"title\" db:\"title\"` }"
+	if rowSrc != expected {
+		t.Errorf("Expected %s, got %s", expected, rowSrc)
+	}
+}
+
+func TestScopeErrors(t *testing.T) {
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT id FROM users JOIN posts ON posts.user_id = users.id;", "[SELECT] column id is ambiguous, qualify it with one of users, posts"},
+		{"SELECT users.name FROM users JOIN posts ON posts.user_id = users.id;", "[SELECT] column name not found in table users"},
+		{"SELECT p.title FROM users JOIN posts ON posts.user_id = users.id;", "[SELECT] unknown table p in p.title"},
+		{"SELECT title FROM users JOIN comments ON comments.user_id = users.id;", "1:30: [SELECT] table 'comments' not found in schema"},
+		{"SELECT email FROM users JOIN users ON users.id = users.id;", "1:30: [SELECT] table users appears more than once in FROM, give it an alias"},
+		{"SELECT max(posts.body) FROM users JOIN posts ON posts.user_id = users.id;", "[SELECT] column body not found in table posts"},
+		{"SELECT (email) FROM users JOIN posts ON posts.user_id = users.id;", "[SELECT] name (email) with AS"},
+		{"SELECT min(*) FROM users JOIN posts ON posts.user_id = users.id;", "1:8: [SELECT] cannot infer the type of min(*)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListPosts", Type: types.MANY}
+			_, err := generateRowStruct(t, joinSchema(), queryBlock, tt.sql)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// TestScopeAliases tests columns are resolved through table aliases and
+// column aliases name the Row fields
+func TestScopeAliases(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "ListPosts", Type: types.MANY}
+	rowSrc, err := generateRowStruct(t, joinSchema(), queryBlock,
+		"SELECT u.email AS author, p.id AS post_id, a.id FROM users u JOIN posts AS p ON p.user_id = u.id LEFT JOIN users a ON a.id = p.user_id;")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "type ListPostsRow struct { " +
+		"Author string `json:\"author\" db:\"author\"` " +
+		"PostId int `json:\"post_id\" db:\"post_id\"` " +
+		"Id *int `json:\"id\" db:\"id\"` }"
+	if rowSrc != expected {
+		t.Errorf("Expected %s, got %s", expected, rowSrc)
+	}
+
+	_, err = generateRowStruct(t, joinSchema(), queryBlock, "SELECT users.email FROM users u JOIN posts p ON p.user_id = u.id;")
+	if err == nil || err.Error() != "[SELECT] unknown table users in users.email" {
+		t.Errorf("expected the table to only be known by its alias, got %v", err)
+	}
+}
+
+// TestScopeAggregates tests aggregates are typed from the function catalog,
+// every aggregate but COUNT is NULL over no rows
+func TestScopeAggregates(t *testing.T) {
+	tests := []struct {
+		column string
+		field  string
+	}{
+		{"count(*)", "Count int64 `json:\"count\" db:\"count\"`"},
+		{"COUNT(amount) AS total", "Total int64 `json:\"total\" db:\"total\"`"},
+		{"count(DISTINCT status) AS statuses", "Statuses int64 `json:\"statuses\" db:\"statuses\"`"},
+		{"sum(ALL amount) AS total", "Total *float64 `json:\"total\" db:\"total\"`"},
+		{"sum(amount)", "Sum *float64 `json:\"sum\" db:\"sum\"`"},
+		{"AVG(id) AS average", "Average *float64 `json:\"average\" db:\"average\"`"},
+		{"min(created_at)", "Min *time.Time `json:\"min\" db:\"min\"`"},
+		{"max(status) AS last_status", "LastStatus *string `json:\"last_status\" db:\"last_status\"`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "GetStats", Type: types.ONE}
+			rowSrc, err := generateRowStruct(t, complaintSchema(), queryBlock, "SELECT "+tt.column+" FROM complaints;")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expected := "type GetStatsRow struct { " + tt.field + " }"; rowSrc != expected {
+				t.Errorf("Expected %s, got %s", expected, rowSrc)
+			}
+		})
+	}
+}
+
+func contactSchema() map[string]any {
+	return map[string]any{
+		"contacts": &parser.Table{
+			Name: "contacts",
+			Fields: []parser.Field{
+				{Name: "id", DataType: parser.Token{Literal: "INT"}, IsPrimary: true},
+				{Name: "name", DataType: parser.Token{Literal: "TEXT"}, NotNull: true},
+				{Name: "phone", DataType: parser.Token{Literal: "TEXT"}},
+				{Name: "nickname", DataType: parser.Token{Literal: "TEXT"}},
+				{Name: "age", DataType: parser.Token{Literal: "INT"}},
+				{Name: "data", DataType: parser.Token{Literal: "TEXT"}, NotNull: true},
+				{Name: "created_at", DataType: parser.Token{Literal: "TIMESTAMP"}, NotNull: true},
+			},
+		},
+		"Status": &parser.Enum{Name: "Status", Values: []string{"open", "closed"}},
+	}
+}
+
+// TestScopeFunctions tests calls are typed from the dialect's function
+// catalog along with when they can be NULL
+func TestScopeFunctions(t *testing.T) {
+	tests := []struct {
+		dialect parser.Dialect
+		column  string
+		field   string
+	}{
+		{parser.Postgres, "COALESCE(phone, '') AS phone", "Phone string `json:\"phone\" db:\"phone\"`"},
+		{parser.Postgres, "coalesce(NULL, phone, nickname)", "Coalesce *string `json:\"coalesce\" db:\"coalesce\"`"},
+		{parser.Postgres, "lower(name)", "Lower string `json:\"lower\" db:\"lower\"`"},
+		{parser.Postgres, "UPPER(phone) AS shout", "Shout *string `json:\"shout\" db:\"shout\"`"},
+		{parser.Postgres, "length(phone)", "Length *int64 `json:\"length\" db:\"length\"`"},
+		{parser.Postgres, "now() AS fetched_at", "FetchedAt time.Time `json:\"fetched_at\" db:\"fetched_at\"`"},
+		{parser.Postgres, "DATE(created_at)", "Date time.Time `json:\"date\" db:\"date\"`"},
+		{parser.Postgres, "CAST(age AS TEXT)", "Age *string `json:\"age\" db:\"age\"`"},
+		{parser.Postgres, "cast(id AS bigint) AS big_id", "BigId int `json:\"big_id\" db:\"big_id\"`"},
+		{parser.Postgres, "age::text", "Age *string `json:\"age\" db:\"age\"`"},
+		{parser.Postgres, "created_at::date AS day", "Day time.Time `json:\"day\" db:\"day\"`"},
+		{parser.Postgres, "age::double precision AS ratio", "Ratio *float64 `json:\"ratio\" db:\"ratio\"`"},
+		{parser.Postgres, "CAST(id AS character varying(10)) AS code", "Code string `json:\"code\" db:\"code\"`"},
+		{parser.Postgres, "created_at::timestamptz AS at", "At time.Time `json:\"at\" db:\"at\"`"},
+		{parser.Postgres, "CAST(data AS \"Status\") AS status", "Status Status `json:\"status\" db:\"status\"`"},
+		{parser.SQLite, "CAST(age AS REAL) AS ratio", "Ratio *float64 `json:\"ratio\" db:\"ratio\"`"},
+		{parser.SQLite, "CAST(name AS INTEGER) AS n", "N int `json:\"n\" db:\"n\"`"},
+		{parser.Postgres, "CASE WHEN age < 18 THEN 'minor' ELSE 'adult' END AS bracket", "Bracket string `json:\"bracket\" db:\"bracket\"`"},
+		{parser.Postgres, "CASE WHEN age < 18 THEN name END AS minor", "Minor *string `json:\"minor\" db:\"minor\"`"},
+		{parser.Postgres, "CASE age WHEN 0 THEN NULL ELSE age END AS years", "Years *int `json:\"years\" db:\"years\"`"},
+		{parser.Postgres, "CASE WHEN phone IS NULL THEN nickname ELSE phone END AS reach", "Reach *string `json:\"reach\" db:\"reach\"`"},
+		{parser.SQLite, "CASE id WHEN 1 THEN 1 ELSE count(*) END AS total", "Total int `json:\"total\" db:\"total\"`"},
+		{parser.SQLite, "CASE WHEN age > 65 THEN CAST(age AS TEXT) ELSE name END AS label", "Label *string `json:\"label\" db:\"label\"`"},
+		{parser.SQLite, "date(created_at, 'start of month') AS month", "Month *string `json:\"month\" db:\"month\"`"},
+		{parser.SQLite, "json_extract(data, '$.city') AS city", "City any `json:\"city\" db:\"city\"`"},
+		{parser.SQLite, "coalesce(age, 0) AS age", "Age int `json:\"age\" db:\"age\"`"},
+		{parser.Postgres, "ROW_NUMBER() OVER (PARTITION BY age ORDER BY created_at DESC) AS place", "Place int64 `json:\"place\" db:\"place\"`"},
+		{parser.Postgres, "rank() OVER (ORDER BY age)", "Rank int64 `json:\"rank\" db:\"rank\"`"},
+		{parser.Postgres, "lag(name) OVER (ORDER BY id)", "Lag *string `json:\"lag\" db:\"lag\"`"},
+		{parser.Postgres, "lead(age, $1, 0) OVER (ORDER BY id) AS next_age", "NextAge *int `json:\"next_age\" db:\"next_age\"`"},
+		{parser.Postgres, "sum(age) OVER (PARTITION BY name ROWS UNBOUNDED PRECEDING) AS running", "Running *float64 `json:\"running\" db:\"running\"`"},
+		{parser.Postgres, "count(*) OVER () AS total", "Total int64 `json:\"total\" db:\"total\"`"},
+		{parser.SQLite, "percent_rank() OVER (ORDER BY age)", "PercentRank float64 `json:\"percent_rank\" db:\"percent_rank\"`"},
+		{parser.SQLite, "first_value(name) OVER (PARTITION BY age ORDER BY id) AS oldest", "Oldest *string `json:\"oldest\" db:\"oldest\"`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "GetContact", Type: types.ONE}
+			rowSrc, err := generateDialectRowStruct(t, contactSchema(), queryBlock, tt.dialect, "SELECT "+tt.column+" FROM contacts;")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expected := "type GetContactRow struct { " + tt.field + " }"; rowSrc != expected {
+				t.Errorf("Expected %s, got %s", expected, rowSrc)
+			}
+		})
+	}
+}
+
+// TestScopeFunctionErrors tests a call the catalog cannot type is reported at
+// the call instead of dropping the column
+func TestScopeFunctionErrors(t *testing.T) {
+	tests := []struct {
+		dialect parser.Dialect
+		column  string
+		err     string
+	}{
+		{parser.Postgres, "json_extract(data, '$.city')", "1:8: [SELECT] cannot infer the type of json_extract(data, '$.city'), json_extract is not a known function"},
+		{parser.SQLite, "NOW()", "1:8: [SELECT] cannot infer the type of NOW(), now is not a known function"},
+		{parser.Generic, "now()", "1:8: [SELECT] cannot infer the type of now(), now is not a known function"},
+		{parser.Postgres, "lower(name, phone)", "1:8: [SELECT] lower cannot be called with 2 arguments in lower(name, phone)"},
+		{parser.SQLite, "json_extract(data)", "1:8: [SELECT] json_extract cannot be called with 1 arguments in json_extract(data)"},
+		{parser.Postgres, "coalesce(NULL) AS nothing", "1:8: [SELECT] cannot infer the type of coalesce(NULL)"},
+		{parser.Postgres, "CAST(id AS BLOB) AS raw", "1:19: [SELECT] cannot infer the type of CAST(id AS BLOB), BLOB has no Go type"},
+		{parser.Postgres, "lower(title) AS title", "[SELECT] column title not found in contacts"},
+		{parser.Postgres, "row_number() AS place", "1:8: [SELECT] row_number needs an OVER clause in row_number()"},
+		{parser.Postgres, "lower(name) OVER () AS l", "1:8: [SELECT] lower is not a window or aggregate function in lower(name) OVER ()"},
+		{parser.SQLite, "ntile() OVER ()", "1:8: [SELECT] ntile cannot be called with 0 arguments in ntile() OVER ()"},
+		{parser.Postgres, "lag(age, 1, 0, 2) OVER ()", "1:8: [SELECT] lag cannot be called with 4 arguments in lag(age, 1, 0, 2) OVER ()"},
+		{parser.Postgres, "id::blob AS raw", "1:12: [SELECT] cannot infer the type of id::BLOB, BLOB has no Go type"},
+		{parser.Postgres, "data::\"Mood\" AS mood", "1:14: [SELECT] cannot infer the type of data::\"Mood\", \"Mood\" has no Go type"},
+		{parser.Postgres, "data::\"status\" AS mood", "1:14: [SELECT] cannot infer the type of data::\"status\", \"status\" has no Go type"},
+		{parser.SQLite, "CAST(id AS timestamptz) AS at", "1:19: [SELECT] cannot infer the type of CAST(id AS TIMESTAMPTZ), TIMESTAMPTZ has no Go type"},
+		{parser.Postgres, "CASE WHEN age > 65 THEN age ELSE name END AS label", "1:8: [SELECT] cannot infer the type of CASE WHEN age > 65 THEN age ELSE name END, its results are int and string"},
+		{parser.Postgres, "CASE WHEN age > 65 THEN NULL END AS nothing", "1:8: [SELECT] cannot infer the type of CASE WHEN age > 65 THEN NULL END"},
+		{parser.Postgres, "CASE WHEN age > 65 THEN title ELSE name END AS label", "[SELECT] column title not found in contacts"},
+		{parser.Postgres, "CASE WHEN age > 65 THEN 'old' END", "[SELECT] name CASE WHEN age > 65 THEN 'old' END with AS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "GetContact", Type: types.ONE}
+			_, err := generateDialectRowStruct(t, contactSchema(), queryBlock, tt.dialect, "SELECT "+tt.column+" FROM contacts;")
+			if err == nil || !strings.HasSuffix(err.Error(), tt.err) {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// TestScopeOperators tests arithmetic is typed from its numbers, || as a
+// string and comparisons as a bool
+func TestScopeOperators(t *testing.T) {
+	tests := []struct {
+		column string
+		field  string
+	}{
+		{"id * 2 AS double", "Double int `json:\"double\" db:\"double\"`"},
+		{"age + 1 AS next_age", "NextAge *int `json:\"next_age\" db:\"next_age\"`"},
+		{"id * 1.5 AS price", "Price float64 `json:\"price\" db:\"price\"`"},
+		{"count(*) + id AS total", "Total int64 `json:\"total\" db:\"total\"`"},
+		{"(id - $1) % 7 AS bucket", "Bucket int `json:\"bucket\" db:\"bucket\"`"},
+		{"-id AS negated", "Negated int `json:\"negated\" db:\"negated\"`"},
+		{"name || ' ' || nickname AS full_name", "FullName *string `json:\"full_name\" db:\"full_name\"`"},
+		{"name || id AS label", "Label string `json:\"label\" db:\"label\"`"},
+		{"age >= 18 AS adult", "Adult *bool `json:\"adult\" db:\"adult\"`"},
+		{"NOT (id = 1) AS other", "Other bool `json:\"other\" db:\"other\"`"},
+		{"phone IS DISTINCT FROM nickname AS differs", "Differs bool `json:\"differs\" db:\"differs\"`"},
+		{"CASE WHEN age > 65 THEN id * 0.5 ELSE id END AS fee", "Fee float64 `json:\"fee\" db:\"fee\"`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "GetContact", Type: types.ONE}
+			rowSrc, err := generateDialectRowStruct(t, contactSchema(), queryBlock, parser.Postgres, "SELECT "+tt.column+" FROM contacts;")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expected := "type GetContactRow struct { " + tt.field + " }"; rowSrc != expected {
+				t.Errorf("Expected %s, got %s", expected, rowSrc)
+			}
+		})
+	}
+
+	errs := []struct {
+		column string
+		err    string
+	}{
+		{"name * 2 AS twice", "[SELECT] cannot infer the type of name * 2, * needs numbers, got string"},
+		{"created_at - id AS earlier", "[SELECT] cannot infer the type of created_at - id, - needs numbers, got time.Time"},
+		{"$1 + $2 AS total", "[SELECT] cannot infer the type of $1 + $2"},
+		{"-name AS negated", "[SELECT] cannot infer the type of -name, - needs a number"},
+	}
+	for _, tt := range errs {
+		t.Run(tt.column, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "GetContact", Type: types.ONE}
+			_, err := generateDialectRowStruct(t, contactSchema(), queryBlock, parser.Postgres, "SELECT "+tt.column+" FROM contacts;")
+			if err == nil || !strings.HasSuffix(err.Error(), tt.err) {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// TestScopeSubqueries tests a subquery resolves its own tables before the
+// outer query's and a scalar subquery is NULL when it finds no row
+func TestScopeSubqueries(t *testing.T) {
+	tests := []struct {
+		sql string
+		row string
+	}{
+		{
+			"SELECT email, (SELECT title FROM posts WHERE posts.user_id = users.id LIMIT 1) AS latest FROM users;",
+			"Email string `json:\"email\" db:\"email\"` Latest *string `json:\"latest\" db:\"latest\"`",
+		},
+		{
+			"SELECT id, (SELECT max(id) FROM users WHERE id < u.id) AS previous FROM users u;",
+			"Id int `json:\"id\" db:\"id\"` Previous *int `json:\"previous\" db:\"previous\"`",
+		},
+		{
+			"SELECT email, EXISTS (SELECT 1 FROM posts WHERE user_id = users.id) AS has_posts FROM users;",
+			"Email string `json:\"email\" db:\"email\"` HasPosts bool `json:\"has_posts\" db:\"has_posts\"`",
+		},
+		{
+			"SELECT p.* FROM (SELECT id, title AS headline FROM posts) p;",
+			"Id int `json:\"id\" db:\"id\"` Headline string `json:\"headline\" db:\"headline\"`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListUsers", Type: types.MANY}
+			rowSrc, err := generateRowStruct(t, joinSchema(), queryBlock, tt.sql)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expected := "type ListUsersRow struct { " + tt.row + " }"; rowSrc != expected {
+				t.Errorf("Expected %s, got %s", expected, rowSrc)
+			}
+		})
+	}
+}
+
+func TestScopeSubqueryErrors(t *testing.T) {
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT (SELECT id, title FROM posts LIMIT 1) AS post FROM users;", "[SELECT] subquery (SELECT id, title FROM posts LIMIT 1) returns 2 columns, a scalar subquery returns one"},
+		{"SELECT (SELECT body FROM posts LIMIT 1) AS body FROM users;", "[SELECT] column body not found in posts, users"},
+		{"SELECT p.title FROM users JOIN (SELECT title, users.email FROM posts) p ON true;", "[SELECT] unknown table users in users.email"},
+		{"SELECT (SELECT id FROM posts LIMIT 1) FROM users;", "[SELECT] name (SELECT id FROM posts LIMIT 1) with AS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListUsers", Type: types.MANY}
+			_, err := generateRowStruct(t, joinSchema(), queryBlock, tt.sql)
+			if err == nil || !strings.HasSuffix(err.Error(), tt.err) {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// TestScopeWith tests CTEs are found before tables of the schema, named by
+// their column list and visible to the CTEs and subqueries after them
+func TestScopeWith(t *testing.T) {
+	tests := []struct {
+		sql string
+		row string
+	}{
+		{
+			"WITH t (uid, headline) AS (SELECT user_id, title FROM posts) SELECT * FROM t;",
+			"Uid int `json:\"uid\" db:\"uid\"` Headline string `json:\"headline\" db:\"headline\"`",
+		},
+		{
+			"WITH a AS (SELECT id, email FROM users), b AS (SELECT a.email FROM a) SELECT email FROM b;",
+			"Email string `json:\"email\" db:\"email\"`",
+		},
+		{
+			"WITH posts AS (SELECT id, email AS title FROM users) SELECT title FROM posts;",
+			"Title string `json:\"title\" db:\"title\"`",
+		},
+		{
+			"WITH a AS (SELECT id FROM users) SELECT title, (SELECT max(id) FROM a) AS top FROM posts;",
+			"Title string `json:\"title\" db:\"title\"` Top *int `json:\"top\" db:\"top\"`",
+		},
+		{
+			"WITH a AS (SELECT user_id FROM posts) SELECT d.user_id FROM (SELECT user_id FROM a) d;",
+			"UserId int `json:\"user_id\" db:\"user_id\"`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListUsers", Type: types.MANY}
+			rowSrc, err := generateRowStruct(t, joinSchema(), queryBlock, tt.sql)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expected := "type ListUsersRow struct { " + tt.row + " }"; rowSrc != expected {
+				t.Errorf("Expected %s, got %s", expected, rowSrc)
+			}
+		})
+	}
+}
+
+func TestScopeWithErrors(t *testing.T) {
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"WITH t (a, b) AS (SELECT id FROM users) SELECT a FROM t;", "[SELECT] t names 2 columns but its query returns 1"},
+		{"WITH a AS (SELECT id FROM b), b AS (SELECT id FROM users) SELECT id FROM a;", "[SELECT] table 'b' not found in schema"},
+		{"WITH a AS (SELECT id FROM users) SELECT email FROM a;", "[SELECT] column email not found in a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListUsers", Type: types.MANY}
+			_, err := generateRowStruct(t, joinSchema(), queryBlock, tt.sql)
+			if err == nil || !strings.HasSuffix(err.Error(), tt.err) {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestScopeCompound(t *testing.T) {
+	tests := []struct {
+		sql string
+		row string
+	}{
+		{
+			"SELECT id, email FROM users UNION SELECT user_id, title FROM posts;",
+			"Id int `json:\"id\" db:\"id\"` Email string `json:\"email\" db:\"email\"`",
+		},
+		{
+			"SELECT id, email FROM users UNION ALL SELECT id, NULL FROM posts;",
+			"Id int `json:\"id\" db:\"id\"` Email *string `json:\"email\" db:\"email\"`",
+		},
+		{
+			"SELECT id, email FROM users EXCEPT SELECT id, NULL FROM posts;",
+			"Id int `json:\"id\" db:\"id\"` Email string `json:\"email\" db:\"email\"`",
+		},
+		{
+			"SELECT u.id, p.title FROM users u LEFT JOIN posts p ON p.user_id = u.id INTERSECT SELECT id, title FROM posts;",
+			"Id int `json:\"id\" db:\"id\"` Title string `json:\"title\" db:\"title\"`",
+		},
+		{
+			"SELECT user_id, count(*) AS total FROM posts GROUP BY user_id UNION SELECT id, sum(id) FROM users GROUP BY id;",
+			"UserId int `json:\"user_id\" db:\"user_id\"` Total *int64 `json:\"total\" db:\"total\"`",
+		},
+		{
+			"WITH RECURSIVE chain (id, parent) AS (SELECT id, user_id FROM posts WHERE id = 1 UNION ALL SELECT p.id, p.user_id FROM posts p JOIN chain c ON p.id = c.parent) SELECT id, parent FROM chain;",
+			"Id int `json:\"id\" db:\"id\"` Parent int `json:\"parent\" db:\"parent\"`",
+		},
+		{
+			"SELECT id, (SELECT title FROM posts UNION SELECT email FROM users) AS name FROM users;",
+			"Id int `json:\"id\" db:\"id\"` Name *string `json:\"name\" db:\"name\"`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListUsers", Type: types.MANY}
+			rowSrc, err := generateRowStruct(t, joinSchema(), queryBlock, tt.sql)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expected := "type ListUsersRow struct { " + tt.row + " }"; rowSrc != expected {
+				t.Errorf("Expected %s, got %s", expected, rowSrc)
+			}
+		})
+	}
+}
+
+func TestScopeCompoundErrors(t *testing.T) {
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT id, email FROM users UNION SELECT id FROM posts;", "[SELECT] query after UNION returns 1 columns, the first query returns 2"},
+		{"SELECT id FROM users UNION ALL SELECT title FROM posts;", "[SELECT] column id is int in the first query but string after UNION ALL"},
+		{"SELECT id FROM users UNION SELECT id FROM admins;", "[SELECT] table 'admins' not found in schema"},
+		{"SELECT id FROM users UNION SELECT email FROM posts;", "[SELECT] column email not found in posts"},
+		{"WITH t AS (SELECT id FROM users UNION SELECT id FROM t) SELECT id FROM t;", "[SELECT] table 't' not found in schema"},
+		{"WITH RECURSIVE t AS (SELECT id FROM t UNION SELECT id FROM users) SELECT id FROM t;", "[SELECT] table 't' not found in schema"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListUsers", Type: types.MANY}
+			_, err := generateRowStruct(t, joinSchema(), queryBlock, tt.sql)
+			if err == nil || !strings.HasSuffix(err.Error(), tt.err) {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"shogunc/internal/parser"
 	"shogunc/internal/types"
 	"shogunc/utils"
+	"strconv"
 	"strings"
 
 	"github.com/hector3211/shogun"
@@ -15,19 +16,31 @@ import (
 type SelectGenerator struct {
 	schemaTypes map[string]any
 	queryblock  *types.QueryBlock
+	dialect     parser.Dialect
+	imports     map[string]bool // Packages the generated code uses
 }
 
 func NewSelectGenerator(types map[string]any, queryBlock *types.QueryBlock) *SelectGenerator {
-	return &SelectGenerator{schemaTypes: types, queryblock: queryBlock}
+	return &SelectGenerator{schemaTypes: types, queryblock: queryBlock, imports: make(map[string]bool)}
+}
+
+func (g *SelectGenerator) SetDialect(dialect parser.Dialect) *SelectGenerator {
+	g.dialect = dialect
+	return g
 }
 
 func (g SelectGenerator) GenerateSelectFunc(astStmt *types.SelectStatement, isMany bool) (*ast.FuncDecl, *ast.GenDecl, error) {
 	paramStruct, paramTypeName, err := g.generateSelectParamStruct(astStmt)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, types.WrapError(astStmt.TableSpan, err)
 	}
 
-	returnType := g.generateReturnType(astStmt.TableName, isMany)
+	if g.returnsRow(astStmt) {
+		if _, err := g.rowColumns(astStmt); err != nil {
+			return nil, nil, err
+		}
+	}
+	returnType := g.returnType(astStmt, isMany)
 
 	params := []*ast.Field{
 		{
@@ -78,6 +91,7 @@ func (g SelectGenerator) generateFunctionBody(astStmt *types.SelectStatement, is
 
 	queryStmt := g.generateSelectQuery(astStmt)
 	stmts = append(stmts, queryStmt)
+	stmts = append(stmts, g.generateSelectParamArgs(astStmt).prep...)
 
 	resultDecl := g.generateResultDecl(astStmt, isMany)
 	resultStmt := &ast.DeclStmt{Decl: resultDecl}
@@ -101,19 +115,16 @@ func (g SelectGenerator) generateManyQueryStmts(astStmt *types.SelectStatement,
 	var stmts []ast.Stmt
 
 	// Generate arguments: (ctx, query, params...)
-	args := []ast.Expr{ast.NewIdent("ctx"), ast.NewIdent("query")}
+	var args queryArgs
 	if hasParams {
-		args = append(args, g.generateSelectParamArgs(astStmt)...)
+		args = g.generateSelectParamArgs(astStmt)
 	}
 
 	// Use Query for :many queries
-	queryCall := &ast.CallExpr{
-		Fun: &ast.SelectorExpr{
-			X:   &ast.SelectorExpr{X: ast.NewIdent("q"), Sel: ast.NewIdent("db")},
-			Sel: ast.NewIdent("Query"),
-		},
-		Args: args,
-	}
+	queryCall := args.call(&ast.SelectorExpr{
+		X:   &ast.SelectorExpr{X: ast.NewIdent("q"), Sel: ast.NewIdent("db")},
+		Sel: ast.NewIdent("Query"),
+	})
 
 	// rows, err := q.db.Query(...)
 	queryStmt := &ast.AssignStmt{
@@ -200,7 +211,7 @@ func (g SelectGenerator) generateManyLoopBody(astStmt *types.SelectStatement) []
 	var stmts []ast.Stmt
 
 	// Declare loop variable: var item <Type>
-	singleType := g.generateReturnType(astStmt.TableName, true)
+	singleType := g.returnType(astStmt, true)
 	typeName := utils.TypeToString(singleType)
 	typeName = strings.TrimPrefix(typeName, "[]")
 
@@ -291,71 +302,25 @@ func (g SelectGenerator) generateReturnStmt() *ast.ReturnStmt {
 }
 
 func (g SelectGenerator) generateSelectParamStruct(astStmt *types.SelectStatement) (*ast.GenDecl, string, error) {
-	fieldMap, err := g.inferDataType(astStmt.TableName) // Extract data type and its column types
+	fieldMap, err := g.paramTypes(astStmt) // Extract data type and its column types
 	if err != nil {
 		return nil, "", err
 	}
 
-	type paramInfo struct {
-		name string
-		typ  string
-		pos  int
-	}
-
-	var paramList []paramInfo
-	for _, condition := range astStmt.Conditions {
-		if condition.Value.Position != 0 {
-			// Normalize column name to lowercase for lookup
-			normalizedColumn := strings.ToLower(condition.Value.Column)
-			goType := fieldMap[normalizedColumn]
-			paramName := utils.ToPascalCase(condition.Value.Column)
-			paramList = append(paramList, paramInfo{
-				name: paramName,
-				typ:  goType,
-				pos:  condition.Value.Position,
-			})
-		}
-	}
-
 	typeName := fmt.Sprintf("%sParams", g.queryblock.Name)
 
-	if len(paramList) == 0 {
+	params := collectParams(g.binds(astStmt), fieldMap)
+	if len(params) == 0 {
 		return nil, "", nil
 	}
 
-	// Sort params by position
-	for i := range len(paramList) - 1 {
-		for j := i + 1; j < len(paramList); j++ {
-			if paramList[i].pos > paramList[j].pos {
-				paramList[i], paramList[j] = paramList[j], paramList[i]
-			}
-		}
-	}
-
-	var fields []*ast.Field
-	for _, param := range paramList {
-		fieldName := utils.ToProperPascalCase(param.name) // Use proper PascalCase (no underscores)
-		// Convert original param name to snake_case for JSON tag
-		jsonTag := utils.ToSnakeCase(param.name)
-		field := &ast.Field{
-			Names: []*ast.Ident{ast.NewIdent(fieldName)}, // Proper PascalCase for Go field name
-			Type:  ast.NewIdent(param.typ),
-			Tag: &ast.BasicLit{
-				Kind:  token.STRING,
-				Value: fmt.Sprintf("`json:\"%s\"`", jsonTag), // Use snake_case for JSON tag
-			},
-		}
-
-		fields = append(fields, field)
-	}
-
 	paramStructDecl := &ast.GenDecl{
 		Tok: token.TYPE,
 		Specs: []ast.Spec{
 			&ast.TypeSpec{
 				Name: ast.NewIdent(typeName),
 				Type: &ast.StructType{
-					Fields: &ast.FieldList{List: fields},
+					Fields: &ast.FieldList{List: paramStructFields(params)},
 				},
 			},
 		},
@@ -364,8 +329,47 @@ func (g SelectGenerator) generateSelectParamStruct(astStmt *types.SelectStatemen
 	return paramStructDecl, typeName, nil
 }
 
+// binds returns the params of every clause and subquery in the order they
+// are written
+func (g SelectGenerator) binds(astStmt *types.SelectStatement) []types.Bind {
+	return types.SelectBinds(astStmt)
+}
+
+// paramTypes maps the columns params can be compared with to their Go types.
+// The tables of subqueries are added after the query's own, so a column the
+// query has wins over one of the same name in a subquery.
+func (g SelectGenerator) paramTypes(astStmt *types.SelectStatement) (map[string]string, error) {
+	var subqueries []*types.SelectStatement
+	types.WalkSelect(astStmt, func(node types.Expr) {
+		if subquery, ok := node.(*types.SubqueryExpr); ok {
+			subqueries = append(subqueries, subquery.Select)
+		}
+	})
+	if len(astStmt.Joins) == 0 && len(subqueries) == 0 {
+		return g.inferDataType(astStmt.TableName)
+	}
+
+	scope, err := newScope(g.schemaTypes, astStmt, g.dialect)
+	if err != nil {
+		return nil, err
+	}
+	fieldMap := scope.fieldTypes()
+	for _, subquery := range subqueries {
+		inner, err := scope.child(subquery)
+		if err != nil {
+			return nil, err
+		}
+		for column, typ := range inner.fieldTypes() {
+			if _, ok := fieldMap[column]; !ok {
+				fieldMap[column] = typ
+			}
+		}
+	}
+	return fieldMap, nil
+}
+
 func (g SelectGenerator) generateResultDecl(astStmt *types.SelectStatement, isMany bool) *ast.GenDecl {
-	resultType := g.generateReturnType(astStmt.TableName, isMany)
+	resultType := g.returnType(astStmt, isMany)
 
 	var typeName string
 	if ident, ok := resultType.(*ast.Ident); ok {
@@ -392,41 +396,72 @@ func (g SelectGenerator) generateResultDecl(astStmt *types.SelectStatement, isMa
 }
 
 func (g SelectGenerator) generateSelectQuery(astStmt *types.SelectStatement) ast.Stmt {
+	params := g.queryParams(astStmt)
 	queryBuilder := shogun.NewSelectBuilder()
-	if len(astStmt.Columns) == 1 && astStmt.Columns[0] == "*" {
-		queryBuilder.Select("*")
-	} else {
-		queryBuilder.Select(strings.Join(astStmt.Columns, ","))
+	if astStmt.Distinct {
+		queryBuilder.Distinct()
 	}
-	queryBuilder.From(astStmt.TableName)
+	var columns []string
+	for _, column := range astStmt.Columns {
+		columns = append(columns, params.column(column))
+	}
+	queryBuilder.Select(strings.Join(columns, ","))
 
-	conditions := make([]string, 0)
-	for _, c := range astStmt.Conditions {
-		if c.ChainOp != types.Illegal {
-			conditions = append(conditions, g.shoguncNextOp(c.ChainOp))
-		}
-		conditions = append(conditions, g.shoguncConditionalOp(c))
+	from := []string{params.table(astStmt.Table())}
+	for _, join := range astStmt.Joins {
+		from = append(from, params.join(join))
 	}
+	queryBuilder.From(strings.Join(from, " "))
 
-	if len(conditions) > 0 {
-		queryBuilder.Where(conditions...)
+	if astStmt.Where != nil {
+		queryBuilder.Where(params.sql(astStmt.Where))
 	}
 
-	if astStmt.Limit != 0 {
-		queryBuilder.Limit(astStmt.Limit)
+	// shogun orders by a single direction and has no OFFSET, so GROUP BY and
+	// what follows it are written after the built query to keep them in order.
+	// ORDER BY and what follows it apply to the queries of a compound too.
+	var tail []string
+	if len(astStmt.GroupBy) > 0 {
+		var exprs []string
+		for _, expr := range astStmt.GroupBy {
+			exprs = append(exprs, params.sql(expr))
+		}
+		tail = append(tail, "GROUP BY "+strings.Join(exprs, ", "))
+	}
+	if astStmt.Having != nil {
+		tail = append(tail, "HAVING "+params.sql(astStmt.Having))
+	}
+	for _, compound := range astStmt.Compounds {
+		tail = append(tail, params.compound(compound))
+	}
+	if len(astStmt.OrderBy) > 0 {
+		var terms []string
+		for _, term := range astStmt.OrderBy {
+			terms = append(terms, params.orderTerm(term))
+		}
+		tail = append(tail, "ORDER BY "+strings.Join(terms, ", "))
+	}
+	if astStmt.Limit != nil {
+		tail = append(tail, "LIMIT "+params.sql(astStmt.Limit))
+	}
+	if astStmt.Offset != nil {
+		tail = append(tail, "OFFSET "+params.sql(astStmt.Offset))
 	}
 
 	sql := queryBuilder.Build()
-
-	// Clean up any quoted bind parameters that the shogun library might add
-	sql = utils.CleanBindParam(sql)
+	if len(tail) > 0 {
+		sql = strings.TrimSuffix(sql, ";") + " " + strings.Join(tail, " ") + ";"
+	}
+	if with := params.with(astStmt); with != "" {
+		sql = with + " " + sql
+	}
 
 	return &ast.AssignStmt{
 		Lhs: []ast.Expr{ast.NewIdent("query")},
 		Tok: token.DEFINE,
 		Rhs: []ast.Expr{&ast.BasicLit{
 			Kind:  token.STRING,
-			Value: fmt.Sprintf("`%s`", sql),
+			Value: sqlStringLit(sql),
 		}},
 	}
 }
@@ -434,16 +469,10 @@ func (g SelectGenerator) generateSelectQuery(astStmt *types.SelectStatement) ast
 func (g SelectGenerator) generateSelectDbQuery(astStmt *types.SelectStatement, hasParams bool) []ast.Stmt {
 	var stmts []ast.Stmt
 
-	args := []ast.Expr{ast.NewIdent("ctx"), ast.NewIdent("query")}
-	args = append(args, g.generateSelectParamArgs(astStmt)...)
-
-	queryRowCall := &ast.CallExpr{
-		Fun: &ast.SelectorExpr{
-			X:   &ast.SelectorExpr{X: ast.NewIdent("q"), Sel: ast.NewIdent("db")},
-			Sel: ast.NewIdent("QueryRow"),
-		},
-		Args: args,
-	}
+	queryRowCall := g.generateSelectParamArgs(astStmt).call(&ast.SelectorExpr{
+		X:   &ast.SelectorExpr{X: ast.NewIdent("q"), Sel: ast.NewIdent("db")},
+		Sel: ast.NewIdent("QueryRow"),
+	})
 
 	// Create row variable: row, err := q.db.QueryRow(...)
 	rowDecl := &ast.AssignStmt{
@@ -464,7 +493,7 @@ func (g SelectGenerator) generateSelectDbQuery(astStmt *types.SelectStatement, h
 			List: []ast.Stmt{
 				&ast.ReturnStmt{
 					Results: []ast.Expr{
-						g.generateZeroValue(astStmt.TableName, false),
+						g.generateZeroValue(astStmt),
 						ast.NewIdent("err"),
 					},
 				},
@@ -490,67 +519,40 @@ func (g SelectGenerator) generateSelectDbQuery(astStmt *types.SelectStatement, h
 	return stmts
 }
 
-func (g SelectGenerator) generateZeroValue(tableName string, isMany bool) ast.Expr {
-	if isMany {
-		return &ast.CompositeLit{
-			Type: &ast.ArrayType{
-				Elt: ast.NewIdent("User"), // This should be dynamic based on tableName
-			},
-		}
-	}
-	return ast.NewIdent("User{}") // This should be dynamic based on tableName
+// generateZeroValue is the empty result a :one query returns with an error
+func (g SelectGenerator) generateZeroValue(astStmt *types.SelectStatement) ast.Expr {
+	return &ast.CompositeLit{Type: g.returnType(astStmt, false)}
 }
 
-func (g SelectGenerator) generateSelectParamArgs(astStmt *types.SelectStatement) []ast.Expr {
-	type paramInfo struct {
-		name string
-		typ  string
-		pos  int
-	}
-
-	var paramList []paramInfo
-
-	for _, condition := range astStmt.Conditions {
-		if condition.Value.Position != 0 {
-			columnNameToLower := strings.ToLower(condition.Value.Column)
-			fieldMap, _ := g.inferDataType(astStmt.TableName)
-			goType := fieldMap[columnNameToLower]
-			paramName := utils.ToProperPascalCase(condition.Value.Column)
-
-			paramList = append(paramList, paramInfo{
-				name: paramName,
-				typ:  goType,
-				pos:  condition.Value.Position,
-			})
-		}
-	}
-
-	// Sort by position
-	for i := range len(paramList) - 1 {
-		for j := i + 1; j < len(paramList); j++ {
-			if paramList[i].pos > paramList[j].pos {
-				paramList[i], paramList[j] = paramList[j],
-					paramList[i]
-			}
-		}
-	}
-
-	var args []ast.Expr
-	for _, param := range paramList {
-		args = append(args, &ast.SelectorExpr{
-			X:   ast.NewIdent("params"),
-			Sel: ast.NewIdent(param.name),
-		})
-	}
-
+func (g SelectGenerator) generateSelectParamArgs(astStmt *types.SelectStatement) queryArgs {
+	args := g.queryParams(astStmt).args()
+	args.addImports(g.imports)
 	return args
 }
 
+func (g SelectGenerator) queryParams(astStmt *types.SelectStatement) queryParams {
+	fieldMap, _ := g.paramTypes(astStmt)
+	return newQueryParams(g.dialect, g.binds(astStmt), fieldMap)
+}
+
 func (g SelectGenerator) generateScanArgs(astStmt *types.SelectStatement) []ast.Expr {
 	var scanArgs []ast.Expr
 	var columns []string
 
-	if len(astStmt.Columns) == 1 && astStmt.Columns[0] == "*" {
+	if g.returnsRow(astStmt) {
+		for _, field := range g.rowFields(astStmt) {
+			scanArgs = append(scanArgs, &ast.UnaryExpr{
+				Op: token.AND,
+				X: &ast.SelectorExpr{
+					X:   ast.NewIdent("result"),
+					Sel: ast.NewIdent(field.name),
+				},
+			})
+		}
+		return scanArgs
+	}
+
+	if len(astStmt.Columns) == 1 && isStar(astStmt.Columns[0].Expr) {
 		// SELECT * case - get all columns from schema
 		fieldMap, err := g.inferDataType(astStmt.TableName)
 		if err != nil {
@@ -573,7 +575,11 @@ func (g SelectGenerator) generateScanArgs(astStmt *types.SelectStatement) []ast.
 
 		columns = columnNames
 	} else {
-		columns = astStmt.Columns
+		for _, column := range astStmt.Columns {
+			if ref, ok := column.Expr.(*types.ColumnRef); ok {
+				columns = append(columns, ref.Column)
+			}
+		}
 	}
 
 	// Generate &result.FieldName expressions for each column
@@ -594,84 +600,135 @@ func (g SelectGenerator) generateScanArgs(astStmt *types.SelectStatement) []ast.
 	return scanArgs
 }
 
-func (g SelectGenerator) generateReturnType(typeName string, isMany bool) ast.Expr {
-	// Generate struct name from table name
-	tableName := strings.TrimSuffix(typeName, "s") // Remove plural 's'
-	structName := utils.Capitalize(tableName)
+// rowField is one field of a generated <QueryName>Row struct
+type rowField struct {
+	name   string // Go field name
+	tag    string // json tag
+	column resultColumn
+}
 
-	if isMany {
-		return &ast.ArrayType{
-			Elt: ast.NewIdent(structName),
+// returnsRow reports whether the query returns a <QueryName>Row struct
+// instead of its table's struct, which is the case for CTEs, compounds,
+// joins, derived tables, aliased columns and anything selected that is not a
+// column
+func (g SelectGenerator) returnsRow(astStmt *types.SelectStatement) bool {
+	if len(astStmt.With) > 0 || len(astStmt.Compounds) > 0 || len(astStmt.Joins) > 0 || astStmt.TableSelect != nil {
+		return true
+	}
+	for _, column := range astStmt.Columns {
+		if _, ok := column.Expr.(*types.ColumnRef); !ok || column.Alias != "" {
+			return true
 		}
 	}
-	return ast.NewIdent(structName)
+	return false
 }
 
-func (g SelectGenerator) shoguncNextOp(nextOp types.LogicalOp) string {
-	switch nextOp {
-	case types.And:
-		return shogun.And()
-	case types.Or:
-		return shogun.Or()
+// returnType is the struct a query returns one of, or a slice of for :many
+func (g SelectGenerator) returnType(astStmt *types.SelectStatement, isMany bool) ast.Expr {
+	if !g.returnsRow(astStmt) {
+		return g.generateReturnType(astStmt.TableName, isMany)
 	}
-	return ""
+
+	rowType := ast.NewIdent(g.queryblock.Name + "Row")
+	if isMany {
+		return &ast.ArrayType{Elt: rowType}
+	}
+	return rowType
 }
 
-func (g SelectGenerator) shoguncConditionalOp(cond types.Condition) string {
-	// Use lowercase column name for consistency with database schema
-	columnName := strings.ToLower(cond.Column)
-
-	if cond.Value.Position != 0 {
-		return fmt.Sprintf("%s = $%d", columnName, cond.Value.Position)
-	} else if cond.Value.Value != nil {
-		// For literal values, use shogun functions
-		switch cond.Operator {
-		case types.EQUAL:
-			return shogun.Equal(columnName, *cond.Value.Value)
-		case types.NOTEQUAL:
-			return shogun.NotEqual(columnName, *cond.Value.Value)
-		case types.LESSTHAN:
-			return shogun.LessThan(columnName, *cond.Value.Value)
-		case types.GREATERTHAN:
-			return shogun.GreaterThan(columnName, *cond.Value.Value)
-		}
-	} else {
-		// Fallback for NULL values
-		switch cond.Operator {
-		case types.EQUAL:
-			return fmt.Sprintf("%s IS NULL", columnName)
-		case types.NOTEQUAL:
-			return fmt.Sprintf("%s IS NOT NULL", columnName)
+func (g SelectGenerator) rowColumns(astStmt *types.SelectStatement) ([]resultColumn, error) {
+	scope, err := newScope(g.schemaTypes, astStmt, g.dialect)
+	if err != nil {
+		return nil, err
+	}
+	return scope.queryColumns(astStmt)
+}
+
+// rowFields names the fields of the Row struct after their columns, a column
+// name selected twice is prefixed with its table
+func (g SelectGenerator) rowFields(astStmt *types.SelectStatement) []rowField {
+	columns, _ := g.rowColumns(astStmt)
+
+	counts := make(map[string]int)
+	for _, column := range columns {
+		counts[column.name]++
+	}
+
+	var fields []rowField
+	for _, column := range columns {
+		source := column.name
+		if counts[column.name] > 1 {
+			source = column.table + "_" + column.name
 		}
+		fields = append(fields, rowField{
+			name:   utils.ToProperPascalCase(source),
+			tag:    utils.ToSnakeCase(source),
+			column: column,
+		})
 	}
-	return ""
+	return fields
 }
 
-func (g SelectGenerator) inferDataType(typeName string) (map[string]string, error) {
-	dataMap := make(map[string]string)
-
-	for k, v := range g.schemaTypes {
-		if k == typeName {
-			switch inferredType := v.(type) {
-			case *parser.Table:
-				for _, field := range inferredType.Fields {
-					goType, err := parser.SqlToGoType(field.DataType)
-					if err != nil || goType == "" {
-						return map[string]string{}, fmt.Errorf("failed to convert field %s: %v", field.Name, err)
-					}
-					dataMap[field.Name] = goType
-				}
-			case *parser.Enum:
-				dataMap[inferredType.Name] = "string"
-			}
-			break // Found the type
+// GenerateRowStruct declares the <QueryName>Row struct the query returns, it
+// is nil when the query returns its table's struct
+func (g SelectGenerator) GenerateRowStruct(astStmt *types.SelectStatement) (*ast.GenDecl, error) {
+	if !g.returnsRow(astStmt) {
+		return nil, nil
+	}
+	if _, err := g.rowColumns(astStmt); err != nil {
+		return nil, err
+	}
+
+	var fields []*ast.Field
+	for _, field := range g.rowFields(astStmt) {
+		fields = append(fields, &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(field.name)},
+			Type:  ast.NewIdent(field.column.goType()),
+			Tag: &ast.BasicLit{
+				Kind:  token.STRING,
+				Value: fmt.Sprintf("`json:\"%s\" db:%q`", field.tag, field.column.name),
+			},
+		})
+	}
+
+	return &ast.GenDecl{
+		Tok: token.TYPE,
+		Specs: []ast.Spec{
+			&ast.TypeSpec{
+				Name: ast.NewIdent(g.queryblock.Name + "Row"),
+				Type: &ast.StructType{Fields: &ast.FieldList{List: fields}},
+			},
+		},
+	}, nil
+}
+
+// isStar reports whether column is * or t.*
+func isStar(column types.Expr) bool {
+	ref, ok := column.(*types.ColumnRef)
+	return ok && ref.Column == "*"
+}
+
+func (g SelectGenerator) generateReturnType(typeName string, isMany bool) ast.Expr {
+	// Generate struct name from table name
+	tableName := strings.TrimSuffix(typeName, "s") // Remove plural 's'
+	structName := utils.Capitalize(tableName)
+
+	if isMany {
+		return &ast.ArrayType{
+			Elt: ast.NewIdent(structName),
 		}
 	}
+	return ast.NewIdent(structName)
+}
 
-	// If no table was found, return an error
-	if len(dataMap) == 0 {
-		return nil, fmt.Errorf("table '%s' not found in schema", typeName)
+// sqlStringLit quotes sql as a Go raw string unless it holds a backtick
+func sqlStringLit(sql string) string {
+	if strings.Contains(sql, "`") {
+		return strconv.Quote(sql)
 	}
+	return fmt.Sprintf("`%s`", sql)
+}
 
-	return dataMap, nil
+func (g SelectGenerator) inferDataType(typeName string) (map[string]string, error) {
+	return inferDataType(g.schemaTypes, typeName)
 }
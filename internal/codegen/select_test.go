@@ -1,7 +1,11 @@
 package codegen
 
 import (
+	"bytes"
+	"fmt"
 	"go/ast"
+	"go/printer"
+	gotoken "go/token"
 	"shogunc/internal/parser"
 	"shogunc/internal/types"
 	"strings"
@@ -31,16 +35,11 @@ func TestGenerateSelectFunc(t *testing.T) {
 	// Create a SELECT statement with bind parameter
 	selectStmt := &types.SelectStatement{
 		TableName: "users",
-		Columns:   []string{"*"},
-		Conditions: []types.Condition{
-			{
-				Column:   "id",
-				Operator: types.EQUAL,
-				Value: types.Bind{
-					Column:   "id",
-					Position: 1,
-				},
-			},
+		Columns:   []types.SelectColumn{{Expr: &types.ColumnRef{Column: "*"}}},
+		Where: &types.BinaryExpr{
+			Left:  &types.ColumnRef{Column: "id"},
+			Op:    "=",
+			Right: &types.Param{Column: "id", Position: 1},
 		},
 	}
 
@@ -92,15 +91,10 @@ func TestGenerateSelectParamStruct(t *testing.T) {
 
 	selectStmt := &types.SelectStatement{
 		TableName: "users",
-		Conditions: []types.Condition{
-			{
-				Column:   "id",
-				Operator: types.EQUAL,
-				Value: types.Bind{
-					Column:   "id",
-					Position: 1,
-				},
-			},
+		Where: &types.BinaryExpr{
+			Left:  &types.ColumnRef{Column: "id"},
+			Op:    "=",
+			Right: &types.Param{Column: "id", Position: 1},
 		},
 	}
 
@@ -146,8 +140,8 @@ func TestGenerateSelectParamStruct_NoParams(t *testing.T) {
 	generator := NewSelectGenerator(schemaTypes, queryBlock)
 
 	selectStmt := &types.SelectStatement{
-		TableName:  "users",
-		Conditions: []types.Condition{}, // No conditions = no parameters
+		TableName: "users",
+		Where:     nil, // No WHERE = no parameters
 	}
 
 	structDecl, typeName, err := generator.generateSelectParamStruct(selectStmt)
@@ -180,9 +174,9 @@ func TestGenerateSelectFunc_NoParams(t *testing.T) {
 	generator := NewSelectGenerator(schemaTypes, queryBlock)
 
 	selectStmt := &types.SelectStatement{
-		TableName:  "users",
-		Columns:    []string{"*"},
-		Conditions: []types.Condition{}, // No conditions = no parameters
+		TableName: "users",
+		Columns:   []types.SelectColumn{{Expr: &types.ColumnRef{Column: "*"}}},
+		Where:     nil, // No WHERE = no parameters
 	}
 
 	funcDecl, paramStruct, err := generator.GenerateSelectFunc(selectStmt, true)
@@ -219,65 +213,805 @@ func TestGenerateSelectFunc_NoParams(t *testing.T) {
 	}
 }
 
-// TestShoguncConditionalOp_BindParam tests bind parameter conditions
-func TestShoguncConditionalOp_BindParam(t *testing.T) {
+// selectQuery returns the SQL generated for a SELECT statement
+func selectQuery(t *testing.T, generator *SelectGenerator, stmt *types.SelectStatement) string {
+	t.Helper()
+
+	assign, ok := generator.generateSelectQuery(stmt).(*ast.AssignStmt)
+	if !ok {
+		t.Fatal("Expected query assignment")
+	}
+	return assign.Rhs[0].(*ast.BasicLit).Value
+}
+
+// TestGenerateSelectQuery_BindParam tests bind params are written as placeholders
+func TestGenerateSelectQuery_BindParam(t *testing.T) {
 	schemaTypes := make(map[string]any)
 	queryBlock := &types.QueryBlock{Name: "GetUser", Type: types.ONE}
 	generator := NewSelectGenerator(schemaTypes, queryBlock)
 
-	condition := types.Condition{
-		Column:   "ID",
-		Operator: types.EQUAL,
-		Value: types.Bind{
-			Column:   "id",
-			Position: 1,
+	stmt := &types.SelectStatement{
+		TableName: "users",
+		Columns:   []types.SelectColumn{{Expr: &types.ColumnRef{Column: "*"}}},
+		Where: &types.BinaryExpr{
+			Left:  &types.ColumnRef{Column: "id"},
+			Op:    "=",
+			Right: &types.Param{Column: "id", Position: 1},
 		},
 	}
 
-	result := generator.shoguncConditionalOp(condition)
-	expected := "id = $1"
+	result := selectQuery(t, generator, stmt)
+	expected := "`SELECT * FROM users WHERE id = $1;`"
 	if result != expected {
 		t.Errorf("Expected '%s', got '%s'", expected, result)
 	}
 }
 
-// TestShoguncConditionalOp_LiteralValue tests literal value conditions
-func TestShoguncConditionalOp_LiteralValue(t *testing.T) {
-	schemaTypes := make(map[string]any)
-	queryBlock := &types.QueryBlock{Name: "GetUser", Type: types.ONE}
-	generator := NewSelectGenerator(schemaTypes, queryBlock)
+// TestGenerateSelectQuery_LiteralKinds tests literals are quoted by kind
+func TestGenerateSelectQuery_LiteralKinds(t *testing.T) {
+	generator := NewSelectGenerator(make(map[string]any), &types.QueryBlock{Name: "GetProduct", Type: types.ONE})
+
+	tests := []struct {
+		value    string
+		kind     types.LiteralKind
+		expected string
+	}{
+		{"9.99", types.FloatLiteral, "price = 9.99"},
+		{"-5", types.IntLiteral, "price = -5"},
+		{"true", types.BoolLiteral, "price = TRUE"},
+		{"O'Brien", types.StringLiteral, "price = 'O''Brien'"},
+		{"cheap", "", "price = 'cheap'"},
+	}
+
+	for _, tt := range tests {
+		stmt := &types.SelectStatement{
+			TableName: "products",
+			Columns:   []types.SelectColumn{{Expr: &types.ColumnRef{Column: "*"}}},
+			Where: &types.BinaryExpr{
+				Left:  &types.ColumnRef{Column: "price"},
+				Op:    "=",
+				Right: &types.Literal{Value: tt.value, Kind: tt.kind},
+			},
+		}
+		expected := fmt.Sprintf("`SELECT * FROM products WHERE %s;`", tt.expected)
+		if result := selectQuery(t, generator, stmt); result != expected {
+			t.Errorf("Expected '%s', got '%s'", expected, result)
+		}
+	}
+}
+
+// renderNode prints a generated declaration as Go source
+func renderNode(t *testing.T, node any) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, gotoken.NewFileSet(), node); err != nil {
+		t.Fatalf("failed printing node: %v", err)
+	}
+	return buf.String()
+}
+
+// generateFromSQL parses sql and generates its function and params struct
+func generateFromSQL(t *testing.T, schemaTypes map[string]any, queryBlock *types.QueryBlock, dialect parser.Dialect, sql string) (string, string) {
+	t.Helper()
 
-	value := "test@example.com"
-	condition := types.Condition{
-		Column:   "EMAIL",
-		Operator: types.EQUAL,
-		Value: types.Bind{
-			Column:   "email",
-			Position: 0,
-			Value:    &value,
+	ast := parser.NewAst(parser.NewLexer(sql)).SetDialect(dialect)
+	if err := ast.Parse(); err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	funcDecl, paramStruct, err := NewGoGenerator(schemaTypes, queryBlock).SetDialect(dialect).Generate(ast.Statements[0])
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	var structSrc string
+	if paramStruct != nil {
+		structSrc = renderNode(t, paramStruct)
+	}
+	return renderNode(t, funcDecl), structSrc
+}
+
+// TestGenerateSelectFunc_NamedParams tests named params name the fields and
+// are rewritten to the dialect's placeholders
+func TestGenerateSelectFunc_NamedParams(t *testing.T) {
+	schemaTypes := map[string]any{
+		"events": &parser.Table{
+			Name: "events",
+			Fields: []parser.Field{
+				{Name: "id", DataType: parser.Token{Literal: "UUID"}},
+				{Name: "created_at", DataType: parser.Token{Literal: "TIMESTAMP"}},
+				{Name: "updated_at", DataType: parser.Token{Literal: "TIMESTAMP"}},
+			},
+		},
+	}
+	sql := "SELECT id FROM events WHERE created_at > @start_date AND created_at < sqlc.arg(end_date) OR updated_at > :start_date;"
+
+	expectedStruct := "type ListEventsParams struct {\n" +
+		"\tStartDate\ttime.Time\t`json:\"start_date\"`\n" +
+		"\tEndDate\t\ttime.Time\t`json:\"end_date\"`\n" +
+		"}"
+
+	tests := []struct {
+		dialect parser.Dialect
+		query   string
+	}{
+		{parser.Postgres, "query := `SELECT id FROM events WHERE created_at > $1 AND created_at < $2 OR updated_at > $1;`"},
+		{parser.SQLite, "query := `SELECT id FROM events WHERE created_at > ?1 AND created_at < ?2 OR updated_at > ?1;`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.dialect), func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListEvents", Type: types.MANY}
+			funcSrc, structSrc := generateFromSQL(t, schemaTypes, queryBlock, tt.dialect, sql)
+
+			if structSrc != expectedStruct {
+				t.Errorf("Expected struct:\n%s\ngot:\n%s", expectedStruct, structSrc)
+			}
+			if !strings.Contains(funcSrc, tt.query) {
+				t.Errorf("Expected function to contain %s, got:\n%s", tt.query, funcSrc)
+			}
+			if !strings.Contains(funcSrc, "q.db.Query(ctx, query, params.StartDate, params.EndDate)") {
+				t.Errorf("Expected each param to be passed once, got:\n%s", funcSrc)
+			}
+		})
+	}
+}
+
+// TestGenerateSelectParamStruct_SameColumn tests two positional params on one
+// column get distinct field names
+func TestGenerateSelectParamStruct_SameColumn(t *testing.T) {
+	schemaTypes := map[string]any{
+		"events": &parser.Table{
+			Name: "events",
+			Fields: []parser.Field{
+				{Name: "created_at", DataType: parser.Token{Literal: "TIMESTAMP"}},
+			},
 		},
 	}
 
-	result := generator.shoguncConditionalOp(condition)
-	// Should use shogun.Equal which formats the result
-	if !strings.Contains(result, "email") {
-		t.Error("Expected result to contain email column")
+	queryBlock := &types.QueryBlock{Name: "ListEvents", Type: types.MANY}
+	_, structSrc := generateFromSQL(t, schemaTypes, queryBlock, parser.Postgres,
+		"SELECT created_at FROM events WHERE created_at > $1 AND created_at < $2;")
+
+	expected := "type ListEventsParams struct {\n" +
+		"\tCreatedAt\ttime.Time\t`json:\"created_at\"`\n" +
+		"\tCreatedAt2\ttime.Time\t`json:\"created_at_2\"`\n" +
+		"}"
+	if structSrc != expected {
+		t.Errorf("Expected struct:\n%s\ngot:\n%s", expected, structSrc)
 	}
 }
 
-// TestShoguncNextOp tests logical operators
-func TestShoguncNextOp(t *testing.T) {
-	schemaTypes := make(map[string]any)
+// TestGenerateSelectFunc_SQLitePlaceholders tests ? params are numbered in
+// the emitted SQL and linked to their columns
+func TestGenerateSelectFunc_SQLitePlaceholders(t *testing.T) {
+	schemaTypes := map[string]any{
+		"users": &parser.Table{
+			Name: "users",
+			Fields: []parser.Field{
+				{Name: "id", DataType: parser.Token{Literal: "INT"}},
+				{Name: "email", DataType: parser.Token{Literal: "TEXT"}},
+			},
+		},
+	}
+
 	queryBlock := &types.QueryBlock{Name: "GetUser", Type: types.ONE}
-	generator := NewSelectGenerator(schemaTypes, queryBlock)
+	funcSrc, structSrc := generateFromSQL(t, schemaTypes, queryBlock, parser.SQLite,
+		"SELECT id FROM users WHERE id = ? AND email = ?;")
+
+	expectedStruct := "type GetUserParams struct {\n" +
+		"\tId\tint\t`json:\"id\"`\n" +
+		"\tEmail\tstring\t`json:\"email\"`\n" +
+		"}"
+	if structSrc != expectedStruct {
+		t.Errorf("Expected struct:\n%s\ngot:\n%s", expectedStruct, structSrc)
+	}
+	if !strings.Contains(funcSrc, "query := `SELECT id FROM users WHERE id = ?1 AND email = ?2;`") {
+		t.Errorf("Expected numbered placeholders, got:\n%s", funcSrc)
+	}
+}
+
+// TestGenerateSelectQuery_Operators is a golden test of every comparison
+// operator against a bind param and a literal
+func TestGenerateSelectQuery_Operators(t *testing.T) {
+	schemaTypes := map[string]any{
+		"users": &parser.Table{
+			Name: "users",
+			Fields: []parser.Field{
+				{Name: "id", DataType: parser.Token{Literal: "INT"}},
+				{Name: "age", DataType: parser.Token{Literal: "INT"}},
+				{Name: "name", DataType: parser.Token{Literal: "TEXT"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		where     string
+		paramType string // Type of the params field, empty for a literal
+	}{
+		{"age = $1", "int"},
+		{"age < $1", "int"},
+		{"age <= $1", "int"},
+		{"age > $1", "int"},
+		{"age >= $1", "int"},
+		{"age != $1", "int"},
+		{"age <> $1", "int"},
+		{"name LIKE $1", "string"},
+		{"name ILIKE $1", "string"},
+		{"age IS DISTINCT FROM $1", "int"},
+		{"age IS NOT DISTINCT FROM $1", "int"},
+		{"age = 18", ""},
+		{"age < 18", ""},
+		{"age <= 18", ""},
+		{"age > 18", ""},
+		{"age >= 18", ""},
+		{"age != 18", ""},
+		{"age <> 18", ""},
+		{"name LIKE 'jo%'", ""},
+		{"name ILIKE 'JO%'", ""},
+		{"age IS DISTINCT FROM 18", ""},
+		{"age IS NOT DISTINCT FROM NULL", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListUsers", Type: types.MANY}
+			funcSrc, structSrc := generateFromSQL(t, schemaTypes, queryBlock, parser.Postgres,
+				"SELECT id FROM users WHERE "+tt.where+";")
+
+			query := "query := `SELECT id FROM users WHERE " + tt.where + ";`"
+			if !strings.Contains(funcSrc, query) {
+				t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+			}
+
+			if tt.paramType == "" {
+				if structSrc != "" {
+					t.Errorf("Expected no params struct for a literal, got:\n%s", structSrc)
+				}
+				return
+			}
+			field := fmt.Sprintf(" %s `json:", tt.paramType)
+			if !strings.Contains(strings.Join(strings.Fields(structSrc), " "), field) {
+				t.Errorf("Expected a %s param, got:\n%s", tt.paramType, structSrc)
+			}
+		})
+	}
+}
+
+// TestGenerateSelectQuery_Distinct tests SELECT DISTINCT is kept
+func TestGenerateSelectQuery_Distinct(t *testing.T) {
+	schemaTypes := map[string]any{
+		"users": &parser.Table{
+			Name:   "users",
+			Fields: []parser.Field{{Name: "role", DataType: parser.Token{Literal: "TEXT"}}},
+		},
+	}
+
+	queryBlock := &types.QueryBlock{Name: "ListRoles", Type: types.MANY}
+	funcSrc, _ := generateFromSQL(t, schemaTypes, queryBlock, parser.Postgres, "SELECT DISTINCT role FROM users;")
+	if !strings.Contains(funcSrc, "query := `SELECT DISTINCT role FROM users;`") {
+		t.Errorf("Expected DISTINCT to be kept, got:\n%s", funcSrc)
+	}
+
+	queryBlock = &types.QueryBlock{Name: "CountRoles", Type: types.ONE}
+	funcSrc, _ = generateFromSQL(t, schemaTypes, queryBlock, parser.Postgres, "SELECT COUNT(DISTINCT role) AS n FROM users;")
+	if !strings.Contains(funcSrc, "query := `SELECT COUNT(DISTINCT role) AS n FROM users;`") {
+		t.Errorf("Expected DISTINCT to be kept in the aggregate, got:\n%s", funcSrc)
+	}
+}
+
+// TestGenerateSelectFunc_Predicates tests IN, BETWEEN and IS NULL params and
+// query text
+func TestGenerateSelectFunc_Predicates(t *testing.T) {
+	schemaTypes := map[string]any{
+		"users": &parser.Table{
+			Name: "users",
+			Fields: []parser.Field{
+				{Name: "id", DataType: parser.Token{Literal: "INT"}},
+				{Name: "age", DataType: parser.Token{Literal: "INT"}},
+				{Name: "deleted_at", DataType: parser.Token{Literal: "TIMESTAMP"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		where  string
+		fields string // Params struct fields, empty when there are none
+		args   string
+	}{
+		{
+			where:  "age BETWEEN $1 AND $2",
+			fields: "MinAge int `json:\"min_age\"` MaxAge int `json:\"max_age\"`",
+			args:   "params.MinAge, params.MaxAge",
+		},
+		{
+			where:  "id NOT IN ($1, $2, 3)",
+			fields: "Id int `json:\"id\"` Id2 int `json:\"id_2\"`",
+			args:   "params.Id, params.Id2",
+		},
+		{
+			where: "deleted_at IS NULL AND age NOT BETWEEN 18 AND 65",
+		},
+		{
+			where:  "deleted_at IS NOT NULL AND id IN ($1)",
+			fields: "Id int `json:\"id\"`",
+			args:   "params.Id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListUsers", Type: types.MANY}
+			funcSrc, structSrc := generateFromSQL(t, schemaTypes, queryBlock, parser.Postgres,
+				"SELECT id FROM users WHERE "+tt.where+";")
+
+			query := "query := `SELECT id FROM users WHERE " + tt.where + ";`"
+			if !strings.Contains(funcSrc, query) {
+				t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+			}
+
+			if tt.fields == "" {
+				if structSrc != "" {
+					t.Errorf("Expected no params struct, got:\n%s", structSrc)
+				}
+				return
+			}
+			expectedStruct := "type ListUsersParams struct { " + tt.fields + " }"
+			if got := strings.Join(strings.Fields(structSrc), " "); got != expectedStruct {
+				t.Errorf("Expected %s, got %s", expectedStruct, got)
+			}
+			if !strings.Contains(funcSrc, "q.db.Query(ctx, query, "+tt.args+")") {
+				t.Errorf("Expected args %s, got:\n%s", tt.args, funcSrc)
+			}
+		})
+	}
+}
+
+func joinSchema() map[string]any {
+	return map[string]any{
+		"users": &parser.Table{
+			Name: "users",
+			Fields: []parser.Field{
+				{Name: "id", DataType: parser.Token{Literal: "INT"}, IsPrimary: true},
+				{Name: "email", DataType: parser.Token{Literal: "TEXT"}, NotNull: true},
+			},
+		},
+		"posts": &parser.Table{
+			Name: "posts",
+			Fields: []parser.Field{
+				{Name: "id", DataType: parser.Token{Literal: "INT"}, IsPrimary: true},
+				{Name: "user_id", DataType: parser.Token{Literal: "INT"}, NotNull: true},
+				{Name: "title", DataType: parser.Token{Literal: "TEXT"}, NotNull: true},
+			},
+		},
+	}
+}
+
+// generateRowStruct renders the Row struct generated for sql
+func generateRowStruct(t *testing.T, schemaTypes map[string]any, queryBlock *types.QueryBlock, sql string) (string, error) {
+	t.Helper()
+	return generateDialectRowStruct(t, schemaTypes, queryBlock, parser.Generic, sql)
+}
+
+func generateDialectRowStruct(t *testing.T, schemaTypes map[string]any, queryBlock *types.QueryBlock, dialect parser.Dialect, sql string) (string, error) {
+	t.Helper()
+
+	ast := parser.NewAst(parser.NewLexer(sql)).SetDialect(dialect)
+	if err := ast.Parse(); err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	rowStruct, err := NewGoGenerator(schemaTypes, queryBlock).SetDialect(dialect).GenerateRowStruct(ast.Statements[0])
+	if err != nil || rowStruct == nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(renderNode(t, rowStruct)), " "), nil
+}
+
+// TestGenerateSelectFunc_Join tests a join returns a Row struct with the
+// columns of both tables and params typed from either table
+func TestGenerateSelectFunc_Join(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "ListPosts", Type: types.MANY}
+	sql := "SELECT users.email, posts.id, users.id, title FROM users " +
+		"JOIN posts ON posts.user_id = users.id WHERE users.email = $1 AND posts.title = $2;"
+	funcSrc, structSrc := generateFromSQL(t, joinSchema(), queryBlock, parser.Postgres, sql)
+
+	expected := []string{
+		"func ListPosts(q *Queries, ctx context.Context, params ListPostsParams) ([]ListPostsRow, error) {",
+		"query := `SELECT users.email,posts.id,users.id,title FROM users INNER JOIN posts ON posts.user_id = users.id WHERE users.email = $1 AND posts.title = $2;`",
+		"var item ListPostsRow",
+		"err = rows.Scan(&item.Email, &item.PostsId, &item.UsersId, &item.Title)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(funcSrc, want) {
+			t.Errorf("Expected function to contain %s, got:\n%s", want, funcSrc)
+		}
+	}
+
+	expectedParams := "type ListPostsParams struct { Email string `json:\"email\"` Title string `json:\"title\"` }"
+	if got := strings.Join(strings.Fields(structSrc), " "); got != expectedParams {
+		t.Errorf("Expected %s, got %s", expectedParams, got)
+	}
+
+	rowSrc, err := generateRowStruct(t, joinSchema(), queryBlock, sql)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedRow := "type ListPostsRow struct { " +
+		"Email string `json:\"email\" db:\"email\"` " +
+		"PostsId int `json:\"posts_id\" db:\"id\"` " +
+		"UsersId int `json:\"users_id\" db:\"id\"` " +
+		"Title string `json:\"title\" db:\"title\"` }"
+	if rowSrc != expectedRow {
+		t.Errorf("Expected %s, got %s", expectedRow, rowSrc)
+	}
+}
+
+// TestGenerateSelectFunc_JoinOne tests a :one join returns an empty Row on
+// error
+func TestGenerateSelectFunc_JoinOne(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "GetPost", Type: types.ONE}
+	funcSrc, _ := generateFromSQL(t, joinSchema(), queryBlock, parser.Postgres,
+		"SELECT posts.* FROM posts JOIN users USING (id) WHERE posts.id = $1;")
+
+	expected := []string{
+		"func GetPost(q *Queries, ctx context.Context, params GetPostParams) (GetPostRow, error) {",
+		"return GetPostRow{}, err",
+		"err = row.Scan(&result.Id, &result.UserId, &result.Title)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(funcSrc, want) {
+			t.Errorf("Expected function to contain %s, got:\n%s", want, funcSrc)
+		}
+	}
+}
+
+// TestGenerateSelectFunc_ColumnAlias tests an aliased column of a single
+// table returns a Row struct named after the alias
+func TestGenerateSelectFunc_ColumnAlias(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "GetEmail", Type: types.ONE}
+	sql := "SELECT u.email AS address FROM users u WHERE u.id = $1;"
+	funcSrc, structSrc := generateFromSQL(t, joinSchema(), queryBlock, parser.Postgres, sql)
+
+	expected := []string{
+		"query := `SELECT u.email AS address FROM users AS u WHERE u.id = $1;`",
+		"err = row.Scan(&result.Address)",
+		"return GetEmailRow{}, err",
+	}
+	for _, want := range expected {
+		if !strings.Contains(funcSrc, want) {
+			t.Errorf("Expected function to contain %s, got:\n%s", want, funcSrc)
+		}
+	}
+	if got := strings.Join(strings.Fields(structSrc), " "); got != "type GetEmailParams struct { Id int `json:\"id\"` }" {
+		t.Errorf("unexpected params struct %s", got)
+	}
+
+	rowSrc, err := generateRowStruct(t, joinSchema(), queryBlock, sql)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expectedRow := "type GetEmailRow struct { Address string `json:\"address\" db:\"address\"` }"; rowSrc != expectedRow {
+		t.Errorf("Expected %s, got %s", expectedRow, rowSrc)
+	}
+}
+
+// TestGenerateSelectQuery_OrderBy tests every ORDER BY term keeps its
+// direction and NULLS ordering ahead of LIMIT
+func TestGenerateSelectQuery_OrderBy(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "ListPosts", Type: types.MANY}
+	funcSrc, _ := generateFromSQL(t, joinSchema(), queryBlock, parser.Postgres,
+		"SELECT p.title FROM posts p JOIN users u ON u.id = p.user_id WHERE u.id = $1 ORDER BY u.email DESC NULLS LAST, p.id LIMIT 20;")
+
+	query := "query := `SELECT p.title FROM posts AS p INNER JOIN users AS u ON u.id = p.user_id WHERE u.id = $1 ORDER BY u.email DESC NULLS LAST, p.id LIMIT 20;`"
+	if !strings.Contains(funcSrc, query) {
+		t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+	}
+
+	funcSrc, _ = generateFromSQL(t, joinSchema(), queryBlock, parser.SQLite,
+		"SELECT title FROM posts ORDER BY id ASC;")
+	if query := "query := `SELECT title FROM posts ORDER BY id ASC;`"; !strings.Contains(funcSrc, query) {
+		t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+	}
+}
+
+// TestGenerateSelectFunc_LimitOffset tests LIMIT and OFFSET params become
+// int64 fields and a literal OFFSET is kept in the query
+func TestGenerateSelectFunc_LimitOffset(t *testing.T) {
+	tests := []struct {
+		sql    string
+		query  string
+		fields string
+		args   string
+	}{
+		{
+			"SELECT title FROM posts WHERE user_id = $1 LIMIT $2 OFFSET $3;",
+			"SELECT title FROM posts WHERE user_id = $1 LIMIT $2 OFFSET $3;",
+			"UserId int `json:\"user_id\"` Limit int64 `json:\"limit\"` Offset int64 `json:\"offset\"`",
+			"params.UserId, params.Limit, params.Offset",
+		},
+		{
+			"SELECT title FROM posts LIMIT @page_size OFFSET @page_start;",
+			"SELECT title FROM posts LIMIT $1 OFFSET $2;",
+			"PageSize int64 `json:\"page_size\"` PageStart int64 `json:\"page_start\"`",
+			"params.PageSize, params.PageStart",
+		},
+		{
+			"SELECT title FROM posts LIMIT $1 OFFSET 5;",
+			"SELECT title FROM posts LIMIT $1 OFFSET 5;",
+			"Limit int64 `json:\"limit\"`",
+			"params.Limit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListPosts", Type: types.MANY}
+			funcSrc, structSrc := generateFromSQL(t, joinSchema(), queryBlock, parser.Postgres, tt.sql)
+
+			if query := "query := `" + tt.query + "`"; !strings.Contains(funcSrc, query) {
+				t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+			}
+			expectedStruct := "type ListPostsParams struct { " + tt.fields + " }"
+			if got := strings.Join(strings.Fields(structSrc), " "); got != expectedStruct {
+				t.Errorf("Expected %s, got %s", expectedStruct, got)
+			}
+			if call := "q.db.Query(ctx, query, " + tt.args + ")"; !strings.Contains(funcSrc, call) {
+				t.Errorf("Expected function to contain %s, got:\n%s", call, funcSrc)
+			}
+		})
+	}
+}
+
+func complaintSchema() map[string]any {
+	return map[string]any{
+		"complaints": &parser.Table{
+			Name: "complaints",
+			Fields: []parser.Field{
+				{Name: "id", DataType: parser.Token{Literal: "INT"}, IsPrimary: true},
+				{Name: "status", DataType: parser.Token{Literal: "TEXT"}, NotNull: true},
+				{Name: "amount", DataType: parser.Token{Literal: "DECIMAL"}},
+				{Name: "created_at", DataType: parser.Token{Literal: "TIMESTAMP"}, NotNull: true},
+			},
+		},
+	}
+}
+
+// TestGenerateSelectFunc_GroupBy tests GROUP BY and HAVING are kept in the
+// query and a param compared with an aggregate is typed from it
+func TestGenerateSelectFunc_GroupBy(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "CountComplaints", Type: types.MANY}
+	sql := "SELECT status, COUNT(*) AS n FROM complaints WHERE created_at > $1 GROUP BY status HAVING COUNT(*) > $2 AND max(amount) < $3 ORDER BY n DESC;"
+	funcSrc, structSrc := generateFromSQL(t, complaintSchema(), queryBlock, parser.Postgres, sql)
+
+	query := "query := `SELECT status,COUNT(*) AS n FROM complaints WHERE created_at > $1 GROUP BY status HAVING COUNT(*) > $2 AND max(amount) < $3 ORDER BY n DESC;`"
+	if !strings.Contains(funcSrc, query) {
+		t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+	}
+	expectedStruct := "type CountComplaintsParams struct { CreatedAt time.Time `json:\"created_at\"` Count int64 `json:\"count\"` Amount float64 `json:\"amount\"` }"
+	if got := strings.Join(strings.Fields(structSrc), " "); got != expectedStruct {
+		t.Errorf("Expected %s, got %s", expectedStruct, got)
+	}
+
+	rowSrc, err := generateRowStruct(t, complaintSchema(), queryBlock, sql)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedRow := "type CountComplaintsRow struct { Status string `json:\"status\" db:\"status\"` N int64 `json:\"n\" db:\"n\"` }"
+	if rowSrc != expectedRow {
+		t.Errorf("Expected %s, got %s", expectedRow, rowSrc)
+	}
+}
+
+// TestGenerateSelectFunc_Subqueries tests params of subqueries are numbered
+// and typed along with the outer query's
+func TestGenerateSelectFunc_Subqueries(t *testing.T) {
+	tests := []struct {
+		dialect parser.Dialect
+		sql     string
+		query   string
+		fields  string
+	}{
+		{
+			parser.Postgres,
+			"SELECT title FROM posts WHERE user_id IN (SELECT id FROM users WHERE email = $1) AND title <> $2;",
+			"SELECT title FROM posts WHERE user_id IN (SELECT id FROM users WHERE email = $1) AND title <> $2;",
+			"Email string `json:\"email\"` Title string `json:\"title\"`",
+		},
+		{
+			parser.Postgres,
+			"SELECT title FROM posts p WHERE EXISTS (SELECT 1 FROM users u WHERE u.id = p.user_id AND u.id IN (sqlc.slice(ids)));",
+			"SELECT title FROM posts AS p WHERE EXISTS (SELECT 1 FROM users AS u WHERE u.id = p.user_id AND u.id = ANY($1));",
+			"Ids []int `json:\"ids\"`",
+		},
+		{
+			parser.SQLite,
+			"SELECT title FROM posts WHERE user_id IN (SELECT id FROM users WHERE email = @email) AND title <> @title;",
+			"SELECT title FROM posts WHERE user_id IN (SELECT id FROM users WHERE email = ?1) AND title <> ?2;",
+			"Email string `json:\"email\"` Title string `json:\"title\"`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListTitles", Type: types.MANY}
+			funcSrc, structSrc := generateFromSQL(t, joinSchema(), queryBlock, tt.dialect, tt.sql)
+
+			if query := "query := `" + tt.query + "`"; !strings.Contains(funcSrc, query) {
+				t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+			}
+			expectedStruct := "type ListTitlesParams struct { " + tt.fields + " }"
+			if got := strings.Join(strings.Fields(structSrc), " "); got != expectedStruct {
+				t.Errorf("Expected %s, got %s", expectedStruct, got)
+			}
+		})
+	}
+}
+
+// TestGenerateSelectFunc_DerivedTable tests a derived table returns a Row
+// struct typed from its query
+func TestGenerateSelectFunc_DerivedTable(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "ListAuthors", Type: types.MANY}
+	sql := "SELECT t.user_id, t.n FROM (SELECT user_id, COUNT(*) AS n FROM posts WHERE title <> $1 GROUP BY user_id) AS t WHERE t.n > $2;"
+	funcSrc, structSrc := generateFromSQL(t, joinSchema(), queryBlock, parser.Postgres, sql)
+
+	query := "query := `SELECT t.user_id,t.n FROM (SELECT user_id, COUNT(*) AS n FROM posts WHERE title <> $1 GROUP BY user_id) AS t WHERE t.n > $2;`"
+	if !strings.Contains(funcSrc, query) {
+		t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+	}
+	expectedStruct := "type ListAuthorsParams struct { Title string `json:\"title\"` N int64 `json:\"n\"` }"
+	if got := strings.Join(strings.Fields(structSrc), " "); got != expectedStruct {
+		t.Errorf("Expected %s, got %s", expectedStruct, got)
+	}
+
+	rowSrc, err := generateRowStruct(t, joinSchema(), queryBlock, sql)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedRow := "type ListAuthorsRow struct { UserId int `json:\"user_id\" db:\"user_id\"` N int64 `json:\"n\" db:\"n\"` }"
+	if rowSrc != expectedRow {
+		t.Errorf("Expected %s, got %s", expectedRow, rowSrc)
+	}
+}
 
-	andOp := generator.shoguncNextOp(types.And)
-	if andOp == "" {
-		t.Error("Expected non-empty AND operator")
+// TestGenerateSelectFunc_With tests a CTE is kept in the query and its
+// columns type the Row struct and the params compared with them
+func TestGenerateSelectFunc_With(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "ListAuthors", Type: types.MANY}
+	sql := "WITH recent AS (SELECT user_id, COUNT(*) AS n FROM posts WHERE id IN (sqlc.slice(ids)) GROUP BY user_id) " +
+		"SELECT u.email, r.n FROM users u JOIN recent r ON r.user_id = u.id WHERE r.n > @min_posts;"
+	funcSrc, structSrc := generateFromSQL(t, joinSchema(), queryBlock, parser.Postgres, sql)
+
+	query := "query := `WITH recent AS (SELECT user_id, COUNT(*) AS n FROM posts WHERE id = ANY($1) GROUP BY user_id) " +
+		"SELECT u.email,r.n FROM users AS u INNER JOIN recent AS r ON r.user_id = u.id WHERE r.n > $2;`"
+	if !strings.Contains(funcSrc, query) {
+		t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+	}
+	expectedStruct := "type ListAuthorsParams struct { Ids []int `json:\"ids\"` MinPosts int64 `json:\"min_posts\"` }"
+	if got := strings.Join(strings.Fields(structSrc), " "); got != expectedStruct {
+		t.Errorf("Expected %s, got %s", expectedStruct, got)
+	}
+
+	rowSrc, err := generateRowStruct(t, joinSchema(), queryBlock, sql)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedRow := "type ListAuthorsRow struct { Email string `json:\"email\" db:\"email\"` N int64 `json:\"n\" db:\"n\"` }"
+	if rowSrc != expectedRow {
+		t.Errorf("Expected %s, got %s", expectedRow, rowSrc)
+	}
+}
+
+func TestGenerateSelectFunc_Compound(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "ListNames", Type: types.MANY}
+	sql := "SELECT id, email FROM users WHERE id IN (sqlc.slice(ids)) " +
+		"UNION ALL SELECT user_id, title FROM posts WHERE title = @title ORDER BY email DESC LIMIT @page_size;"
+
+	funcSrc, structSrc := generateFromSQL(t, joinSchema(), queryBlock, parser.Postgres, sql)
+	query := "query := `SELECT id,email FROM users WHERE id = ANY($1) " +
+		"UNION ALL SELECT user_id, title FROM posts WHERE title = $2 ORDER BY email DESC LIMIT $3;`"
+	if !strings.Contains(funcSrc, query) {
+		t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+	}
+	expectedStruct := "type ListNamesParams struct { Ids []int `json:\"ids\"` Title string `json:\"title\"` PageSize int64 `json:\"page_size\"` }"
+	if got := strings.Join(strings.Fields(structSrc), " "); got != expectedStruct {
+		t.Errorf("Expected %s, got %s", expectedStruct, got)
+	}
+
+	// sqlite expands the slice in place, params after it keep their order
+	funcSrc, _ = generateFromSQL(t, joinSchema(), queryBlock, parser.SQLite, sql)
+	query = "query := `SELECT id,email FROM users WHERE id IN (/*SLICE:ids*/?) " +
+		"UNION ALL SELECT user_id, title FROM posts WHERE title = ? ORDER BY email DESC LIMIT ?;`"
+	if !strings.Contains(funcSrc, query) {
+		t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+	}
+
+	rowSrc, err := generateRowStruct(t, joinSchema(), queryBlock, sql)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedRow := "type ListNamesRow struct { Id int `json:\"id\" db:\"id\"` Email string `json:\"email\" db:\"email\"` }"
+	if rowSrc != expectedRow {
+		t.Errorf("Expected %s, got %s", expectedRow, rowSrc)
+	}
+}
+
+func TestGenerateSelectFunc_CaseAndCast(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "ListUsers", Type: types.MANY}
+	sql := "SELECT id, CASE WHEN email = @email THEN 'me' ELSE email END AS who FROM users WHERE id > @min_id::bigint;"
+	funcSrc, structSrc := generateFromSQL(t, joinSchema(), queryBlock, parser.Postgres, sql)
+
+	query := "query := `SELECT id,CASE WHEN email = $1 THEN 'me' ELSE email END AS who FROM users WHERE id > $2::BIGINT;`"
+	if !strings.Contains(funcSrc, query) {
+		t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+	}
+	expectedStruct := "type ListUsersParams struct { Email string `json:\"email\"` MinId int `json:\"min_id\"` }"
+	if got := strings.Join(strings.Fields(structSrc), " "); got != expectedStruct {
+		t.Errorf("Expected %s, got %s", expectedStruct, got)
+	}
+
+	rowSrc, err := generateRowStruct(t, joinSchema(), queryBlock, sql)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedRow := "type ListUsersRow struct { Id int `json:\"id\" db:\"id\"` Who string `json:\"who\" db:\"who\"` }"
+	if rowSrc != expectedRow {
+		t.Errorf("Expected %s, got %s", expectedRow, rowSrc)
+	}
+}
+
+func TestGenerateSelectFunc_Window(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "ListLeaderboard", Type: types.MANY}
+	sql := "SELECT id, ROW_NUMBER() OVER (PARTITION BY status ORDER BY created_at DESC) AS place, " +
+		"sum(amount) OVER (ORDER BY id ROWS BETWEEN @window PRECEDING AND CURRENT ROW) AS recent " +
+		"FROM complaints WHERE status = @status;"
+	funcSrc, structSrc := generateFromSQL(t, complaintSchema(), queryBlock, parser.SQLite, sql)
+
+	query := "query := `SELECT id,ROW_NUMBER() OVER (PARTITION BY status ORDER BY created_at DESC) AS place," +
+		"sum(amount) OVER (ORDER BY id ROWS BETWEEN ?1 PRECEDING AND CURRENT ROW) AS recent FROM complaints WHERE status = ?2;`"
+	if !strings.Contains(funcSrc, query) {
+		t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+	}
+	expectedStruct := "type ListLeaderboardParams struct { Window int64 `json:\"window\"` Status string `json:\"status\"` }"
+	if got := strings.Join(strings.Fields(structSrc), " "); got != expectedStruct {
+		t.Errorf("Expected %s, got %s", expectedStruct, got)
+	}
+
+	rowSrc, err := generateDialectRowStruct(t, complaintSchema(), queryBlock, parser.SQLite, sql)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedRow := "type ListLeaderboardRow struct { Id int `json:\"id\" db:\"id\"` Place int64 `json:\"place\" db:\"place\"` Recent *float64 `json:\"recent\" db:\"recent\"` }"
+	if rowSrc != expectedRow {
+		t.Errorf("Expected %s, got %s", expectedRow, rowSrc)
+	}
+}
+
+// TestGenerateSelectFunc_QuotedTables tests quoted table, CTE and USING
+// names are written back quoted
+func TestGenerateSelectFunc_QuotedTables(t *testing.T) {
+	schema := map[string]any{
+		"Users": &parser.Table{
+			Name:   "Users",
+			Fields: []parser.Field{{Name: "id", DataType: parser.Token{Literal: "INT"}, IsPrimary: true}},
+		},
+		"order": &parser.Table{
+			Name: "order",
+			Fields: []parser.Field{
+				{Name: "id", DataType: parser.Token{Literal: "INT"}, IsPrimary: true},
+				{Name: "total", DataType: parser.Token{Literal: "DECIMAL"}, NotNull: true},
+			},
+		},
 	}
+	queryBlock := &types.QueryBlock{Name: "ListOrders", Type: types.MANY}
+	sql := `WITH "Big" ("Id") AS (SELECT id FROM "order" WHERE total > $1) ` +
+		`SELECT u.id, o.total FROM "Users" u LEFT JOIN "order" o USING (id) JOIN "Big" b ON b."Id" = o.id;`
+	funcSrc, _ := generateFromSQL(t, schema, queryBlock, parser.Postgres, sql)
 
-	orOp := generator.shoguncNextOp(types.Or)
-	if orOp == "" {
-		t.Error("Expected non-empty OR operator")
+	query := "query := `WITH \"Big\" (\"Id\") AS (SELECT id FROM \"order\" WHERE total > $1) " +
+		"SELECT u.id,o.total FROM \"Users\" AS u LEFT JOIN \"order\" AS o USING (id) INNER JOIN \"Big\" AS b ON b.\"Id\" = o.id;`"
+	if !strings.Contains(funcSrc, query) {
+		t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
 	}
 }
@@ -0,0 +1,270 @@
+package codegen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"shogunc/internal/parser"
+	"shogunc/internal/types"
+)
+
+// queryParams writes the params of one query into its SQL and passes them to
+// the driver. Postgres binds a slice param as a single array, sqlite has no
+// arrays so each slice is expanded into one ? per element when the query runs.
+type queryParams struct {
+	dialect parser.Dialect
+	binds   []types.Bind // Every param in the order it is written
+	fields  []paramField
+}
+
+// queryArgs are the arguments passed to the driver after ctx and query, prep
+// builds them and rewrites query first
+type queryArgs struct {
+	prep     []ast.Stmt
+	args     []ast.Expr
+	variadic bool     // args is a single []any spread into the call
+	imports  []string // Packages prep calls, strings when slices are expanded
+}
+
+func newQueryParams(dialect parser.Dialect, binds []types.Bind, fieldMap map[string]string) queryParams {
+	return queryParams{dialect: dialect, binds: binds, fields: collectParams(binds, fieldMap)}
+}
+
+// expandsSlices reports whether the query holds slice params that are
+// expanded when it runs
+func (p queryParams) expandsSlices() bool {
+	if p.dialect != parser.SQLite {
+		return false
+	}
+	for _, field := range p.fields {
+		if field.slice {
+			return true
+		}
+	}
+	return false
+}
+
+// sql renders e for the driver
+func (p queryParams) sql(e types.Expr) string {
+	if e == nil {
+		return ""
+	}
+	return p.expr(e).SQL(p.placeholder)
+}
+
+// column renders an item of a SELECT list for the driver
+func (p queryParams) column(column types.SelectColumn) string {
+	column.Expr = p.expr(column.Expr)
+	return column.SQL(p.placeholder)
+}
+
+// orderTerm renders a term of ORDER BY for the driver
+func (p queryParams) orderTerm(term types.OrderTerm) string {
+	term.Expr = p.expr(term.Expr)
+	return term.SQL(p.placeholder)
+}
+
+// join renders a JOIN clause for the driver
+func (p queryParams) join(join types.Join) string {
+	join.Table = p.tableRef(join.Table)
+	join.On = p.expr(join.On)
+	return join.SQL(p.placeholder)
+}
+
+// with renders the WITH clause of stmt for the driver
+func (p queryParams) with(stmt *types.SelectStatement) string {
+	if p.dialect == parser.SQLite {
+		return stmt.WithSQL(p.placeholder)
+	}
+	return types.RewriteSelect(stmt, p.rewriteSlice).WithSQL(p.placeholder)
+}
+
+// compound renders a query joined with UNION, INTERSECT or EXCEPT for the
+// driver
+func (p queryParams) compound(compound types.Compound) string {
+	if p.dialect != parser.SQLite {
+		compound.Select = types.RewriteSelect(compound.Select, p.rewriteSlice)
+	}
+	return compound.Op + " " + compound.Select.SQL(p.placeholder)
+}
+
+// table renders a table of FROM for the driver
+func (p queryParams) table(table types.TableRef) string {
+	return p.tableRef(table).SQL(p.placeholder)
+}
+
+func (p queryParams) tableRef(table types.TableRef) types.TableRef {
+	if p.dialect != parser.SQLite {
+		table.Select = types.RewriteSelect(table.Select, p.rewriteSlice)
+	}
+	return table
+}
+
+// expr rewrites x IN (sqlc.slice(ids)) to x = ANY($1) unless slices are
+// expanded, where every ? is left unnumbered so the expanded lists do not
+// shift the params after them
+func (p queryParams) expr(e types.Expr) types.Expr {
+	if p.dialect == parser.SQLite {
+		return e
+	}
+	return types.RewriteExpr(e, p.rewriteSlice)
+}
+
+func (p queryParams) rewriteSlice(node types.Expr) types.Expr {
+	in, ok := node.(*types.InExpr)
+	if !ok || len(in.List) != 1 {
+		return node
+	}
+	param, ok := in.List[0].(*types.Param)
+	if !ok || !param.Slice {
+		return node
+	}
+	if in.Not {
+		return &types.BinaryExpr{Left: in.Expr, Op: "<>", Right: &types.FuncCall{Name: "ALL", Args: in.List}}
+	}
+	return &types.BinaryExpr{Left: in.Expr, Op: "=", Right: &types.FuncCall{Name: "ANY", Args: in.List}}
+}
+
+func (p queryParams) placeholder(pos int) string {
+	if !p.expandsSlices() {
+		return p.dialect.Placeholder(pos)
+	}
+	if field := p.field(pos); field != nil && field.slice {
+		return sliceMarker(*field)
+	}
+	return "?"
+}
+
+func (p queryParams) field(pos int) *paramField {
+	for i := range p.fields {
+		if p.fields[i].pos == pos {
+			return &p.fields[i]
+		}
+	}
+	return nil
+}
+
+// sliceMarker stands in for a slice param in the SQL until it is expanded
+func sliceMarker(field paramField) string {
+	return fmt.Sprintf("/*SLICE:%s*/?", field.tag)
+}
+
+// args passes each params field once in position order, or builds an args
+// slice with every param in the order it is written when slices are expanded
+func (p queryParams) args() queryArgs {
+	if !p.expandsSlices() {
+		return queryArgs{args: paramArgs(p.fields)}
+	}
+
+	// var args []any
+	prep := []ast.Stmt{&ast.DeclStmt{Decl: &ast.GenDecl{
+		Tok: token.VAR,
+		Specs: []ast.Spec{&ast.ValueSpec{
+			Names: []*ast.Ident{ast.NewIdent("args")},
+			Type:  &ast.ArrayType{Elt: ast.NewIdent("any")},
+		}},
+	}}}
+
+	for _, bind := range p.binds {
+		field := p.field(bind.Position)
+		if field == nil {
+			continue
+		}
+		value := &ast.SelectorExpr{X: ast.NewIdent("params"), Sel: ast.NewIdent(field.name)}
+		if !field.slice {
+			prep = append(prep, appendArg(value))
+			continue
+		}
+		prep = append(prep, expandSlice(*field, value, bind.NotIn))
+	}
+
+	return queryArgs{prep: prep, args: []ast.Expr{ast.NewIdent("args")}, variadic: true, imports: []string{"strings"}}
+}
+
+// addImports records the packages prep calls in imports
+func (a queryArgs) addImports(imports map[string]bool) {
+	for _, pkg := range a.imports {
+		imports[pkg] = true
+	}
+}
+
+// appendArg is args = append(args, value)
+func appendArg(value ast.Expr) ast.Stmt {
+	return &ast.AssignStmt{
+		Lhs: []ast.Expr{ast.NewIdent("args")},
+		Tok: token.ASSIGN,
+		Rhs: []ast.Expr{&ast.CallExpr{
+			Fun:  ast.NewIdent("append"),
+			Args: []ast.Expr{ast.NewIdent("args"), value},
+		}},
+	}
+}
+
+// expandSlice appends each element of the slice value to args and replaces
+// its marker with one ? per element. An empty slice is replaced with NULL so
+// the IN matches nothing, or with an empty subquery under NOT IN so it
+// matches every row like <> ALL of an empty array does on postgres.
+func expandSlice(field paramField, value ast.Expr, notIn bool) ast.Stmt {
+	marker := &ast.BasicLit{Kind: token.STRING, Value: fmt.Sprintf("%q", sliceMarker(field))}
+	replace := func(with ast.Expr) ast.Stmt {
+		// query = strings.Replace(query, marker, with, 1)
+		return &ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent("query")},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{&ast.CallExpr{
+				Fun:  &ast.SelectorExpr{X: ast.NewIdent("strings"), Sel: ast.NewIdent("Replace")},
+				Args: []ast.Expr{ast.NewIdent("query"), marker, with, &ast.BasicLit{Kind: token.INT, Value: "1"}},
+			}},
+		}
+	}
+
+	// strings.Repeat(",?", len(value))[1:]
+	placeholders := &ast.SliceExpr{
+		X: &ast.CallExpr{
+			Fun: &ast.SelectorExpr{X: ast.NewIdent("strings"), Sel: ast.NewIdent("Repeat")},
+			Args: []ast.Expr{
+				&ast.BasicLit{Kind: token.STRING, Value: `",?"`},
+				&ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{value}},
+			},
+		},
+		Low: &ast.BasicLit{Kind: token.INT, Value: "1"},
+	}
+
+	empty := `"NULL"`
+	if notIn {
+		empty = `"SELECT NULL WHERE 0"`
+	}
+
+	return &ast.IfStmt{
+		Cond: &ast.BinaryExpr{
+			X:  &ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{value}},
+			Op: token.GTR,
+			Y:  &ast.BasicLit{Kind: token.INT, Value: "0"},
+		},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.RangeStmt{
+				Key:   ast.NewIdent("_"),
+				Value: ast.NewIdent("v"),
+				Tok:   token.DEFINE,
+				X:     value,
+				Body:  &ast.BlockStmt{List: []ast.Stmt{appendArg(ast.NewIdent("v"))}},
+			},
+			replace(placeholders),
+		}},
+		Else: &ast.BlockStmt{List: []ast.Stmt{
+			replace(&ast.BasicLit{Kind: token.STRING, Value: empty}),
+		}},
+	}
+}
+
+// call builds fun(ctx, query, args...)
+func (a queryArgs) call(fun ast.Expr) *ast.CallExpr {
+	call := &ast.CallExpr{
+		Fun:  fun,
+		Args: append([]ast.Expr{ast.NewIdent("ctx"), ast.NewIdent("query")}, a.args...),
+	}
+	if a.variadic {
+		call.Ellipsis = 1
+	}
+	return call
+}
@@ -0,0 +1,135 @@
+package codegen
+
+import (
+	"shogunc/internal/parser"
+	"shogunc/internal/types"
+	"strings"
+	"testing"
+)
+
+func sliceSchema() map[string]any {
+	return map[string]any{
+		"users": &parser.Table{
+			Name: "users",
+			Fields: []parser.Field{
+				{Name: "id", DataType: parser.Token{Literal: "INT"}},
+				{Name: "email", DataType: parser.Token{Literal: "TEXT"}},
+			},
+		},
+	}
+}
+
+// TestGenerateSliceParams_Postgres tests slices are passed to pgx as a
+// single array param
+func TestGenerateSliceParams_Postgres(t *testing.T) {
+	tests := []struct {
+		where string
+		query string
+	}{
+		{"id IN (sqlc.slice(ids)) AND email = @email", "id = ANY($1) AND email = $2"},
+		{"id NOT IN (sqlc.slice(ids)) AND email = @email", "id <> ALL($1) AND email = $2"},
+		{"id = ANY(@ids) AND email = @email", "id = ANY($1) AND email = $2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListUsers", Type: types.MANY}
+			funcSrc, structSrc := generateFromSQL(t, sliceSchema(), queryBlock, parser.Postgres,
+				"SELECT id FROM users WHERE "+tt.where+";")
+
+			expectedStruct := "type ListUsersParams struct { Ids []int `json:\"ids\"` Email string `json:\"email\"` }"
+			if got := strings.Join(strings.Fields(structSrc), " "); got != expectedStruct {
+				t.Errorf("Expected %s, got %s", expectedStruct, got)
+			}
+			if !strings.Contains(funcSrc, "query := `SELECT id FROM users WHERE "+tt.query+";`") {
+				t.Errorf("Expected query with %s, got:\n%s", tt.query, funcSrc)
+			}
+			if !strings.Contains(funcSrc, "q.db.Query(ctx, query, params.Ids, params.Email)") {
+				t.Errorf("Expected the slice passed as is, got:\n%s", funcSrc)
+			}
+		})
+	}
+}
+
+// TestGenerateSliceParams_SQLite tests slices are expanded into one ? per
+// element when the query runs
+func TestGenerateSliceParams_SQLite(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "ListUsers", Type: types.MANY}
+	funcSrc, structSrc := generateFromSQL(t, sliceSchema(), queryBlock, parser.SQLite,
+		"SELECT id FROM users WHERE email = @email AND id IN (sqlc.slice(ids));")
+
+	expectedStruct := "type ListUsersParams struct { Email string `json:\"email\"` Ids []int `json:\"ids\"` }"
+	if got := strings.Join(strings.Fields(structSrc), " "); got != expectedStruct {
+		t.Errorf("Expected %s, got %s", expectedStruct, got)
+	}
+
+	expected := []string{
+		"query := `SELECT id FROM users WHERE email = ? AND id IN (/*SLICE:ids*/?);`",
+		"var args []any",
+		"args = append(args, params.Email)",
+		"if len(params.Ids) > 0 {",
+		"for _, v := range params.Ids {",
+		"args = append(args, v)",
+		`query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(params.Ids))[1:], 1)`,
+		`query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)`,
+		"rows, err := q.db.Query(ctx, query, args...)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(funcSrc, want) {
+			t.Errorf("Expected function to contain %s, got:\n%s", want, funcSrc)
+		}
+	}
+}
+
+// TestGenerateSliceParams_SQLiteExec tests slices are expanded for DELETE
+func TestGenerateSliceParams_SQLiteExec(t *testing.T) {
+	queryBlock := &types.QueryBlock{Name: "DeleteUsers", Type: types.EXEC}
+	funcSrc, _ := generateFromSQL(t, sliceSchema(), queryBlock, parser.SQLite,
+		"DELETE FROM users WHERE id IN (sqlc.slice(ids));")
+
+	if !strings.Contains(funcSrc, "err := q.db.Exec(ctx, query, args...)") {
+		t.Errorf("Expected expanded args, got:\n%s", funcSrc)
+	}
+	if !strings.Contains(funcSrc, "query := `DELETE FROM users WHERE id IN (/*SLICE:ids*/?);`") {
+		t.Errorf("Expected slice marker, got:\n%s", funcSrc)
+	}
+}
+
+// TestGenerateSliceParams_EmptyNotIn tests an empty slice under NOT IN
+// matches every row on both dialects instead of none
+func TestGenerateSliceParams_EmptyNotIn(t *testing.T) {
+	tests := []struct {
+		dialect parser.Dialect
+		want    []string
+	}{
+		{parser.Postgres, []string{
+			"query := `SELECT id FROM users WHERE id <> ALL($1) AND email IN (SELECT email FROM users WHERE id = ANY($1));`",
+		}},
+		{parser.SQLite, []string{
+			"query := `SELECT id FROM users WHERE id NOT IN (/*SLICE:ids*/?) AND email IN (SELECT email FROM users WHERE id IN (/*SLICE:ids*/?));`",
+			`query = strings.Replace(query, "/*SLICE:ids*/?", "SELECT NULL WHERE 0", 1)`,
+			`query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.dialect), func(t *testing.T) {
+			queryBlock := &types.QueryBlock{Name: "ListOtherUsers", Type: types.MANY}
+			funcSrc, _ := generateFromSQL(t, sliceSchema(), queryBlock, tt.dialect,
+				"SELECT id FROM users WHERE id NOT IN (sqlc.slice(ids)) AND email IN (SELECT email FROM users WHERE id IN (sqlc.slice(ids)));")
+
+			for _, want := range tt.want {
+				if !strings.Contains(funcSrc, want) {
+					t.Errorf("Expected function to contain %s, got:\n%s", want, funcSrc)
+				}
+			}
+			if tt.dialect == parser.SQLite {
+				notIn := strings.Index(funcSrc, `"SELECT NULL WHERE 0"`)
+				in := strings.Index(funcSrc, `"NULL", 1)`)
+				if notIn > in {
+					t.Errorf("Expected the NOT IN list to be replaced first, got:\n%s", funcSrc)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package codegen
+
+import (
+	"fmt"
+	"go/ast"
+	"shogunc/internal/parser"
+	"shogunc/internal/types"
+	"strings"
+
+	"github.com/hector3211/shogun"
+)
+
+type UpdateGenerator struct {
+	schemaTypes map[string]any
+	queryblock  *types.QueryBlock
+	dialect     parser.Dialect
+	imports     map[string]bool // Packages the generated code uses
+}
+
+func NewUpdateGenerator(types map[string]any, queryBlock *types.QueryBlock) *UpdateGenerator {
+	return &UpdateGenerator{schemaTypes: types, queryblock: queryBlock, imports: make(map[string]bool)}
+}
+
+func (g *UpdateGenerator) SetDialect(dialect parser.Dialect) *UpdateGenerator {
+	g.dialect = dialect
+	return g
+}
+
+func (g UpdateGenerator) GenerateUpdateFunc(astStmt *types.UpdateStatement) (*ast.FuncDecl, *ast.GenDecl, error) {
+	fieldMap, err := inferDataType(g.schemaTypes, astStmt.TableName)
+	if err != nil {
+		return nil, nil, types.WrapError(astStmt.TableSpan, err)
+	}
+
+	var paramStruct *ast.GenDecl
+	var paramTypeName string
+	params := newQueryParams(g.dialect, g.binds(astStmt), fieldMap)
+	if len(params.fields) > 0 {
+		paramTypeName = fmt.Sprintf("%sParams", g.queryblock.Name)
+		paramStruct = paramStructDecl(paramTypeName, params.fields)
+	}
+
+	args := params.args()
+	args.addImports(g.imports)
+	function := generateExecFunc(g.queryblock.Name, paramTypeName, g.generateUpdateQuery(astStmt, params), args)
+	return function, paramStruct, nil
+}
+
+// binds returns the params of the SET values followed by the WHERE clause
+func (g UpdateGenerator) binds(astStmt *types.UpdateStatement) []types.Bind {
+	var binds []types.Bind
+	for _, set := range astStmt.Sets {
+		binds = append(binds, types.ExprBinds(set.Value)...)
+	}
+	return append(binds, types.ExprBinds(astStmt.Where)...)
+}
+
+func (g UpdateGenerator) generateUpdateQuery(astStmt *types.UpdateStatement, params queryParams) string {
+	var sets []string
+	for _, set := range astStmt.Sets {
+		sets = append(sets, fmt.Sprintf("%s = %s", types.QuoteIdent(set.Column, set.ColumnQuoted), params.sql(set.Value)))
+	}
+
+	queryBuilder := shogun.NewUpdateBuilder().
+		Update(types.QuoteIdent(astStmt.TableName, astStmt.TableQuoted)).
+		Set(strings.Join(sets, ", "))
+	if astStmt.Where != nil {
+		queryBuilder.Where(params.sql(astStmt.Where))
+	}
+	return queryBuilder.Build()
+}
@@ -0,0 +1,81 @@
+package codegen
+
+import (
+	"shogunc/internal/parser"
+	"shogunc/internal/types"
+	"strings"
+	"testing"
+)
+
+// TestGenerateUpdateFunc tests SET and WHERE params share one params struct
+// and the query keeps the WHERE expression as written
+func TestGenerateUpdateFunc(t *testing.T) {
+	schemaTypes := map[string]any{
+		"users": &parser.Table{
+			Name: "users",
+			Fields: []parser.Field{
+				{Name: "id", DataType: parser.Token{Literal: "UUID"}},
+				{Name: "email", DataType: parser.Token{Literal: "VARCHAR"}},
+				{Name: "role", DataType: parser.Token{Literal: "TEXT"}},
+			},
+		},
+	}
+
+	queryBlock := &types.QueryBlock{Name: "UpdateUserEmail", Type: types.EXEC}
+	funcSrc, structSrc := generateFromSQL(t, schemaTypes, queryBlock, parser.Postgres,
+		"UPDATE users SET email = $1 WHERE id = $2 AND (role = 'admin' OR lower(email) <> $3);")
+
+	expectedStruct := "type UpdateUserEmailParams struct {\n" +
+		"\tEmail\tstring\t`json:\"email\"`\n" +
+		"\tId\tstring\t`json:\"id\"`\n" +
+		"\tLower\tstring\t`json:\"lower\"`\n" +
+		"}"
+	if structSrc != expectedStruct {
+		t.Errorf("Expected struct:\n%s\ngot:\n%s", expectedStruct, structSrc)
+	}
+
+	expectedFunc := "func UpdateUserEmail(q *Queries, ctx context.Context, params UpdateUserEmailParams) error {\n" +
+		"\tquery := `UPDATE users SET email = $1 WHERE id = $2 AND (role = 'admin' OR lower(email) <> $3);`\n" +
+		"\terr := q.db.Exec(ctx, query, params.Email, params.Id, params.Lower)\n" +
+		"\treturn err\n" +
+		"}"
+	if funcSrc != expectedFunc {
+		t.Errorf("Expected function:\n%s\ngot:\n%s", expectedFunc, funcSrc)
+	}
+}
+
+// TestGenerateUpdateFunc_UnknownTable tests the table must be in the schema
+func TestGenerateUpdateFunc_UnknownTable(t *testing.T) {
+	generator := NewUpdateGenerator(map[string]any{}, &types.QueryBlock{Name: "UpdateUser", Type: types.EXEC})
+	stmt := &types.UpdateStatement{
+		TableName: "users",
+		Sets:      []types.Assignment{{Column: "email", Value: &types.Param{Column: "email", Position: 1}}},
+	}
+
+	if _, _, err := generator.GenerateUpdateFunc(stmt); err == nil || !strings.Contains(err.Error(), "table 'users' not found") {
+		t.Errorf("Expected table not found error, got %v", err)
+	}
+}
+
+// TestGenerateUpdateFunc_Quoted tests quoted table and SET column names are
+// written back quoted
+func TestGenerateUpdateFunc_Quoted(t *testing.T) {
+	schemaTypes := map[string]any{
+		"Order": &parser.Table{
+			Name: "Order",
+			Fields: []parser.Field{
+				{Name: "id", DataType: parser.Token{Literal: "INT"}},
+				{Name: "type", DataType: parser.Token{Literal: "TEXT"}},
+			},
+		},
+	}
+
+	queryBlock := &types.QueryBlock{Name: "SetOrderType", Type: types.EXEC}
+	funcSrc, _ := generateFromSQL(t, schemaTypes, queryBlock, parser.Postgres,
+		`UPDATE "Order" SET "type" = $1 WHERE id = $2;`)
+
+	query := "query := `UPDATE \"Order\" SET \"type\" = $1 WHERE id = $2;`"
+	if !strings.Contains(funcSrc, query) {
+		t.Errorf("Expected function to contain %s, got:\n%s", query, funcSrc)
+	}
+}
@@ -1,5 +1,10 @@
 package parser
 
+import (
+	"shogunc/internal/types"
+	"strings"
+)
+
 type Node any
 
 type Ast struct {
@@ -7,6 +12,13 @@ type Ast struct {
 	Statements   []Node
 	currentToken Token
 	peekToken    Token
+	errs         []error // Errors recovered from while parsing
+	comments     []Token // Comments written right before currentToken
+	peekComments []Token // Comments written right before peekToken
+	dialect      Dialect
+	namedParams  map[string]int    // Position given to each named param
+	lastParam    int               // Highest param position handed out
+	unterminated *types.Diagnostic // First string, quoted name or comment left open, it swallows the rest of the input
 }
 
 func NewAst(l *Lexer) *Ast {
@@ -15,7 +27,92 @@ func NewAst(l *Lexer) *Ast {
 	}
 }
 
+func (a *Ast) SetDialect(dialect Dialect) *Ast {
+	a.dialect = dialect
+	return a
+}
+
+// NextToken advances past comments, they are kept on the token they precede
 func (a *Ast) NextToken() {
 	a.currentToken = a.peekToken
+	a.comments = a.peekComments
+	a.peekComments = nil
+
 	a.peekToken = a.l.NextToken()
+	for a.peekToken.Type == COMMENT {
+		// A comment sharing a line with the token before it trails that token
+		if a.currentToken.Type == "" || a.peekToken.Pos.Line != a.currentToken.End.Line {
+			a.peekComments = append(a.peekComments, a.peekToken)
+		}
+		a.peekToken = a.l.NextToken()
+	}
+	if a.peekToken.Type == ILLEGAL && a.unterminated == nil {
+		a.unterminated = unterminatedError(a.peekToken)
+	}
+}
+
+// unterminatedError reports a literal or comment that never closes at its
+// opening quote, it is nil for any other ILLEGAL token
+func unterminatedError(tok Token) *types.Diagnostic {
+	var what string
+	switch {
+	case strings.HasPrefix(tok.Literal, "/*"):
+		what = "block comment"
+	case strings.HasPrefix(tok.Literal, "'"), strings.HasPrefix(strings.ToUpper(tok.Literal), "E'"):
+		what = "string literal"
+	case strings.HasPrefix(tok.Literal, `"`), strings.HasPrefix(tok.Literal, "`"):
+		what = "quoted identifier"
+	default:
+		return nil
+	}
+	return types.Errorf(types.Span{Pos: tok.Pos, End: tok.Pos}, "unterminated %s", what)
+}
+
+// leadingComment returns the text of the comments before the current token
+func (a *Ast) leadingComment() string {
+	var lines []string
+	for _, c := range a.comments {
+		lines = append(lines, CommentText(c.Literal))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isIdent reports whether the current token can be used as a name, which
+// includes keywords the dialect does not reserve
+func (a *Ast) isIdent() bool {
+	switch a.currentToken.Type {
+	case IDENT, QUOTED_IDENT:
+		return true
+	}
+	return IsKeyword(a.currentToken) && !a.dialect.IsReserved(a.currentToken.Type)
+}
+
+// identName returns the name the current token refers to, quoted names keep
+// their case and bare names fold to lower case
+func (a *Ast) identName() string {
+	if a.currentToken.Type == QUOTED_IDENT {
+		return a.currentToken.Literal
+	}
+	return strings.ToLower(a.currentToken.Literal)
+}
+
+// errorf reports an error at the current token
+func (a *Ast) errorf(format string, args ...any) error {
+	return types.Errorf(a.currentToken.Span, format, args...)
+}
+
+// errorAt reports an error at the given token
+func (a *Ast) errorAt(tok Token, format string, args ...any) error {
+	return types.Errorf(tok.Span, format, args...)
+}
+
+// synchronize skips past the next semicolon so parsing can resume at the
+// following statement
+func (a *Ast) synchronize() {
+	for a.currentToken.Type != SEMICOLON && a.currentToken.Type != EOF {
+		a.NextToken()
+	}
+	if a.currentToken.Type == SEMICOLON {
+		a.NextToken()
+	}
 }
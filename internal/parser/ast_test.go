@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"shogunc/internal/types"
 	"shogunc/utils"
+	"strings"
 	"testing"
 	"time"
 )
@@ -135,10 +136,10 @@ func TestAstStatementParsing(t *testing.T) {
 				switch stmt := n.(type) {
 				case *types.SelectStatement:
 					t.Logf("SELECT - table: %s, fields: %v", stmt.TableName, stmt.Columns)
-					for _, c := range stmt.Conditions {
-						t.Logf("Condition - Left: %s, Operator: %s, Right: %+v", c.Column, c.Operator, c.Value)
+					if stmt.Where != nil {
+						t.Logf("WHERE: %s", stmt.Where.SQL(Generic.Placeholder))
 					}
-					t.Logf("LIMIT: %d, OFFSET: %d", stmt.Limit, stmt.Offset)
+					t.Logf("LIMIT: %v, OFFSET: %v", stmt.Limit, stmt.Offset)
 
 				case *types.InsertStatement:
 					t.Logf("INSERT - table: %s, columns: %v", stmt.TableName, stmt.Columns)
@@ -172,13 +173,10 @@ func TestAstStatementParsing(t *testing.T) {
 			switch stmt := n.(type) {
 			case *types.SelectStatement:
 				t.Logf("SELECT - table: %s, fields: %v", stmt.TableName, stmt.Columns)
-				for _, c := range stmt.Conditions {
-					t.Logf("Condition - Left: %s, Operator: %s, Right: %+v", c.Column, c.Operator, c.Value)
-					if c.Value.Value != nil {
-						t.Logf("DEBUG: Literal value: %q", *c.Value.Value)
-					}
+				if stmt.Where != nil {
+					t.Logf("WHERE: %s", stmt.Where.SQL(Generic.Placeholder))
 				}
-				t.Logf("LIMIT: %d, OFFSET: %d", stmt.Limit, stmt.Offset)
+				t.Logf("LIMIT: %v, OFFSET: %v", stmt.Limit, stmt.Offset)
 			}
 		}
 	})
@@ -188,22 +186,32 @@ func TestBindFunctionality(t *testing.T) {
 	tests := []struct {
 		name     string
 		sql      string
-		expected []types.Condition
+		where    string
+		expected []types.Bind
 	}{
 		{
-			name: "SELECT with explicit bind parameters",
-			sql:  "SELECT * FROM users WHERE id = $1 AND name = $2;",
-			expected: []types.Condition{
-				{Column: "id", Operator: types.ConditionOp("="), Value: types.Bind{Column: "id", Position: 1}},
-				{Column: "name", Operator: types.ConditionOp("="), Value: types.Bind{Column: "name", Position: 2}},
+			name:  "SELECT with explicit bind parameters",
+			sql:   "SELECT * FROM users WHERE id = $1 AND name = $2;",
+			where: "id = $1 AND name = $2",
+			expected: []types.Bind{
+				{Column: "id", Position: 1},
+				{Column: "name", Position: 2},
 			},
 		},
 		{
-			name: "SELECT with string literals",
-			sql:  "SELECT * FROM users WHERE active = 'true' AND role = 'admin';",
-			expected: []types.Condition{
-				{Column: "active", Operator: types.ConditionOp("="), Value: types.Bind{Column: "active", Value: &[]string{"true"}[0]}},
-				{Column: "role", Operator: types.ConditionOp("="), Value: types.Bind{Column: "role", Value: &[]string{"admin"}[0]}},
+			name:  "SELECT with string literals",
+			sql:   "SELECT * FROM users WHERE active = 'true' AND role = 'admin';",
+			where: "active = 'true' AND role = 'admin'",
+		},
+		{
+			name:  "SELECT with multi-character operators",
+			sql:   "SELECT * FROM users WHERE age >= $1 AND age <= $2 OR role != 'admin' AND status <> 'banned' AND score > $3 AND rank < $4;",
+			where: "age >= $1 AND age <= $2 OR role != 'admin' AND status <> 'banned' AND score > $3 AND rank < $4",
+			expected: []types.Bind{
+				{Column: "age", Position: 1},
+				{Column: "age", Position: 2},
+				{Column: "score", Position: 3},
+				{Column: "rank", Position: 4},
 			},
 		},
 		{
@@ -230,27 +238,23 @@ func TestBindFunctionality(t *testing.T) {
 
 			switch stmt := parser.Statements[0].(type) {
 			case *types.SelectStatement:
-				if tt.expected != nil {
-					if len(stmt.Conditions) != len(tt.expected) {
-						t.Errorf("expected %d conditions, got %d", len(tt.expected), len(stmt.Conditions))
-						return
-					}
+				if got := stmt.Where.SQL(Generic.Placeholder); got != tt.where {
+					t.Errorf("expected WHERE %s, got %s", tt.where, got)
+				}
+
+				binds := types.ExprBinds(stmt.Where)
+				if len(binds) != len(tt.expected) {
+					t.Errorf("expected %d binds, got %d", len(tt.expected), len(binds))
+					return
+				}
 
-					for i, expected := range tt.expected {
-						actual := stmt.Conditions[i]
-						if actual.Column != expected.Column {
-							t.Errorf("condition %d: expected Left %s, got %s", i, expected.Column, actual.Column)
-						}
-						if actual.Operator != expected.Operator {
-							t.Errorf("condition %d: expected Operator %s, got %s", i, expected.Operator, actual.Operator)
-						}
-						// Check Bind struct
-						if actual.Value.Column != expected.Value.Column {
-							t.Errorf("condition %d: expected Column %s, got %s", i, expected.Value.Column, actual.Value.Column)
-						}
-						if actual.Value.Position != expected.Value.Position {
-							t.Errorf("condition %d: expected Position %d, got %d", i, expected.Value.Position, actual.Value.Position)
-						}
+				for i, expected := range tt.expected {
+					actual := binds[i]
+					if actual.Column != expected.Column {
+						t.Errorf("bind %d: expected Column %s, got %s", i, expected.Column, actual.Column)
+					}
+					if actual.Position != expected.Position {
+						t.Errorf("bind %d: expected Position %d, got %d", i, expected.Position, actual.Position)
 					}
 				}
 
@@ -342,7 +346,7 @@ func TestSchemaTypes(t *testing.T) {
 			{
 				Name: "id",
 				DataType: Token{
-					Type:    IDENT,
+					Type:    UUID,
 					Literal: "UUID",
 				},
 				NotNull:   true,
@@ -353,7 +357,7 @@ func TestSchemaTypes(t *testing.T) {
 			{
 				Name: "permit_number",
 				DataType: Token{
-					Type:    IDENT,
+					Type:    BIGINT,
 					Literal: "BIGINT",
 				},
 				NotNull:   true,
@@ -364,7 +368,7 @@ func TestSchemaTypes(t *testing.T) {
 			{
 				Name: "created_by",
 				DataType: Token{
-					Type:    IDENT,
+					Type:    SMALLINT,
 					Literal: "SMALLINT",
 				},
 				NotNull:   true,
@@ -375,7 +379,7 @@ func TestSchemaTypes(t *testing.T) {
 			{
 				Name: "updated_at",
 				DataType: Token{
-					Type:    IDENT,
+					Type:    TIMESTAMP,
 					Literal: "TIMESTAMP",
 				},
 				NotNull:   false,
@@ -386,7 +390,7 @@ func TestSchemaTypes(t *testing.T) {
 			{
 				Name: "expires_at",
 				DataType: Token{
-					Type:    IDENT,
+					Type:    TIMESTAMP,
 					Literal: "TIMESTAMP",
 				},
 				NotNull:   true,
@@ -399,7 +403,7 @@ func TestSchemaTypes(t *testing.T) {
 			{
 				Name: "id",
 				DataType: Token{
-					Type:    IDENT,
+					Type:    UUID,
 					Literal: "UUID",
 				},
 				NotNull:   false,
@@ -410,7 +414,7 @@ func TestSchemaTypes(t *testing.T) {
 			{
 				Name: "access_code",
 				DataType: Token{
-					Type:    IDENT,
+					Type:    VARCHAR,
 					Literal: "VARCHAR",
 				},
 				NotNull:   false,
@@ -421,7 +425,7 @@ func TestSchemaTypes(t *testing.T) {
 			{
 				Name: "in_use",
 				DataType: Token{
-					Type:    IDENT,
+					Type:    BOOLEAN,
 					Literal: "BOOLEAN",
 				},
 				NotNull:   true,
@@ -432,7 +436,7 @@ func TestSchemaTypes(t *testing.T) {
 			{
 				Name: "user_id",
 				DataType: Token{
-					Type:    IDENT,
+					Type:    BIGINT,
 					Literal: "BIGINT",
 				},
 				NotNull:   false,
@@ -462,7 +466,7 @@ func TestSchemaTypes(t *testing.T) {
 				if fields[idx].Name != f.Name {
 					t.Errorf("table %s: expected field name '%s', got '%s'", name, fields[idx].Name, f.Name)
 				}
-				if fields[idx].DataType != f.DataType {
+				if fields[idx].DataType.Type != f.DataType.Type || fields[idx].DataType.Literal != f.DataType.Literal {
 					t.Errorf("table %s: expected field '%s' type %v, got type %v", name, fields[idx].Name, fields[idx].DataType, f.DataType)
 				}
 			}
@@ -477,36 +481,36 @@ func TestStringifySelectStatement(t *testing.T) {
 	}{
 		{
 			stmt: &types.SelectStatement{
-				Columns:   []string{"id", "name"},
+				Columns:   []types.SelectColumn{{Expr: &types.ColumnRef{Column: "id"}}, {Expr: &types.ColumnRef{Column: "name"}}},
 				TableName: "users",
-				Conditions: []types.Condition{
-					{Column: "age", Operator: types.ConditionOp(">"), Value: types.Bind{Value: &[]string{"30"}[0]}},
+				Where: &types.BinaryExpr{
+					Left:  &types.ColumnRef{Column: "age"},
+					Op:    ">",
+					Right: &types.Literal{Value: "30", Kind: types.IntLiteral},
 				},
-				Limit:  10,
-				Offset: 5,
+				Limit:  &types.Literal{Value: "10", Kind: types.IntLiteral},
+				Offset: &types.Literal{Value: "5", Kind: types.IntLiteral},
 			},
 			want: "SELECT id, name FROM users WHERE age > 30 LIMIT 10 OFFSET 5;",
 		},
 		{
 			stmt: &types.SelectStatement{
-				Columns:    []string{"*"},
-				TableName:  "orders",
-				Conditions: nil,
-				Limit:      0,
-				Offset:     0,
+				Columns:   []types.SelectColumn{{Expr: &types.ColumnRef{Column: "*"}}},
+				TableName: "orders",
+				Where:     nil,
 			},
 			want: "SELECT * FROM orders;",
 		},
 		{
 			stmt: &types.SelectStatement{
-				Columns:   []string{"id", "name"},
+				Columns:   []types.SelectColumn{{Expr: &types.ColumnRef{Column: "id"}}, {Expr: &types.ColumnRef{Column: "name"}}},
 				TableName: "products",
-				Conditions: []types.Condition{
-					{Column: "price", Operator: types.ConditionOp(">="), Value: types.Bind{Value: &[]string{"100"}[0]}},
+				Where: &types.BinaryExpr{
+					Left:  &types.ColumnRef{Column: "price"},
+					Op:    ">=",
+					Right: &types.Literal{Value: "100", Kind: types.IntLiteral},
 				},
 				Distinct: true,
-				Limit:    0,
-				Offset:   0,
 			},
 			want: "SELECT DISTINCT id, name FROM products WHERE price >= 100;",
 		},
@@ -638,3 +642,1606 @@ func TestStringifyEnumType(t *testing.T) {
 		t.Errorf("unexpected enum SQL:\nGot:\n%s\n\nExpected:\n%s", got, expected)
 	}
 }
+
+func TestParseErrorPosition(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		parse func(*Ast) error
+		want  string
+	}{
+		{
+			name:  "query missing table",
+			input: "SELECT id\nFROM WHERE id = $1;",
+			parse: (*Ast).Parse,
+			want:  "queries/users.sql:2:6: expected table name (IDENT), got WHERE",
+		},
+		{
+			name:  "schema bad datatype",
+			input: "CREATE TABLE IF NOT EXISTS \"users\" (\n    \"id\" UUID,\n    \"age\" ,\n    \"name\" TEXT\n);",
+			parse: (*Ast).ParseSchema,
+			want:  "queries/users.sql:3:11: [PARSER_TABLE] expected datatype, got: , field_idx: 1",
+		},
+		{
+			name:  "insert missing closing paren",
+			input: "INSERT INTO users (id) VALUES (",
+			parse: (*Ast).Parse,
+			want:  "queries/users.sql:1:32: expected ) to close VALUES, got EOF",
+		},
+		{
+			name:  "insert too few values",
+			input: "INSERT INTO users (id, email) VALUES ($1);",
+			parse: (*Ast).Parse,
+			want:  "queries/users.sql:1:38: VALUES has 1 values for 2 columns",
+		},
+		{
+			name:  "insert too many values",
+			input: "INSERT INTO users (id) VALUES ($1, $2);",
+			parse: (*Ast).Parse,
+			want:  "queries/users.sql:1:31: VALUES has 2 values for 1 columns",
+		},
+		{
+			name:  "insert unsupported value",
+			input: "INSERT INTO users (id, created_at) VALUES ($1, now());",
+			parse: (*Ast).Parse,
+			want:  "queries/users.sql:1:48: unsupported value now in VALUES, wanted a param or literal",
+		},
+		{
+			name:  "insert several rows",
+			input: "INSERT INTO users (id) VALUES ($1), ($2);",
+			parse: (*Ast).Parse,
+			want:  "queries/users.sql:1:35: INSERT with more than one VALUES row is not supported",
+		},
+		{
+			name:  "query unterminated string",
+			input: "SELECT id FROM users\nWHERE name = 'O''Brien AND id = $1;",
+			parse: (*Ast).Parse,
+			want:  "queries/users.sql:2:14: unterminated string literal",
+		},
+		{
+			name:  "query unterminated quoted identifier",
+			input: "SELECT \"id FROM users;",
+			parse: (*Ast).Parse,
+			want:  "queries/users.sql:1:8: unterminated quoted identifier",
+		},
+		{
+			name:  "query unterminated comment",
+			input: "SELECT id FROM users; /* open",
+			parse: (*Ast).Parse,
+			want:  "queries/users.sql:1:23: unterminated block comment",
+		},
+		{
+			name:  "schema unterminated default",
+			input: "CREATE TABLE t (\n    a TEXT DEFAULT 'x,\n    b INT\n);\nCREATE TABLE u (id INT);",
+			parse: (*Ast).ParseSchema,
+			want:  "queries/users.sql:2:20: unterminated string literal",
+		},
+		{
+			name:  "schema table missing closing paren",
+			input: "CREATE TABLE t (a TEXT",
+			parse: (*Ast).ParseSchema,
+			want:  "queries/users.sql:1:23: [PARSER_TABLE] expected ) to close table t, got EOF",
+		},
+		{
+			name:  "schema table column cut off",
+			input: "CREATE TABLE t (a TEXT NOT NULL;",
+			parse: (*Ast).ParseSchema,
+			want:  "queries/users.sql:1:32: [PARSER_TABLE] expected ) to close table t, got ;",
+		},
+		{
+			name:  "schema enum missing closing paren",
+			input: "CREATE TYPE s AS ENUM ('a', 'b';",
+			parse: (*Ast).ParseSchema,
+			want:  "queries/users.sql:1:32: expected ) to close ENUM s, got ;",
+		},
+		{
+			name:  "schema enum at end of file",
+			input: "CREATE TYPE s AS ENUM ('a'",
+			parse: (*Ast).ParseSchema,
+			want:  "queries/users.sql:1:27: expected ) to close ENUM s, got EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lexer := NewLexerAt(tt.input, types.Position{File: "queries/users.sql", Line: 1, Column: 1})
+			err := tt.parse(NewAst(lexer))
+			if err == nil {
+				t.Fatal("expected parse error")
+			}
+			if err.Error() != tt.want {
+				t.Errorf("expected error %q, got %q", tt.want, err.Error())
+			}
+		})
+	}
+}
+
+func TestSchemaParseRecovers(t *testing.T) {
+	schema := `CREATE TABLE IF NOT EXISTS "users" (
+    "id"    UUID PRIMARY KEY,
+    "age"   ,
+    "email" VARCHAR NOT NULL,
+    "role"  TEXT DEFAULT
+);
+CREATE INDEX "users_email" ON "users" ("email");
+CREATE TYPE "Status" AS ENUM ('open', 'closed');
+CREATE TABLE IF NOT EXISTS "lockers" (
+    "id"    UUID PRIMARY KEY,
+    "code"  VARCHAR BOGUS
+);`
+
+	parser := NewAst(NewLexer(schema))
+	err := parser.ParseSchema()
+	if err == nil {
+		t.Fatal("expected schema errors")
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined errors, got %T", err)
+	}
+
+	expected := []string{
+		"3:13: [PARSER_TABLE] expected datatype, got: , field_idx: 1",
+		"6:1: [PARSER_TABLE] invalid DEFAULT value: ) TYPE: ) field_idx: 3",
+		"7:8: [SCHEMA_PARSER] unexpected token: INDEX",
+		"11:21: [PARSER_TABLE] unexpected token in field definition: BOGUS TYPE: IDENT field_idx: 1",
+	}
+	errs := joined.Unwrap()
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %d: %v", len(expected), len(errs), err)
+	}
+	for i, want := range expected {
+		if errs[i].Error() != want {
+			t.Errorf("error %d: expected %q, got %q", i, want, errs[i].Error())
+		}
+	}
+
+	// Statements around the bad ones are still parsed
+	var tables, enums int
+	for _, n := range parser.Statements {
+		switch s := n.(type) {
+		case *Table:
+			tables++
+			if s.Name == "users" && len(s.Fields) != 2 {
+				t.Errorf("expected users to keep its 2 valid fields, got %d", len(s.Fields))
+			}
+		case *Enum:
+			enums++
+		}
+	}
+	if tables != 2 || enums != 1 {
+		t.Errorf("expected 2 tables and 1 enum, got %d tables and %d enums", tables, enums)
+	}
+}
+
+func TestSchemaParseDialectTypes(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		schema  string
+		want    []string
+	}{
+		{
+			SQLite,
+			"CREATE TABLE scores (id INTEGER PRIMARY KEY, points REAL NOT NULL, seen DATETIME DEFAULT CURRENT_TIMESTAMP, note varchar(255));",
+			[]string{"id:INT:int", "points:DECIMAL:float64", "seen:TIMESTAMP:time.Time", "note:VARCHAR:string"},
+		},
+		{
+			Postgres,
+			`CREATE TABLE scores (id serial PRIMARY KEY, points double precision NOT NULL, seen timestamp with time zone DEFAULT now(), note character varying(255), state "State");`,
+			[]string{"id:INT:int", "points:DECIMAL:float64", "seen:TIMESTAMP:time.Time", "note:VARCHAR:string", "state:ENUM:State"},
+		},
+		{
+			Generic,
+			"CREATE TABLE scores (points numeric(10, 2), state state); CREATE TYPE state AS ENUM ('on', 'off');",
+			[]string{"points:DECIMAL:float64", "state:ENUM:state"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.dialect), func(t *testing.T) {
+			parser := NewAst(NewLexer(tt.schema)).SetDialect(tt.dialect)
+			if err := parser.ParseSchema(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, field := range parser.Statements[0].(*Table).Fields {
+				goType, _ := SqlToGoType(field.DataType)
+				got = append(got, fmt.Sprintf("%s:%s:%s", field.Name, field.DataType.Type, goType))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSchemaParseUnknownType(t *testing.T) {
+	schema := `CREATE TABLE docs (
+    id   INT PRIMARY KEY,
+    body JSONB NOT NULL,
+    raw  bytea,
+    size int8
+);`
+
+	parser := NewAst(NewLexer(schema)).SetDialect(SQLite)
+	err := parser.ParseSchema()
+	if err == nil {
+		t.Fatal("expected unknown type errors")
+	}
+
+	expected := "3:10: [PARSER_TABLE] unknown type jsonb for column body, wanted a database type or a CREATE TYPE\n" +
+		"4:10: [PARSER_TABLE] unknown type bytea for column raw, wanted a database type or a CREATE TYPE\n" +
+		"5:10: [PARSER_TABLE] unknown type int8 for column size, wanted a database type or a CREATE TYPE"
+	if err.Error() != expected {
+		t.Errorf("expected errors:\n%s\ngot:\n%s", expected, err.Error())
+	}
+
+	table := parser.Statements[0].(*Table)
+	if len(table.Fields) != 1 || table.Fields[0].Name != "id" {
+		t.Errorf("expected only id to be kept, got %+v", table.Fields)
+	}
+}
+
+func TestSchemaParseComments(t *testing.T) {
+	schema := `-- Everyone who can log in
+CREATE TABLE IF NOT EXISTS "users" (
+    /* primary key */
+    "id"    UUID PRIMARY KEY, -- generated by the app
+    -- "legacy_id" INT,
+    -- where we send receipts
+    "email" VARCHAR NOT NULL
+);
+/* ticket state */
+CREATE TYPE "Status" AS ENUM ('open', 'closed');`
+
+	parser := NewAst(NewLexer(schema))
+	if err := parser.ParseSchema(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parser.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(parser.Statements))
+	}
+
+	table, ok := parser.Statements[0].(*Table)
+	if !ok {
+		t.Fatalf("expected *Table, got %T", parser.Statements[0])
+	}
+	if table.Comment != "Everyone who can log in" {
+		t.Errorf("unexpected table comment: %q", table.Comment)
+	}
+	if len(table.Fields) != 2 {
+		t.Fatalf("expected 2 fields, got %d", len(table.Fields))
+	}
+	if table.Fields[0].Comment != "primary key" {
+		t.Errorf("unexpected id comment: %q", table.Fields[0].Comment)
+	}
+	if table.Fields[1].Comment != "\"legacy_id\" INT,\nwhere we send receipts" {
+		t.Errorf("unexpected email comment: %q", table.Fields[1].Comment)
+	}
+
+	enum, ok := parser.Statements[1].(*Enum)
+	if !ok {
+		t.Fatalf("expected *Enum, got %T", parser.Statements[1])
+	}
+	if enum.Comment != "ticket state" {
+		t.Errorf("unexpected enum comment: %q", enum.Comment)
+	}
+}
+
+func TestSchemaParseBareIdentifiers(t *testing.T) {
+	schema := `CREATE TYPE Mood AS ENUM ('happy', 'sad');
+CREATE TABLE Users (
+    id         UUID PRIMARY KEY,
+    "FullName" TEXT NOT NULL,
+    ` + "`nick`" + `   VARCHAR,
+    mood       Mood
+);
+CREATE TABLE IF NOT EXISTS "Audit" (
+    entry TEXT
+);`
+
+	parser := NewAst(NewLexer(schema))
+	if err := parser.ParseSchema(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parser.Statements) != 3 {
+		t.Fatalf("expected 3 statements, got %d", len(parser.Statements))
+	}
+
+	enum := parser.Statements[0].(*Enum)
+	if enum.Name != "mood" {
+		t.Errorf("expected enum name mood, got %q", enum.Name)
+	}
+
+	users := parser.Statements[1].(*Table)
+	if users.Name != "users" {
+		t.Errorf("expected table name users, got %q", users.Name)
+	}
+	names := []string{"id", "FullName", "nick", "mood"}
+	if len(users.Fields) != len(names) {
+		t.Fatalf("expected %d fields, got %d", len(names), len(users.Fields))
+	}
+	for i, name := range names {
+		if users.Fields[i].Name != name {
+			t.Errorf("field %d: expected name %q, got %q", i, name, users.Fields[i].Name)
+		}
+	}
+	if users.Fields[3].DataType.Type != ENUM || users.Fields[3].DataType.Literal != "mood" {
+		t.Errorf("expected mood to be ENUM mood, got %s %q", users.Fields[3].DataType.Type, users.Fields[3].DataType.Literal)
+	}
+
+	audit := parser.Statements[2].(*Table)
+	if audit.Name != "Audit" {
+		t.Errorf("expected quoted table name to keep its case, got %q", audit.Name)
+	}
+}
+
+// columnsSQL renders a SELECT list joined with commas
+func columnsSQL(columns []types.SelectColumn) string {
+	rendered := make([]string, len(columns))
+	for i, column := range columns {
+		rendered[i] = column.SQL(Generic.Placeholder)
+	}
+	return strings.Join(rendered, ",")
+}
+
+func TestParseQuotedIdentifiers(t *testing.T) {
+	parser := NewAst(NewLexer(`SELECT "Id", email FROM "Users" WHERE "Role" = 'admin';`))
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stmt := parser.Statements[0].(*types.SelectStatement)
+	if stmt.TableName != "Users" {
+		t.Errorf("expected table Users, got %q", stmt.TableName)
+	}
+	if got := columnsSQL(stmt.Columns); got != `"Id",email` {
+		t.Errorf("unexpected columns: %v", stmt.Columns)
+	}
+	// "Role" keeps its quotes and 'admin' stays a string literal
+	if got := stmt.Where.SQL(Generic.Placeholder); got != `"Role" = 'admin'` {
+		t.Errorf("unexpected WHERE: %s", got)
+	}
+
+	parser = NewAst(NewLexer(`INSERT INTO "Users" ("Id", email) VALUES ($1, $2) RETURNING "Id";`))
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	insert := parser.Statements[0].(*types.InsertStatement)
+	if insert.TableName != "Users" || len(insert.Columns) != 2 || insert.Columns[0] != "Id" {
+		t.Errorf("unexpected insert: %+v", insert)
+	}
+	if !insert.TableQuoted || fmt.Sprint(insert.ColumnQuoted) != "[true false]" {
+		t.Errorf("expected the table and Id to be quoted, got %+v", insert)
+	}
+	if len(insert.ReturningFields) != 1 || insert.ReturningFields[0] != "Id" {
+		t.Errorf("unexpected returning fields: %v", insert.ReturningFields)
+	}
+}
+
+func TestKeywordsAsIdentifiers(t *testing.T) {
+	schema := `CREATE TABLE docs (
+    id     UUID PRIMARY KEY,
+    type   TEXT NOT NULL,
+    status TEXT,
+    key    VARCHAR UNIQUE,
+    date   DATE,
+    "default" BOOLEAN
+);`
+
+	parser := NewAst(NewLexer(schema)).SetDialect(Postgres)
+	if err := parser.ParseSchema(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	table := parser.Statements[0].(*Table)
+	names := []string{"id", "type", "status", "key", "date", "default"}
+	if len(table.Fields) != len(names) {
+		t.Fatalf("expected %d fields, got %d", len(names), len(table.Fields))
+	}
+	for i, name := range names {
+		if table.Fields[i].Name != name {
+			t.Errorf("field %d: expected name %q, got %q", i, name, table.Fields[i].Name)
+		}
+	}
+	if !table.Fields[3].IsUnique || table.Fields[4].DataType.Type != DATE {
+		t.Errorf("keyword columns lost their definitions: %+v", table.Fields)
+	}
+
+	parser = NewAst(NewLexer("SELECT id, type, key FROM docs WHERE date = $1 AND status = $2;")).SetDialect(Postgres)
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stmt := parser.Statements[0].(*types.SelectStatement)
+	if got := columnsSQL(stmt.Columns); got != "id,type,key" {
+		t.Errorf("unexpected columns: %v", stmt.Columns)
+	}
+	binds := types.ExprBinds(stmt.Where)
+	if len(binds) != 2 || binds[0].Column != "date" || binds[1].Column != "status" {
+		t.Errorf("unexpected binds: %+v", binds)
+	}
+}
+
+func TestReservedKeywordsPerDialect(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		column  string
+		wantErr bool
+	}{
+		{dialect: SQLite, column: "offset", wantErr: false},
+		{dialect: Postgres, column: "offset", wantErr: true},
+		{dialect: Postgres, column: "values", wantErr: false},
+		{dialect: SQLite, column: "values", wantErr: true},
+		{dialect: Generic, column: "offset", wantErr: true},
+		{dialect: Generic, column: "type", wantErr: false},
+		{dialect: SQLite, column: "default", wantErr: true},
+		{dialect: SQLite, column: "like", wantErr: false},
+		{dialect: Postgres, column: "ilike", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%s", tt.dialect, tt.column), func(t *testing.T) {
+			schema := fmt.Sprintf("CREATE TABLE t (%s TEXT);", tt.column)
+			parser := NewAst(NewLexer(schema)).SetDialect(tt.dialect)
+			err := parser.ParseSchema()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if DialectFor("pgx") != Postgres || DialectFor("SQLite3") != SQLite || DialectFor("mysql") != Generic {
+		t.Error("unexpected dialect for driver")
+	}
+}
+
+func TestParseLiteralKinds(t *testing.T) {
+	parser := NewAst(NewLexer("SELECT * FROM products WHERE price > 9.99 AND stock > -5 AND name = 'O''Brien' AND active = TRUE;"))
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stmt := parser.Statements[0].(*types.SelectStatement)
+	expected := []struct {
+		value string
+		kind  types.LiteralKind
+	}{
+		{"9.99", types.FloatLiteral},
+		{"-5", types.IntLiteral},
+		{"O'Brien", types.StringLiteral},
+		{"true", types.BoolLiteral},
+	}
+	var literals []*types.Literal
+	types.WalkExpr(stmt.Where, func(e types.Expr) {
+		if literal, ok := e.(*types.Literal); ok {
+			literals = append(literals, literal)
+		}
+	})
+	if len(literals) != len(expected) {
+		t.Fatalf("expected %d literals, got %d", len(expected), len(literals))
+	}
+	for i, want := range expected {
+		got := literals[i]
+		if got.Value != want.value || got.Kind != want.kind {
+			t.Errorf("literal %d: expected %s %q, got %s %q", i, want.kind, want.value, got.Kind, got.Value)
+		}
+	}
+
+	expectedSQL := "SELECT * FROM products WHERE price > 9.99 AND stock > -5 AND name = 'O''Brien' AND active = TRUE;"
+	if parser.String() != expectedSQL {
+		t.Errorf("expected %q, got %q", expectedSQL, parser.String())
+	}
+
+	schema := `CREATE TABLE people (
+    name  TEXT DEFAULT 'O''Brien',
+    score DECIMAL DEFAULT -1.5,
+    note  TEXT DEFAULT NULL
+);`
+	parser = NewAst(NewLexer(schema))
+	if err := parser.ParseSchema(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	table := parser.Statements[0].(*Table)
+	if d := table.Fields[0].Default; d == nil || *d != "O'Brien" {
+		t.Errorf("unexpected name default: %v", d)
+	}
+	if d := table.Fields[1].Default; d == nil || *d != "-1.5" {
+		t.Errorf("unexpected score default: %v", d)
+	}
+	if table.Fields[2].Default != nil {
+		t.Errorf("expected DEFAULT NULL to leave no default, got %q", *table.Fields[2].Default)
+	}
+}
+
+func TestParseNamedParams(t *testing.T) {
+	parser := NewAst(NewLexer(`SELECT * FROM events WHERE created_at > @start_date AND created_at < :end_date AND owner = sqlc.arg(owner_id) OR updated_at > @start_date;`))
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stmt := parser.Statements[0].(*types.SelectStatement)
+	expected := []types.Bind{
+		{Column: "created_at", Name: "start_date", Position: 1},
+		{Column: "created_at", Name: "end_date", Position: 2},
+		{Column: "owner", Name: "owner_id", Position: 3},
+		{Column: "updated_at", Name: "start_date", Position: 1},
+	}
+	binds := types.ExprBinds(stmt.Where)
+	if len(binds) != len(expected) {
+		t.Fatalf("expected %d binds, got %d", len(expected), len(binds))
+	}
+	for i, want := range expected {
+		got := binds[i]
+		if got.Column != want.Column || got.Name != want.Name || got.Position != want.Position {
+			t.Errorf("bind %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	parser = NewAst(NewLexer(`INSERT INTO users (id, email) VALUES (@id, sqlc.arg(email));`))
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	insert := parser.Statements[0].(*types.InsertStatement)
+	if len(insert.Values) != 2 || insert.Values[0].Name != "id" || insert.Values[1].Name != "email" || insert.Values[1].Position != 2 {
+		t.Errorf("unexpected insert values: %+v", insert.Values)
+	}
+
+	// Without a column list the values are left for the schema to name
+	parser = NewAst(NewLexer(`INSERT INTO users VALUES ($1, $2);`))
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	insert = parser.Statements[0].(*types.InsertStatement)
+	if len(insert.Columns) != 0 || len(insert.Values) != 2 || insert.Values[1].Position != 2 {
+		t.Errorf("unexpected insert: %+v", insert)
+	}
+}
+
+func TestParseNamedParamErrors(t *testing.T) {
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT * FROM users WHERE id = $1 AND email = @email;", "1:47: cannot mix named param email with positional params"},
+		{"SELECT * FROM users WHERE id = @id AND email = $2;", "1:49: cannot mix $2 with named params"},
+		{"SELECT * FROM users WHERE id = sqlc.narg(id);", "1:37: unknown sqlc macro sqlc.narg, wanted sqlc.arg or sqlc.slice"},
+		{"SELECT * FROM users WHERE id = sqlc.arg(id;", "1:43: expected ) after sqlc.arg(id, got ;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			err := NewAst(NewLexer(tt.sql)).Parse()
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseSQLitePlaceholders(t *testing.T) {
+	tests := []struct {
+		sql      string
+		expected []types.Bind
+	}{
+		{
+			"SELECT * FROM users WHERE id = ? AND email = ? OR name = ?;",
+			[]types.Bind{{Column: "id", Position: 1}, {Column: "email", Position: 2}, {Column: "name", Position: 3}},
+		},
+		{
+			"SELECT * FROM users WHERE id = ?2 AND email = ?1 OR name = ?2;",
+			[]types.Bind{{Column: "id", Position: 2}, {Column: "email", Position: 1}, {Column: "name", Position: 2}},
+		},
+		{
+			// A bare ? takes the number after the highest one used so far
+			"SELECT * FROM users WHERE id = ?3 AND email = ? OR name = ?1;",
+			[]types.Bind{{Column: "id", Position: 3}, {Column: "email", Position: 4}, {Column: "name", Position: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			parser := NewAst(NewLexer(tt.sql)).SetDialect(SQLite)
+			if err := parser.Parse(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			stmt := parser.Statements[0].(*types.SelectStatement)
+			binds := types.ExprBinds(stmt.Where)
+			if len(binds) != len(tt.expected) {
+				t.Fatalf("expected %d binds, got %d", len(tt.expected), len(binds))
+			}
+			for i, want := range tt.expected {
+				got := binds[i]
+				if got.Column != want.Column || got.Position != want.Position {
+					t.Errorf("bind %d: expected %+v, got %+v", i, want, got)
+				}
+			}
+		})
+	}
+
+	parser := NewAst(NewLexer(`INSERT INTO users (id, email, name) VALUES (?, ?, 'anon');`)).SetDialect(SQLite)
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	insert := parser.Statements[0].(*types.InsertStatement)
+	if len(insert.Values) != 3 || insert.Values[0].Position != 1 || insert.Values[1].Position != 2 || insert.Values[2].Position != 0 {
+		t.Errorf("unexpected insert values: %+v", insert.Values)
+	}
+}
+
+func TestParseSQLitePlaceholderErrors(t *testing.T) {
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT * FROM users WHERE id = ?0;", "1:32: invalid bind param: ?0"},
+		{"SELECT * FROM users WHERE id = @id AND email = ?;", "1:48: cannot mix ? with named params"},
+		{"SELECT * FROM users WHERE id = ? AND email = @email;", "1:46: cannot mix named param email with positional params"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			err := NewAst(NewLexer(tt.sql)).SetDialect(SQLite).Parse()
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// exprTree renders e with every operator wrapped in brackets, so tests can
+// check how it was grouped
+func exprTree(e types.Expr) string {
+	switch node := e.(type) {
+	case *types.BinaryExpr:
+		return fmt.Sprintf("[%s %s %s]", exprTree(node.Left), node.Op, exprTree(node.Right))
+	case *types.UnaryExpr:
+		return fmt.Sprintf("[%s %s]", node.Op, exprTree(node.Operand))
+	case *types.ParenExpr:
+		return "(" + exprTree(node.Inner) + ")"
+	case *types.FuncCall:
+		var args []string
+		for _, arg := range node.Args {
+			args = append(args, exprTree(arg))
+		}
+		if node.Star {
+			args = append(args, "*")
+		}
+		quantifier := ""
+		if node.Quantifier != "" {
+			quantifier = node.Quantifier + " "
+		}
+		return node.Name + "(" + quantifier + strings.Join(args, ", ") + ")"
+	case *types.InExpr:
+		var items []string
+		for _, item := range node.List {
+			items = append(items, exprTree(item))
+		}
+		return fmt.Sprintf("[%s IN%s (%s)]", exprTree(node.Expr), negatedTree(node.Not), strings.Join(items, ", "))
+	case *types.BetweenExpr:
+		return fmt.Sprintf("[%s BETWEEN%s %s %s]", exprTree(node.Expr), negatedTree(node.Not), exprTree(node.Low), exprTree(node.High))
+	case *types.IsNullExpr:
+		return fmt.Sprintf("[%s IS NULL%s]", exprTree(node.Expr), negatedTree(node.Not))
+	default:
+		return e.SQL(Generic.Placeholder)
+	}
+}
+
+func negatedTree(not bool) string {
+	if not {
+		return "!"
+	}
+	return ""
+}
+
+func TestParseWhereExpressions(t *testing.T) {
+	tests := []struct {
+		where string
+		tree  string
+	}{
+		{"a = $1 OR b = $2 AND c = $3", "[[a = $1] OR [[b = $2] AND [c = $3]]]"},
+		{"(a = $1 OR b = $2) AND c = $3", "[([[a = $1] OR [b = $2]]) AND [c = $3]]"},
+		{"NOT active AND id = $1", "[[NOT active] AND [id = $1]]"},
+		{"NOT id = $1", "[NOT [id = $1]]"},
+		{"lower(email) = $1", "[lower(email) = $1]"},
+		{"count(*) > 1", "[count(*) > 1]"},
+		{"count(DISTINCT email) > 1 AND sum(ALL amount) > $1", "[[count(DISTINCT email) > 1] AND [sum(ALL amount) > $1]]"},
+		{"coalesce(nickname, first_name || ' ' || last_name) = $1", "[coalesce(nickname, [[first_name || ' '] || last_name]) = $1]"},
+		{"price * quantity - discount >= $1", "[[[price * quantity] - discount] >= $1]"},
+		{"-balance < 0", "[[- balance] < 0]"},
+		{"- -5 < balance", "[[- -5] < balance]"},
+		{"- -balance < 0", "[[- [- balance]] < 0]"},
+		{"u.email = $1", "[u.email = $1]"},
+		{"name LIKE $1 OR name ILIKE 'a%'", "[[name LIKE $1] OR [name ILIKE 'a%']]"},
+		{"age IS DISTINCT FROM $1 AND score IS NOT DISTINCT FROM NULL", "[[age IS DISTINCT FROM $1] AND [score IS NOT DISTINCT FROM NULL]]"},
+		{"id IN ($1, $2) AND role NOT IN ('admin', 'owner')", "[[id IN ($1, $2)] AND [role IN! ('admin', 'owner')]]"},
+		{"age BETWEEN $1 AND $2 AND active = TRUE", "[[age BETWEEN $1 $2] AND [active = TRUE]]"},
+		{"age NOT BETWEEN 18 + 1 AND 65 OR age IS NULL", "[[age BETWEEN! [18 + 1] 65] OR [age IS NULL]]"},
+		{"deleted_at IS NOT NULL AND name NOT LIKE 'test%'", "[[deleted_at IS NULL!] AND [name NOT LIKE 'test%']]"},
+		{"NOT email IS NULL", "[NOT [email IS NULL]]"},
+		{`"Users"."Role" <> 'admin'`, `["Users"."Role" <> 'admin']`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			parser := NewAst(NewLexer("SELECT * FROM users WHERE " + tt.where + ";"))
+			if err := parser.Parse(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			stmt := parser.Statements[0].(*types.SelectStatement)
+			if got := exprTree(stmt.Where); got != tt.tree {
+				t.Errorf("expected tree %s, got %s", tt.tree, got)
+			}
+			if got := stmt.Where.SQL(Generic.Placeholder); got != tt.where {
+				t.Errorf("expected SQL %s, got %s", tt.where, got)
+			}
+		})
+	}
+}
+
+func TestParseUpdateAndDelete(t *testing.T) {
+	parser := NewAst(NewLexer("UPDATE users SET email = $1, updated_at = now() WHERE id = $2 AND (role = 'admin' OR NOT active);"))
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	update := parser.Statements[0].(*types.UpdateStatement)
+	if update.TableName != "users" || len(update.Sets) != 2 || update.Sets[0].Column != "email" || update.Sets[1].Column != "updated_at" {
+		t.Fatalf("unexpected update: %+v", update)
+	}
+	if binds := types.ExprBinds(update.Sets[0].Value); len(binds) != 1 || binds[0].Column != "email" || binds[0].Position != 1 {
+		t.Errorf("expected SET param to be tied to email, got %+v", binds)
+	}
+	expected := "UPDATE users SET email = $1, updated_at = now() WHERE id = $2 AND (role = 'admin' OR NOT active);"
+	if parser.String() != expected {
+		t.Errorf("expected %q, got %q", expected, parser.String())
+	}
+
+	parser = NewAst(NewLexer("DELETE FROM sessions WHERE expires_at < now() OR user_id = $1;"))
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	del := parser.Statements[0].(*types.DeleteStatement)
+	if del.TableName != "sessions" {
+		t.Errorf("expected table sessions, got %q", del.TableName)
+	}
+	if binds := types.ExprBinds(del.Where); len(binds) != 1 || binds[0].Column != "user_id" {
+		t.Errorf("unexpected binds: %+v", binds)
+	}
+	expected = "DELETE FROM sessions WHERE expires_at < now() OR user_id = $1;"
+	if parser.String() != expected {
+		t.Errorf("expected %q, got %q", expected, parser.String())
+	}
+}
+
+func TestParseWhereErrors(t *testing.T) {
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT * FROM users WHERE (id = $1;", "1:35: expected ), got ;"},
+		{"SELECT * FROM users WHERE id = ;", "1:32: expected expression, got ;"},
+		{"SELECT * FROM users WHERE lower(email = $1;", "1:43: expected ), got ;"},
+		{"SELECT * FROM users WHERE u. = $1;", "1:30: expected column name after u., got ="},
+		{"UPDATE users SET = $1;", "1:18: expected column name in SET, got ="},
+		{"SELECT * FROM users WHERE id IN $1;", "1:33: expected (, got $"},
+		{"SELECT * FROM users WHERE id IN ();", "1:34: expected expression, got )"},
+		{"SELECT * FROM users WHERE age BETWEEN $1 OR $2;", "1:42: expected AND, got OR"},
+		{"SELECT * FROM users WHERE age IS DISTINCT $1;", "1:34: expected NULL or DISTINCT FROM after IS, got DISTINCT"},
+		{"SELECT * FROM users WHERE age IS NOT 1;", "1:38: expected NULL or DISTINCT FROM after IS NOT, got 1"},
+		{"DELETE FROM users WHERE id = $1 id;", "1:33: unexpected id at end of statement"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			err := NewAst(NewLexer(tt.sql)).Parse()
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParsePredicateBinds(t *testing.T) {
+	parser := NewAst(NewLexer("SELECT * FROM users WHERE age BETWEEN $1 AND $2 AND id IN ($3, $4) AND deleted_at IS NULL;"))
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stmt := parser.Statements[0].(*types.SelectStatement)
+	expected := []types.Bind{
+		{Column: "age", Name: "min_age", Position: 1},
+		{Column: "age", Name: "max_age", Position: 2},
+		{Column: "id", Position: 3},
+		{Column: "id", Position: 4},
+	}
+	binds := types.ExprBinds(stmt.Where)
+	if len(binds) != len(expected) {
+		t.Fatalf("expected %d binds, got %d: %+v", len(expected), len(binds), binds)
+	}
+	for i, want := range expected {
+		if binds[i] != want {
+			t.Errorf("bind %d: expected %+v, got %+v", i, want, binds[i])
+		}
+	}
+}
+
+func TestParseSliceParams(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		sql     string
+		bind    types.Bind
+	}{
+		{Postgres, "SELECT * FROM users WHERE id IN (sqlc.slice(ids));", types.Bind{Column: "id", Name: "ids", Position: 1, Slice: true}},
+		{SQLite, "SELECT * FROM users WHERE id NOT IN (sqlc.slice('ids'));", types.Bind{Column: "id", Name: "ids", Position: 1, Slice: true, NotIn: true}},
+		{Postgres, "SELECT * FROM users WHERE id = ANY($1);", types.Bind{Column: "id", Position: 1, Slice: true}},
+		{Postgres, "SELECT * FROM users WHERE id <> ALL(@ids);", types.Bind{Column: "id", Name: "ids", Position: 1, Slice: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			parser := NewAst(NewLexer(tt.sql)).SetDialect(tt.dialect)
+			if err := parser.Parse(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			binds := types.ExprBinds(parser.Statements[0].(*types.SelectStatement).Where)
+			if len(binds) != 1 || binds[0] != tt.bind {
+				t.Errorf("expected bind %+v, got %+v", tt.bind, binds)
+			}
+		})
+	}
+}
+
+func TestParseSliceParamErrors(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		sql     string
+		err     string
+	}{
+		{Postgres, "SELECT * FROM users WHERE id = sqlc.slice(ids);", "1:32: sqlc.slice(ids) can only be used as the list of an IN"},
+		{Postgres, "SELECT * FROM users WHERE id IN (1, sqlc.slice(ids));", "1:37: sqlc.slice(ids) has to be the only item of an IN list"},
+		{Postgres, "UPDATE users SET id = sqlc.slice(ids);", "1:23: sqlc.slice(ids) can only be used as the list of an IN"},
+		{SQLite, "SELECT * FROM users WHERE id = ANY(@ids);", "1:36: sqlite has no arrays, use IN (sqlc.slice(ids)) instead of ANY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			err := NewAst(NewLexer(tt.sql)).SetDialect(tt.dialect).Parse()
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseJoins(t *testing.T) {
+	tests := []struct {
+		sql   string
+		joins []types.Join
+		want  string // Statement rendered back
+	}{
+		{
+			sql:   "SELECT u.email, posts.* FROM users JOIN posts ON posts.user_id = users.id;",
+			joins: []types.Join{{Type: types.InnerJoin, Table: types.TableRef{Name: "posts"}}},
+			want:  "SELECT u.email, posts.* FROM users INNER JOIN posts ON posts.user_id = users.id;",
+		},
+		{
+			sql: "SELECT * FROM users LEFT OUTER JOIN posts USING (id, user_id) RIGHT JOIN tags ON tags.id = $1 WHERE users.id = $2;",
+			joins: []types.Join{
+				{Type: types.LeftJoin, Table: types.TableRef{Name: "posts"}, Using: []types.Ident{{Name: "id"}, {Name: "user_id"}}},
+				{Type: types.RightJoin, Table: types.TableRef{Name: "tags"}},
+			},
+			want: "SELECT * FROM users LEFT JOIN posts USING (id, user_id) RIGHT JOIN tags ON tags.id = $1 WHERE users.id = $2;",
+		},
+		{
+			sql: "SELECT * FROM users full join posts on true CROSS JOIN tags;",
+			joins: []types.Join{
+				{Type: types.FullJoin, Table: types.TableRef{Name: "posts"}},
+				{Type: types.CrossJoin, Table: types.TableRef{Name: "tags"}},
+			},
+			want: "SELECT * FROM users FULL JOIN posts ON TRUE CROSS JOIN tags;",
+		},
+		{
+			sql: `SELECT o.id FROM "Users" u LEFT JOIN "order" o USING ("Id", user_id);`,
+			joins: []types.Join{
+				{Type: types.LeftJoin, Table: types.TableRef{Name: "order", Quoted: true}, Using: []types.Ident{{Name: "Id", Quoted: true}, {Name: "user_id"}}},
+			},
+			want: `SELECT o.id FROM "Users" AS u LEFT JOIN "order" AS o USING ("Id", user_id);`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			parser := NewAst(NewLexer(tt.sql))
+			if err := parser.Parse(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			stmt := parser.Statements[0].(*types.SelectStatement)
+			if len(stmt.Joins) != len(tt.joins) {
+				t.Fatalf("expected %d joins, got %+v", len(tt.joins), stmt.Joins)
+			}
+			for i, want := range tt.joins {
+				got := stmt.Joins[i]
+				if got.Type != want.Type || got.Table.Name != want.Table.Name || got.Table.Quoted != want.Table.Quoted || fmt.Sprint(got.Using) != fmt.Sprint(want.Using) {
+					t.Errorf("join %d: expected %+v, got %+v", i, want, got)
+				}
+			}
+			if got := parser.String(); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseJoinBinds(t *testing.T) {
+	parser := NewAst(NewLexer("SELECT * FROM users JOIN posts ON posts.user_id = users.id AND posts.title = $1 WHERE users.email = $2;"))
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stmt := parser.Statements[0].(*types.SelectStatement)
+	binds := append(types.ExprBinds(stmt.Joins[0].On), types.ExprBinds(stmt.Where)...)
+	expected := []types.Bind{
+		{Column: "title", Table: "posts", Position: 1},
+		{Column: "email", Table: "users", Position: 2},
+	}
+	if len(binds) != len(expected) {
+		t.Fatalf("expected %d binds, got %+v", len(expected), binds)
+	}
+	for i, want := range expected {
+		if binds[i] != want {
+			t.Errorf("bind %d: expected %+v, got %+v", i, want, binds[i])
+		}
+	}
+}
+
+func TestParseJoinErrors(t *testing.T) {
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT * FROM users JOIN posts;", "1:31: expected ON or USING after JOIN posts, got ;"},
+		{"SELECT * FROM users LEFT posts ON true;", "1:26: expected JOIN, got IDENT"},
+		{"SELECT * FROM users JOIN ON true;", "1:26: expected table name (IDENT), got ON"},
+		{"SELECT * FROM users JOIN posts USING ();", "1:39: expected column name in USING, got )"},
+		{"SELECT * FROM users JOIN posts ON id = sqlc.slice(ids);", "1:40: sqlc.slice(ids) can only be used as the list of an IN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			err := NewAst(NewLexer(tt.sql)).Parse()
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseAliases(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string // Statement rendered back
+	}{
+		{
+			"SELECT first_name AS name, u.email mail FROM users u;",
+			"SELECT first_name AS name, u.email AS mail FROM users AS u;",
+		},
+		{
+			`SELECT p.title AS "Title" FROM users AS u LEFT JOIN posts p ON p.user_id = u.id;`,
+			`SELECT p.title AS "Title" FROM users AS u LEFT JOIN posts AS p ON p.user_id = u.id;`,
+		},
+		{
+			"SELECT u.* FROM users u WHERE u.id = $1;",
+			"SELECT u.* FROM users AS u WHERE u.id = $1;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			parser := NewAst(NewLexer(tt.sql))
+			if err := parser.Parse(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := parser.String(); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	parser := NewAst(NewLexer("SELECT first_name AS name FROM users u;"))
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stmt := parser.Statements[0].(*types.SelectStatement)
+	if len(stmt.Columns) != 1 || stmt.Columns[0].Alias != "name" || stmt.TableName != "users" || stmt.TableAlias != "u" {
+		t.Errorf("unexpected statement: %+v", stmt)
+	}
+}
+
+func TestParseAliasErrors(t *testing.T) {
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT id AS FROM users;", "1:14: expected alias after AS, got FROM"},
+		{"SELECT id FROM users AS;", "1:24: expected alias after AS, got ;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			err := NewAst(NewLexer(tt.sql)).Parse()
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseOrderBy(t *testing.T) {
+	parser := NewAst(NewLexer("SELECT * FROM users WHERE age > $1 ORDER BY last_name, created_at desc NULLS LAST, lower(email) ASC nulls first LIMIT 10;"))
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stmt := parser.Statements[0].(*types.SelectStatement)
+	expected := []struct {
+		expr, direction, nulls string
+	}{
+		{"last_name", "", ""},
+		{"created_at", "DESC", "LAST"},
+		{"lower(email)", "ASC", "FIRST"},
+	}
+	if len(stmt.OrderBy) != len(expected) {
+		t.Fatalf("expected %d terms, got %+v", len(expected), stmt.OrderBy)
+	}
+	for i, want := range expected {
+		term := stmt.OrderBy[i]
+		if term.Expr.SQL(Generic.Placeholder) != want.expr || term.Direction != want.direction || term.Nulls != want.nulls {
+			t.Errorf("term %d: expected %+v, got %+v", i, want, term)
+		}
+	}
+	if stmt.Where.SQL(Generic.Placeholder) != "age > $1" || stmt.Limit.SQL(Generic.Placeholder) != "10" {
+		t.Errorf("ORDER BY swallowed the clauses around it: %+v", stmt)
+	}
+
+	want := "SELECT * FROM users WHERE age > $1 ORDER BY last_name, created_at DESC NULLS LAST, lower(email) ASC NULLS FIRST LIMIT 10;"
+	if got := parser.String(); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestParseOrderByErrors(t *testing.T) {
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT * FROM users ORDER id;", "1:27: expected BY, got IDENT"},
+		{"SELECT * FROM users ORDER BY;", "1:29: expected expression, got ;"},
+		{"SELECT * FROM users ORDER BY id NULLS;", "1:38: expected FIRST or LAST after NULLS, got ;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			err := NewAst(NewLexer(tt.sql)).Parse()
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseLimitOffset(t *testing.T) {
+	tests := []struct {
+		sql    string
+		limit  string
+		offset string
+		names  []string
+	}{
+		{"SELECT * FROM users LIMIT 10 OFFSET 5;", "10", "5", nil},
+		{"SELECT * FROM users LIMIT $1 OFFSET $2;", "$1", "$2", []string{"limit", "offset"}},
+		{"SELECT * FROM users LIMIT @page_size OFFSET @page_start;", "$1", "$2", []string{"page_size", "page_start"}},
+		{"SELECT * FROM users WHERE id > $1 LIMIT $2;", "$2", "", []string{"limit"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			parser := NewAst(NewLexer(tt.sql))
+			if err := parser.Parse(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			stmt := parser.Statements[0].(*types.SelectStatement)
+			if got := stmt.Limit.SQL(Generic.Placeholder); got != tt.limit {
+				t.Errorf("expected LIMIT %s, got %s", tt.limit, got)
+			}
+			if tt.offset == "" && stmt.Offset != nil {
+				t.Errorf("expected no OFFSET, got %v", stmt.Offset)
+			}
+			if tt.offset != "" && stmt.Offset.SQL(Generic.Placeholder) != tt.offset {
+				t.Errorf("expected OFFSET %s, got %v", tt.offset, stmt.Offset)
+			}
+
+			var names []string
+			for _, bind := range append(types.ExprBinds(stmt.Limit), types.ExprBinds(stmt.Offset)...) {
+				if bind.Type != "int64" {
+					t.Errorf("expected %s to be int64, got %q", bind.Name, bind.Type)
+				}
+				names = append(names, bind.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.names, ",") {
+				t.Errorf("expected params %v, got %v", tt.names, names)
+			}
+		})
+	}
+}
+
+func TestParseGroupBy(t *testing.T) {
+	parser := NewAst(NewLexer("SELECT status, COUNT(*) AS n FROM complaints GROUP BY status, lower(kind) HAVING COUNT(*) > @min_count AND min(amount) < @cap ORDER BY n DESC;"))
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stmt := parser.Statements[0].(*types.SelectStatement)
+	if len(stmt.GroupBy) != 2 || stmt.GroupBy[1].SQL(Generic.Placeholder) != "lower(kind)" {
+		t.Errorf("expected GROUP BY status, lower(kind), got %+v", stmt.GroupBy)
+	}
+
+	binds := types.ExprBinds(stmt.Having)
+	if len(binds) != 2 {
+		t.Fatalf("expected 2 binds, got %+v", binds)
+	}
+	if binds[0].Name != "min_count" || binds[0].Type != "int64" {
+		t.Errorf("expected COUNT(*) to type its param as an int64, got %+v", binds[0])
+	}
+	if binds[1].Name != "cap" || binds[1].Column != "amount" || binds[1].Type != "" {
+		t.Errorf("expected min(amount) to tie its param to amount, got %+v", binds[1])
+	}
+
+	want := "SELECT status, COUNT(*) AS n FROM complaints GROUP BY status, lower(kind) HAVING COUNT(*) > $1 AND min(amount) < $2 ORDER BY n DESC;"
+	if got := parser.String(); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestParseCast(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT CAST(age AS text) FROM users;", "SELECT CAST(age AS TEXT) FROM users;"},
+		{"SELECT cast(name AS varchar(20)) AS short FROM users;", "SELECT CAST(name AS VARCHAR(20)) AS short FROM users;"},
+		{"SELECT * FROM users WHERE CAST(balance AS DECIMAL(10, 2)) > $1;", "SELECT * FROM users WHERE CAST(balance AS DECIMAL(10,2)) > $1;"},
+		{"SELECT age::text, -balance::decimal(10, 2) AS debt FROM users;", "SELECT age::TEXT, -balance::DECIMAL(10,2) AS debt FROM users;"},
+		{"SELECT * FROM users WHERE created_at::date = $1::date + 1;", "SELECT * FROM users WHERE created_at::DATE = $1::DATE + 1;"},
+		{"SELECT CAST(name AS character varying(20)), score::double precision FROM users;", "SELECT CAST(name AS CHARACTER VARYING(20)), score::DOUBLE PRECISION FROM users;"},
+		{`SELECT id FROM tickets WHERE status = CAST($1 AS "Status") OR status::"Status" = 'open';`, `SELECT id FROM tickets WHERE status = CAST($1 AS "Status") OR status::"Status" = 'open';`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			parser := NewAst(NewLexer(tt.sql))
+			if err := parser.Parse(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := parser.String(); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	errors := []struct {
+		dialect Dialect
+		sql     string
+		err     string
+	}{
+		{Postgres, "SELECT CAST(age AS 5) FROM users;", "1:20: expected type name after AS, got 5"},
+		{Postgres, "SELECT age::5 FROM users;", "1:13: expected type name after ::, got 5"},
+		{SQLite, "SELECT age::text FROM users;", "1:11: sqlite has no :: casts, use CAST(age AS TEXT) instead"},
+	}
+	for _, tt := range errors {
+		err := NewAst(NewLexer(tt.sql)).SetDialect(tt.dialect).Parse()
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("expected error %q, got %v", tt.err, err)
+		}
+	}
+}
+
+func TestParseCase(t *testing.T) {
+	tests := []struct {
+		sql    string
+		want   string
+		params []string
+	}{
+		{
+			"SELECT CASE WHEN age < 18 THEN 'minor' WHEN age < $1 THEN 'adult' ELSE 'senior' END AS bracket FROM users;",
+			"SELECT CASE WHEN age < 18 THEN 'minor' WHEN age < $1 THEN 'adult' ELSE 'senior' END AS bracket FROM users;",
+			[]string{"1:age:"},
+		},
+		{
+			"select case status when $1 then email else $2 end from users where case when phone is null then 0 else 1 end = 1;",
+			"SELECT CASE status WHEN $1 THEN email ELSE $2 END FROM users WHERE CASE WHEN phone IS NULL THEN 0 ELSE 1 END = 1;",
+			[]string{"1:status:", "2:email:"},
+		},
+		{
+			"SELECT id FROM users WHERE created_at > CAST($1 AS TIMESTAMP) AND age > $2::int;",
+			"SELECT id FROM users WHERE created_at > CAST($1 AS TIMESTAMP) AND age > $2::INT;",
+			[]string{"1::time.Time", "2::int"},
+		},
+		{
+			"SELECT id FROM users WHERE score > $1::double precision AND created_at > CAST($2 AS timestamp with time zone);",
+			"SELECT id FROM users WHERE score > $1::DOUBLE PRECISION AND created_at > CAST($2 AS TIMESTAMP WITH TIME ZONE);",
+			[]string{"1::float64", "2::time.Time"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			parser := NewAst(NewLexer(tt.sql)).SetDialect(Postgres)
+			if err := parser.Parse(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := parser.String(); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+
+			var params []string
+			for _, bind := range types.SelectBinds(parser.Statements[0].(*types.SelectStatement)) {
+				source := bind.Name
+				if source == "" {
+					source = bind.Column
+				}
+				params = append(params, fmt.Sprintf("%d:%s:%s", bind.Position, source, bind.Type))
+			}
+			if strings.Join(params, ",") != strings.Join(tt.params, ",") {
+				t.Errorf("expected params %v, got %v", tt.params, params)
+			}
+		})
+	}
+
+	errors := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT CASE END FROM users;", "1:13: expected WHEN after CASE, got END"},
+		{"SELECT CASE WHEN age > 1 'old' END FROM users;", "1:26: expected THEN, got STRING"},
+		{"SELECT CASE WHEN age > 1 THEN 'old' FROM users;", "1:37: expected END, got FROM"},
+	}
+	for _, tt := range errors {
+		err := NewAst(NewLexer(tt.sql)).Parse()
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("expected error %q, got %v", tt.err, err)
+		}
+	}
+}
+
+func TestParseFunctionParams(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		where   string
+		name    string
+		typ     string
+	}{
+		{Postgres, "lower(email) = lower($1)", "", "string"},
+		{Postgres, "date(created_at) = $1", "date", "time.Time"},
+		{SQLite, "json_extract(data, $1) = 'x'", "", "string"},
+		{SQLite, "length(name) > @min_length", "min_length", "int64"},
+		{Generic, "date(created_at) = $1", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			parser := NewAst(NewLexer("SELECT id FROM users WHERE " + tt.where + ";")).SetDialect(tt.dialect)
+			if err := parser.Parse(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			binds := types.ExprBinds(parser.Statements[0].(*types.SelectStatement).Where)
+			if len(binds) != 1 || binds[0].Name != tt.name || binds[0].Type != tt.typ {
+				t.Errorf("expected a %q param named %q, got %+v", tt.typ, tt.name, binds)
+			}
+		})
+	}
+}
+
+func TestParseSubqueries(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		sql     string
+		want    string
+		params  []string
+	}{
+		{
+			Postgres,
+			"SELECT * FROM posts WHERE user_id IN (SELECT id FROM users WHERE role = $1) AND title = $2;",
+			"SELECT * FROM posts WHERE user_id IN (SELECT id FROM users WHERE role = $1) AND title = $2;",
+			[]string{"1:role", "2:title"},
+		},
+		{
+			Postgres,
+			"SELECT id FROM users u WHERE NOT EXISTS (SELECT 1 FROM posts p WHERE p.user_id = u.id);",
+			"SELECT id FROM users AS u WHERE NOT EXISTS (SELECT 1 FROM posts AS p WHERE p.user_id = u.id);",
+			nil,
+		},
+		{
+			Postgres,
+			"SELECT t.n FROM (SELECT user_id, COUNT(*) AS n FROM posts WHERE title <> @title GROUP BY user_id) t WHERE t.n > @min;",
+			"SELECT t.n FROM (SELECT user_id, COUNT(*) AS n FROM posts WHERE title <> $1 GROUP BY user_id) AS t WHERE t.n > $2;",
+			[]string{"1:title", "2:min"},
+		},
+		{
+			Postgres,
+			"SELECT id, (SELECT COUNT(*) FROM posts WHERE posts.user_id = users.id AND title = @title) AS post_count FROM users WHERE email = @email;",
+			"SELECT id, (SELECT COUNT(*) FROM posts WHERE posts.user_id = users.id AND title = $1) AS post_count FROM users WHERE email = $2;",
+			[]string{"1:title", "2:email"},
+		},
+		{
+			SQLite,
+			"SELECT id FROM users JOIN (SELECT user_id FROM posts WHERE title = ?) AS p ON p.user_id = users.id WHERE email = ?;",
+			"SELECT id FROM users INNER JOIN (SELECT user_id FROM posts WHERE title = $1) AS p ON p.user_id = users.id WHERE email = $2;",
+			[]string{"1:title", "2:email"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			parser := NewAst(NewLexer(tt.sql)).SetDialect(tt.dialect)
+			if err := parser.Parse(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := parser.String(); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+
+			var params []string
+			for _, bind := range types.SelectBinds(parser.Statements[0].(*types.SelectStatement)) {
+				source := bind.Name
+				if source == "" {
+					source = bind.Column
+				}
+				params = append(params, fmt.Sprintf("%d:%s", bind.Position, source))
+			}
+			if strings.Join(params, ",") != strings.Join(tt.params, ",") {
+				t.Errorf("expected params %v, got %v", tt.params, params)
+			}
+		})
+	}
+}
+
+func TestParseSubqueryErrors(t *testing.T) {
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT * FROM (SELECT id FROM users);", "1:15: subquery in FROM needs an alias"},
+		{"SELECT * FROM users WHERE EXISTS id;", "1:34: expected (SELECT after EXISTS, got id"},
+		{"SELECT * FROM users WHERE id IN (SELECT id FROM posts;", "1:54: expected ), got ;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			err := NewAst(NewLexer(tt.sql)).Parse()
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseWith(t *testing.T) {
+	tests := []struct {
+		sql    string
+		want   string
+		params []string
+	}{
+		{
+			"WITH active AS (SELECT id, email FROM users WHERE role = @role) SELECT a.email FROM active a WHERE a.id > @min_id;",
+			"WITH active AS (SELECT id, email FROM users WHERE role = $1) SELECT a.email FROM active AS a WHERE a.id > $2;",
+			[]string{"1:role", "2:min_id"},
+		},
+		{
+			"with recursive chain (id, manager) as (select id, manager_id from employees where id = $1), top as (select id from chain) select id from top;",
+			"WITH RECURSIVE chain (id, manager) AS (SELECT id, manager_id FROM employees WHERE id = $1), top AS (SELECT id FROM chain) SELECT id FROM top;",
+			[]string{"1:id"},
+		},
+		{
+			"SELECT id FROM users WHERE id IN (WITH authors AS (SELECT user_id FROM posts WHERE title = $1) SELECT user_id FROM authors) AND email = $2;",
+			"SELECT id FROM users WHERE id IN (WITH authors AS (SELECT user_id FROM posts WHERE title = $1) SELECT user_id FROM authors) AND email = $2;",
+			[]string{"1:title", "2:email"},
+		},
+		{
+			`WITH "Active" ("Id", email) AS (SELECT id, email FROM "Users") SELECT "Id" FROM "Active";`,
+			`WITH "Active" ("Id", email) AS (SELECT id, email FROM "Users") SELECT "Id" FROM "Active";`,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			parser := NewAst(NewLexer(tt.sql))
+			if err := parser.Parse(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := parser.String(); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+
+			var params []string
+			for _, bind := range types.SelectBinds(parser.Statements[0].(*types.SelectStatement)) {
+				source := bind.Name
+				if source == "" {
+					source = bind.Column
+				}
+				params = append(params, fmt.Sprintf("%d:%s", bind.Position, source))
+			}
+			if strings.Join(params, ",") != strings.Join(tt.params, ",") {
+				t.Errorf("expected params %v, got %v", tt.params, params)
+			}
+		})
+	}
+}
+
+func TestParseWithErrors(t *testing.T) {
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"WITH AS (SELECT id FROM users) SELECT id FROM users;", "1:6: expected CTE name after WITH, got AS"},
+		{"WITH a (id, ) AS (SELECT id FROM users) SELECT id FROM a;", "1:13: expected column name in a (...), got )"},
+		{"WITH a SELECT id FROM users;", "1:8: expected AS, got SELECT"},
+		{"WITH a AS SELECT id FROM users;", "1:11: expected (SELECT after a AS, got SELECT"},
+		{"WITH a AS (SELECT id FROM users) DELETE FROM a;", "1:34: expected SELECT, got DELETE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			err := NewAst(NewLexer(tt.sql)).Parse()
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseCompound(t *testing.T) {
+	tests := []struct {
+		sql    string
+		want   string
+		params []string
+	}{
+		{
+			"SELECT id, email FROM users WHERE role = $1 UNION SELECT id, email FROM admins WHERE email = $2;",
+			"SELECT id, email FROM users WHERE role = $1 UNION SELECT id, email FROM admins WHERE email = $2;",
+			[]string{"1:role", "2:email"},
+		},
+		{
+			"select id from users union all select user_id from posts intersect select id from admins except all select id from banned order by id desc limit @page_size offset @skip;",
+			"SELECT id FROM users UNION ALL SELECT user_id FROM posts INTERSECT SELECT id FROM admins EXCEPT ALL SELECT id FROM banned ORDER BY id DESC LIMIT $1 OFFSET $2;",
+			[]string{"1:page_size", "2:skip"},
+		},
+		{
+			"WITH RECURSIVE chain AS (SELECT id, manager_id FROM employees WHERE id = $1 UNION ALL SELECT e.id, e.manager_id FROM employees e JOIN chain c ON e.id = c.manager_id) SELECT id FROM chain;",
+			"WITH RECURSIVE chain AS (SELECT id, manager_id FROM employees WHERE id = $1 UNION ALL SELECT e.id, e.manager_id FROM employees AS e INNER JOIN chain AS c ON e.id = c.manager_id) SELECT id FROM chain;",
+			[]string{"1:id"},
+		},
+		{
+			"SELECT id FROM users WHERE id IN (SELECT user_id FROM posts UNION SELECT user_id FROM comments WHERE body = $1) AND email = $2;",
+			"SELECT id FROM users WHERE id IN (SELECT user_id FROM posts UNION SELECT user_id FROM comments WHERE body = $1) AND email = $2;",
+			[]string{"1:body", "2:email"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			parser := NewAst(NewLexer(tt.sql))
+			if err := parser.Parse(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := parser.String(); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+
+			var params []string
+			for _, bind := range types.SelectBinds(parser.Statements[0].(*types.SelectStatement)) {
+				source := bind.Name
+				if source == "" {
+					source = bind.Column
+				}
+				params = append(params, fmt.Sprintf("%d:%s", bind.Position, source))
+			}
+			if strings.Join(params, ",") != strings.Join(tt.params, ",") {
+				t.Errorf("expected params %v, got %v", tt.params, params)
+			}
+		})
+	}
+}
+
+func TestParseCompoundErrors(t *testing.T) {
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT id FROM users UNION;", "1:27: expected SELECT after UNION, got ;"},
+		{"SELECT id FROM users EXCEPT ALL (SELECT id FROM admins);", "1:33: expected SELECT after EXCEPT ALL, got ("},
+		{"SELECT id FROM users ORDER BY id UNION SELECT id FROM admins;", "1:34: unexpected UNION at end of statement"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			err := NewAst(NewLexer(tt.sql)).Parse()
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		sql    string
+		want   string
+		params []string
+	}{
+		{
+			"SELECT id, ROW_NUMBER() OVER (PARTITION BY unit ORDER BY created_at DESC) AS place FROM users;",
+			"SELECT id, ROW_NUMBER() OVER (PARTITION BY unit ORDER BY created_at DESC) AS place FROM users;",
+			nil,
+		},
+		{
+			"select sum(amount) over (partition by user_id, unit order by id rows between unbounded preceding and current row) from payments;",
+			"SELECT sum(amount) OVER (PARTITION BY user_id, unit ORDER BY id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) FROM payments;",
+			nil,
+		},
+		{
+			"SELECT avg(score) OVER (ORDER BY id ROWS BETWEEN $1 PRECEDING AND $2 FOLLOWING), lag(score, $3) OVER () FROM scores;",
+			"SELECT avg(score) OVER (ORDER BY id ROWS BETWEEN $1 PRECEDING AND $2 FOLLOWING), lag(score, $3) OVER () FROM scores;",
+			[]string{"1:preceding:int64", "2:following:int64", "3::int64"},
+		},
+		{
+			"SELECT count(*) OVER (ORDER BY created_at RANGE @span PRECEDING) FROM users;",
+			"SELECT count(*) OVER (ORDER BY created_at RANGE $1 PRECEDING) FROM users;",
+			[]string{"1:span:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			parser := NewAst(NewLexer(tt.sql)).SetDialect(Postgres)
+			if err := parser.Parse(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := parser.String(); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+
+			var params []string
+			for _, bind := range types.SelectBinds(parser.Statements[0].(*types.SelectStatement)) {
+				params = append(params, fmt.Sprintf("%d:%s:%s", bind.Position, bind.Name, bind.Type))
+			}
+			if strings.Join(params, ",") != strings.Join(tt.params, ",") {
+				t.Errorf("expected params %v, got %v", tt.params, params)
+			}
+		})
+	}
+
+	errors := []struct {
+		sql string
+		err string
+	}{
+		{"SELECT row_number() OVER w FROM users;", "1:26: expected ( after OVER, got w"},
+		{"SELECT sum(x) OVER (ROWS UNBOUNDED CURRENT ROW) FROM t;", "1:36: expected PRECEDING or FOLLOWING after UNBOUNDED, got CURRENT"},
+		{"SELECT sum(x) OVER (ROWS BETWEEN 1 AND CURRENT ROW) FROM t;", "1:36: expected PRECEDING or FOLLOWING after 1, got AND"},
+		{"SELECT sum(x) OVER (ROWS CURRENT) FROM t;", "1:33: expected ROW, got )"},
+		{"SELECT sum(x) OVER (ORDER BY x LIMIT 1) FROM t;", "1:32: expected ), got LIMIT"},
+	}
+	for _, tt := range errors {
+		err := NewAst(NewLexer(tt.sql)).Parse()
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("expected error %q, got %v", tt.err, err)
+		}
+	}
+}
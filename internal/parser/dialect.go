@@ -0,0 +1,175 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect is the SQL flavour being parsed, it decides which keywords are
+// reserved and which can still be used as names
+type Dialect string
+
+const (
+	Generic  Dialect = ""
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite3"
+)
+
+// DialectFor maps a configured driver name to its dialect
+func DialectFor(driver string) Dialect {
+	switch strings.ToLower(driver) {
+	case "postgres", "postgresql", "pgx":
+		return Postgres
+	case "sqlite", "sqlite3":
+		return SQLite
+	default:
+		return Generic
+	}
+}
+
+// Keywords that are reserved in both dialects
+var reservedKeywords = map[TokenType]struct{}{
+	ALL:       {},
+	AND:       {},
+	AS:        {},
+	CASE:      {},
+	CAST:      {},
+	CREATE:    {},
+	CROSS:     {},
+	DEFAULT:   {},
+	DISTINCT:  {},
+	ELSE:      {},
+	EXCEPT:    {},
+	FROM:      {},
+	FULL:      {},
+	GROUP:     {},
+	HAVING:    {},
+	IN:        {},
+	INNER:     {},
+	INTERSECT: {},
+	INTO:      {},
+	IS:        {},
+	JOIN:      {},
+	LEFT:      {},
+	LIMIT:     {},
+	NOT:       {},
+	NULL:      {},
+	ON:        {},
+	OR:        {},
+	ORDER:     {},
+	OUTER:     {},
+	PRIMARY:   {},
+	RETURNING: {},
+	RIGHT:     {},
+	SELECT:    {},
+	TABLE:     {},
+	THEN:      {},
+	TRUE:      {},
+	FALSE:     {},
+	UNION:     {},
+	UNIQUE:    {},
+	USING:     {},
+	WHEN:      {},
+	WHERE:     {},
+	WITH:      {},
+}
+
+var postgresReserved = map[TokenType]struct{}{
+	ASC:    {},
+	DESC:   {},
+	END:    {},
+	ILIKE:  {},
+	LIKE:   {},
+	OFFSET: {},
+}
+
+var sqliteReserved = map[TokenType]struct{}{
+	ADD:     {},
+	ALTER:   {},
+	BETWEEN: {},
+	DELETE:  {},
+	DROP:    {},
+	EXISTS:  {},
+	INDEX:   {},
+	INSERT:  {},
+	SET:     {},
+	UPDATE:  {},
+	VALUES:  {},
+}
+
+// Placeholder renders the positional param syntax for pos. SQLite numbers
+// its ? params so a param used twice still binds one value.
+func (d Dialect) Placeholder(pos int) string {
+	if d == SQLite {
+		return fmt.Sprintf("?%d", pos)
+	}
+	return fmt.Sprintf("$%d", pos)
+}
+
+// IsReserved reports whether tokType is a keyword that has to be quoted to be
+// used as a name. The generic dialect reserves everything either dialect does.
+func (d Dialect) IsReserved(tokType TokenType) bool {
+	if _, ok := reservedKeywords[tokType]; ok {
+		return true
+	}
+
+	_, pg := postgresReserved[tokType]
+	_, lite := sqliteReserved[tokType]
+	switch d {
+	case Postgres:
+		return pg
+	case SQLite:
+		return lite
+	default:
+		return pg || lite
+	}
+}
+
+// IsKeyword reports whether tok was lexed from a keyword
+func IsKeyword(tok Token) bool {
+	tokType, ok := keyWords[strings.ToUpper(tok.Literal)]
+	return ok && tokType == tok.Type
+}
+
+// DatabaseType finds the database type a type name is read as, a length or
+// precision such as VARCHAR(20) is ignored and names are case insensitive.
+// The generic dialect only knows the names every dialect has.
+func (d Dialect) DatabaseType(name string) (TokenType, bool) {
+	name, _, _ = strings.Cut(name, "(")
+	name = strings.ToUpper(strings.Join(strings.Fields(name), " "))
+	for _, names := range d.typeNames() {
+		if tokType, ok := names[name]; ok {
+			return tokType, true
+		}
+	}
+	return "", false
+}
+
+// GoType maps a type name of the dialect to its Go type
+func (d Dialect) GoType(name string) (string, error) {
+	tokType, _ := d.DatabaseType(name)
+	return SqlToGoType(Token{Type: tokType, Literal: name})
+}
+
+// startsTypeName reports whether words are a type name of the dialect or its
+// first words, so DOUBLE PRECISION is read as one name
+func (d Dialect) startsTypeName(words string) bool {
+	for _, names := range d.typeNames() {
+		for name := range names {
+			if name == words || strings.HasPrefix(name, words+" ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (d Dialect) typeNames() []map[string]TokenType {
+	switch d {
+	case Postgres:
+		return []map[string]TokenType{dbTypes, postgresTypes}
+	case SQLite:
+		return []map[string]TokenType{dbTypes, sqliteTypes}
+	}
+	return []map[string]TokenType{dbTypes}
+}
@@ -0,0 +1,752 @@
+package parser
+
+import (
+	"shogunc/internal/types"
+	"strings"
+)
+
+// Binding power of each operator, higher binds tighter
+const (
+	precLowest  int = iota
+	precOr          // OR
+	precAnd         // AND
+	precNot         // NOT x
+	precCompare     // = <> < <= > >= LIKE IN BETWEEN IS
+	precSum         // + - ||
+	precProduct     // * / %
+	precPrefix      // -x
+	precCast        // x::type
+)
+
+var precedences = map[TokenType]int{
+	OR:          precOr,
+	AND:         precAnd,
+	ASSIGN:      precCompare,
+	NOT_EQ:      precCompare,
+	LT:          precCompare,
+	LTE:         precCompare,
+	GT:          precCompare,
+	GTE:         precCompare,
+	LIKE:        precCompare,
+	ILIKE:       precCompare,
+	IS:          precCompare,
+	IN:          precCompare,
+	BETWEEN:     precCompare,
+	PLUS:        precSum,
+	MINUS:       precSum,
+	CONCAT:      precSum,
+	ASTERIK:     precProduct,
+	SLASH:       precProduct,
+	PERCENT:     precProduct,
+	DOUBLECOLON: precCast,
+}
+
+// parseWhere reads the condition after WHERE or HAVING, the current token is
+// the keyword
+func (a *Ast) parseWhere() (types.Expr, error) {
+	a.NextToken()
+	where, err := a.parseExpr(precLowest)
+	if err != nil {
+		return nil, err
+	}
+	return where, checkSliceParams(where)
+}
+
+// parseExpr reads an expression starting at the current token with
+// precedence climbing, it stops before the first operator that binds no
+// tighter than precedence. The current token is left just past the
+// expression.
+func (a *Ast) parseExpr(precedence int) (types.Expr, error) {
+	left, err := a.parsePrefixExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		opPrecedence := a.infixPrecedence()
+		if opPrecedence <= precedence {
+			return left, nil
+		}
+
+		left, err = a.parseInfixExpr(left, opPrecedence)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// infixPrecedence returns how tightly the operator at the current token
+// binds, NOT only continues an expression as NOT IN, NOT BETWEEN or NOT LIKE
+func (a *Ast) infixPrecedence() int {
+	if a.currentToken.Type == NOT {
+		switch a.peekToken.Type {
+		case IN, BETWEEN, LIKE, ILIKE:
+			return precCompare
+		}
+		return precLowest
+	}
+	return precedences[a.currentToken.Type]
+}
+
+// parseInfixExpr reads the operator at the current token and its right hand
+// side, left is the expression already read before it
+func (a *Ast) parseInfixExpr(left types.Expr, precedence int) (types.Expr, error) {
+	negated := a.currentToken.Type == NOT
+	if negated {
+		a.NextToken()
+	}
+
+	switch a.currentToken.Type {
+	case IN:
+		return a.parseInExpr(left, negated)
+	case BETWEEN:
+		return a.parseBetweenExpr(left, negated)
+	case IS:
+		return a.parseIsExpr(left)
+	case DOUBLECOLON:
+		return a.parsePostfixCast(left)
+	}
+
+	op := a.currentToken.Literal
+	if IsKeyword(a.currentToken) {
+		op = strings.ToUpper(op)
+	}
+	if negated {
+		op = "NOT " + op
+	}
+	a.NextToken()
+
+	right, err := a.parseExpr(precedence)
+	if err != nil {
+		return nil, err
+	}
+	if precedence == precCompare {
+		a.linkParam(left, right)
+	}
+	return &types.BinaryExpr{Left: left, Op: op, Right: right}, nil
+}
+
+// parseInExpr reads IN (a, b, ...) or IN (SELECT ...) after left
+func (a *Ast) parseInExpr(left types.Expr, negated bool) (types.Expr, error) {
+	a.NextToken() // consume IN
+	in := &types.InExpr{Expr: left, Not: negated}
+	if a.isSubquery() {
+		subquery, err := a.parseSubquery()
+		if err != nil {
+			return nil, err
+		}
+		in.List = []types.Expr{subquery}
+		return in, nil
+	}
+
+	if err := a.expect(LPAREN); err != nil {
+		return nil, err
+	}
+
+	for {
+		item, err := a.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		a.linkParam(left, item)
+		in.List = append(in.List, item)
+
+		if a.currentToken.Type != COMMA {
+			break
+		}
+		a.NextToken()
+	}
+
+	if err := a.expect(RPAREN); err != nil {
+		return nil, err
+	}
+
+	for _, item := range in.List {
+		if param, ok := item.(*types.Param); ok && param.Slice {
+			if len(in.List) > 1 {
+				return nil, types.Errorf(param.Span, "sqlc.slice(%s) has to be the only item of an IN list", param.Name)
+			}
+			param.NotIn = negated
+		}
+	}
+	return in, nil
+}
+
+// parseBetweenExpr reads BETWEEN low AND high after left. Unnamed params for
+// the bounds are named min_ and max_ after the column.
+func (a *Ast) parseBetweenExpr(left types.Expr, negated bool) (types.Expr, error) {
+	a.NextToken() // consume BETWEEN
+
+	// The bounds bind tighter than the AND between them
+	low, err := a.parseExpr(precCompare)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.expect(AND); err != nil {
+		return nil, err
+	}
+	high, err := a.parseExpr(precCompare)
+	if err != nil {
+		return nil, err
+	}
+
+	a.linkParam(left, low)
+	a.linkParam(left, high)
+	if column, ok := left.(*types.ColumnRef); ok {
+		if param, ok := low.(*types.Param); ok && param.Name == "" {
+			param.Name = "min_" + column.Column
+		}
+		if param, ok := high.(*types.Param); ok && param.Name == "" {
+			param.Name = "max_" + column.Column
+		}
+	}
+	return &types.BetweenExpr{Expr: left, Low: low, High: high, Not: negated}, nil
+}
+
+// parseIsExpr reads IS [NOT] NULL or IS [NOT] DISTINCT FROM right after left
+func (a *Ast) parseIsExpr(left types.Expr) (types.Expr, error) {
+	op := "IS"
+	a.NextToken()
+	negated := a.currentToken.Type == NOT
+	if negated {
+		op += " NOT"
+		a.NextToken()
+	}
+
+	if a.currentToken.Type == NULL {
+		a.NextToken()
+		return &types.IsNullExpr{Expr: left, Not: negated}, nil
+	}
+
+	if a.currentToken.Type != DISTINCT || a.peekToken.Type != FROM {
+		return nil, a.errorf("expected NULL or DISTINCT FROM after %s, got %s", op, a.currentToken.Literal)
+	}
+	a.NextToken() // consume DISTINCT
+	a.NextToken() // consume FROM
+
+	right, err := a.parseExpr(precCompare)
+	if err != nil {
+		return nil, err
+	}
+	a.linkParam(left, right)
+	return &types.BinaryExpr{Left: left, Op: op + " DISTINCT FROM", Right: right}, nil
+}
+
+// parsePrefixExpr reads a single operand along with any prefix operator
+func (a *Ast) parsePrefixExpr() (types.Expr, error) {
+	switch {
+	case a.currentToken.Type == NOT:
+		a.NextToken()
+		operand, err := a.parseExpr(precNot)
+		if err != nil {
+			return nil, err
+		}
+		return &types.UnaryExpr{Op: "NOT", Operand: operand}, nil
+	case a.isSubquery():
+		return a.parseSubquery()
+	case a.currentToken.Type == EXISTS:
+		a.NextToken()
+		if !a.isSubquery() {
+			return nil, a.errorf("expected (SELECT after EXISTS, got %s", a.currentToken.Literal)
+		}
+		subquery, err := a.parseSubquery()
+		if err != nil {
+			return nil, err
+		}
+		return &types.ExistsExpr{Select: subquery.Select}, nil
+	case a.currentToken.Type == LPAREN:
+		a.NextToken()
+		inner, err := a.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		if err := a.expect(RPAREN); err != nil {
+			return nil, err
+		}
+		return &types.ParenExpr{Inner: inner}, nil
+	case a.isParam():
+		param, err := a.parseParam()
+		if err != nil {
+			return nil, err
+		}
+		a.NextToken()
+		return param, nil
+	case a.currentToken.Type == CAST:
+		return a.parseCast()
+	case a.currentToken.Type == CASE:
+		return a.parseCase()
+	case a.isIdent() || a.currentToken.Type == ALL && a.peekToken.Type == LPAREN:
+		if a.peekToken.Type == LPAREN {
+			return a.parseFuncCall()
+		}
+		return a.parseColumnRef()
+	}
+
+	if value, kind, ok := a.parseLiteral(); ok {
+		a.NextToken()
+		return &types.Literal{Value: value, Kind: kind}, nil
+	}
+
+	// A sign that parseLiteral could not fold into a number
+	if a.currentToken.Type == MINUS || a.currentToken.Type == PLUS {
+		op := a.currentToken.Literal
+		a.NextToken()
+		operand, err := a.parseExpr(precPrefix)
+		if err != nil {
+			return nil, err
+		}
+		return &types.UnaryExpr{Op: op, Operand: operand}, nil
+	}
+
+	return nil, a.errorf("expected expression, got %s", a.currentToken.Literal)
+}
+
+// isSubquery reports whether a parenthesized query starts at the current token
+func (a *Ast) isSubquery() bool {
+	return a.currentToken.Type == LPAREN && (a.peekToken.Type == SELECT || a.peekToken.Type == WITH)
+}
+
+// parseSubquery reads (SELECT ...), the current token is the (
+func (a *Ast) parseSubquery() (*types.SubqueryExpr, error) {
+	a.NextToken() // consume (
+	stmt, err := a.parseSelectStatement()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.expect(RPAREN); err != nil {
+		return nil, err
+	}
+	return &types.SubqueryExpr{Select: stmt}, nil
+}
+
+// parseFuncCall reads name(args...) or name(*)
+func (a *Ast) parseFuncCall() (types.Expr, error) {
+	call := &types.FuncCall{Name: a.currentToken.Literal, Span: a.currentToken.Span}
+	a.NextToken() // consume name
+	a.NextToken() // consume (
+
+	if a.currentToken.Type == ASTERIK {
+		call.Star = true
+		a.NextToken()
+	} else if a.currentToken.Type == DISTINCT || a.currentToken.Type == ALL {
+		// An aggregate over the distinct values, or all of them as by default
+		call.Quantifier = string(a.currentToken.Type)
+		a.NextToken()
+	}
+
+	for !call.Star && a.currentToken.Type != RPAREN {
+		arg, err := a.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+
+		if a.currentToken.Type != COMMA {
+			break
+		}
+		a.NextToken()
+	}
+
+	if err := a.expect(RPAREN); err != nil {
+		return nil, err
+	}
+	if a.currentToken.Type == OVER {
+		window, err := a.parseWindow()
+		if err != nil {
+			return nil, err
+		}
+		call.Over = window
+	}
+
+	// = ANY($1) compares with every element of an array param
+	if param := arrayParam(call); param != nil {
+		if a.dialect == SQLite {
+			return nil, types.Errorf(param.Span, "sqlite has no arrays, use IN (sqlc.slice(%s)) instead of %s", param.Name, strings.ToUpper(call.Name))
+		}
+		param.Slice = true
+	}
+
+	// A param passed to a function of the catalog takes the argument's type
+	if fn, ok := a.dialect.Function(call.Name); ok {
+		for i, arg := range call.Args {
+			if param, ok := arg.(*types.Param); ok && param.Column == "" && param.Type == "" {
+				param.Type = fn.ArgType(i)
+			}
+		}
+	}
+	return call, nil
+}
+
+// parseWindow reads OVER ([PARTITION BY ...] [ORDER BY ...] [frame]) after a
+// call
+func (a *Ast) parseWindow() (*types.Window, error) {
+	a.NextToken() // consume OVER
+	if a.currentToken.Type != LPAREN {
+		return nil, a.errorf("expected ( after OVER, got %s", a.currentToken.Literal)
+	}
+	a.NextToken()
+
+	window := &types.Window{}
+	if a.currentToken.Type == PARTITION {
+		a.NextToken()
+		partitionBy, err := a.parseByList()
+		if err != nil {
+			return nil, err
+		}
+		window.PartitionBy = partitionBy
+	}
+	if a.currentToken.Type == ORDER {
+		orderBy, err := a.parseOrderBy()
+		if err != nil {
+			return nil, err
+		}
+		window.OrderBy = orderBy
+	}
+	switch a.currentToken.Type {
+	case ROWS, RANGE, GROUPS:
+		frame, err := a.parseFrame()
+		if err != nil {
+			return nil, err
+		}
+		window.Frame = frame
+	}
+
+	if err := a.expect(RPAREN); err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+// parseFrame reads ROWS, RANGE or GROUPS followed by a bound or BETWEEN bound
+// AND bound
+func (a *Ast) parseFrame() (*types.Frame, error) {
+	frame := &types.Frame{Units: string(a.currentToken.Type)}
+	a.NextToken()
+
+	between := a.currentToken.Type == BETWEEN
+	if between {
+		a.NextToken()
+	}
+	start, err := a.parseFrameBound(frame.Units)
+	if err != nil {
+		return nil, err
+	}
+	frame.Start = start
+	if !between {
+		return frame, nil
+	}
+
+	if err := a.expect(AND); err != nil {
+		return nil, err
+	}
+	end, err := a.parseFrameBound(frame.Units)
+	if err != nil {
+		return nil, err
+	}
+	frame.End = &end
+	return frame, nil
+}
+
+// parseFrameBound reads UNBOUNDED PRECEDING, UNBOUNDED FOLLOWING, CURRENT ROW
+// or offset PRECEDING and offset FOLLOWING. A param counting ROWS or GROUPS
+// is an int64 named after the direction unless it was given a name.
+func (a *Ast) parseFrameBound(units string) (types.FrameBound, error) {
+	switch a.currentToken.Type {
+	case UNBOUNDED:
+		a.NextToken()
+		if a.currentToken.Type != PRECEDING && a.currentToken.Type != FOLLOWING {
+			return types.FrameBound{}, a.errorf("expected PRECEDING or FOLLOWING after UNBOUNDED, got %s", a.currentToken.Literal)
+		}
+		bound := types.FrameBound{Bound: "UNBOUNDED " + string(a.currentToken.Type)}
+		a.NextToken()
+		return bound, nil
+	case CURRENT:
+		a.NextToken()
+		if err := a.expect(ROW); err != nil {
+			return types.FrameBound{}, err
+		}
+		return types.FrameBound{Bound: "CURRENT ROW"}, nil
+	}
+
+	// The offset binds tighter than the AND of BETWEEN
+	offset, err := a.parseExpr(precCompare)
+	if err != nil {
+		return types.FrameBound{}, err
+	}
+	if a.currentToken.Type != PRECEDING && a.currentToken.Type != FOLLOWING {
+		return types.FrameBound{}, a.errorf("expected PRECEDING or FOLLOWING after %s, got %s", offset.SQL(Generic.Placeholder), a.currentToken.Literal)
+	}
+	bound := types.FrameBound{Offset: offset, Bound: string(a.currentToken.Type)}
+	a.NextToken()
+
+	if param, ok := offset.(*types.Param); ok && units != string(RANGE) {
+		if param.Name == "" {
+			param.Name = strings.ToLower(bound.Bound)
+		}
+		param.Type = "int64"
+	}
+	return bound, nil
+}
+
+// parseCast reads CAST(expr AS type)
+func (a *Ast) parseCast() (types.Expr, error) {
+	a.NextToken() // consume CAST
+	if err := a.expect(LPAREN); err != nil {
+		return nil, err
+	}
+	inner, err := a.parseExpr(precLowest)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.expect(AS); err != nil {
+		return nil, err
+	}
+	typeSpan := a.currentToken.Span
+	typ, quoted, err := a.parseTypeName("AS")
+	if err != nil {
+		return nil, err
+	}
+	if err := a.expect(RPAREN); err != nil {
+		return nil, err
+	}
+	return a.castParam(&types.CastExpr{Expr: inner, Type: typ, TypeQuoted: quoted, TypeSpan: typeSpan}), nil
+}
+
+// parsePostfixCast reads ::type after left, a cast only postgres has
+func (a *Ast) parsePostfixCast(left types.Expr) (types.Expr, error) {
+	span := a.currentToken.Span
+	a.NextToken() // consume ::
+	typeSpan := a.currentToken.Span
+	typ, quoted, err := a.parseTypeName("::")
+	if err != nil {
+		return nil, err
+	}
+	if a.dialect == SQLite {
+		return nil, types.Errorf(span, "sqlite has no :: casts, use CAST(%s AS %s) instead", left.SQL(Generic.Placeholder), types.QuoteIdent(typ, quoted))
+	}
+	return a.castParam(&types.CastExpr{Expr: left, Type: typ, TypeQuoted: quoted, TypeSpan: typeSpan, Postfix: true}), nil
+}
+
+// parseTypeName reads a type name in upper case, keeping a length or
+// precision written after it. A name of several words such as DOUBLE
+// PRECISION is read whole, and a quoted name such as an enum's keeps its case.
+func (a *Ast) parseTypeName(after string) (string, bool, error) {
+	if a.currentToken.Type == QUOTED_IDENT {
+		typ := a.currentToken.Literal
+		a.NextToken()
+		return typ, true, nil
+	}
+	if a.currentToken.Type != IDENT && !IsKeyword(a.currentToken) {
+		return "", false, a.errorf("expected type name after %s, got %s", after, a.currentToken.Literal)
+	}
+	typ := strings.ToUpper(a.currentToken.Literal)
+	a.NextToken()
+	for a.currentToken.Type == IDENT || IsKeyword(a.currentToken) {
+		longer := typ + " " + strings.ToUpper(a.currentToken.Literal)
+		if !a.dialect.startsTypeName(longer) {
+			break
+		}
+		typ = longer
+		a.NextToken()
+	}
+	if a.currentToken.Type == LPAREN {
+		var size []string
+		for a.NextToken(); a.currentToken.Type == INT || a.currentToken.Type == COMMA; a.NextToken() {
+			size = append(size, a.currentToken.Literal)
+		}
+		if err := a.expect(RPAREN); err != nil {
+			return "", false, err
+		}
+		typ += "(" + strings.Join(size, "") + ")"
+	}
+	return typ, false, nil
+}
+
+// castParam gives a param cast to a type the Go type of that type, so
+// CAST($1 AS TEXT) binds a string
+func (a *Ast) castParam(cast *types.CastExpr) *types.CastExpr {
+	param, ok := cast.Expr.(*types.Param)
+	if !ok || param.Column != "" || param.Type != "" || cast.TypeQuoted {
+		return cast
+	}
+	if typ, err := a.dialect.GoType(cast.Type); err == nil {
+		param.Type = typ
+	}
+	return cast
+}
+
+// parseCase reads CASE [operand] WHEN ... THEN ... [ELSE ...] END. A param
+// compared with the operand of a simple CASE is linked to the operand, and a
+// param given as a result to the first column given as another result.
+func (a *Ast) parseCase() (types.Expr, error) {
+	expr := &types.CaseExpr{Span: a.currentToken.Span}
+	a.NextToken() // consume CASE
+	if a.currentToken.Type != WHEN && a.currentToken.Type != END {
+		operand, err := a.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		expr.Operand = operand
+	}
+	if a.currentToken.Type != WHEN {
+		return nil, a.errorf("expected WHEN after CASE, got %s", a.currentToken.Literal)
+	}
+
+	var results []types.Expr
+	for a.currentToken.Type == WHEN {
+		a.NextToken()
+		cond, err := a.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		if err := a.expect(THEN); err != nil {
+			return nil, err
+		}
+		result, err := a.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		if expr.Operand != nil {
+			a.linkParam(expr.Operand, cond)
+		}
+		expr.Whens = append(expr.Whens, types.When{Cond: cond, Result: result})
+		results = append(results, result)
+	}
+	if a.currentToken.Type == ELSE {
+		a.NextToken()
+		result, err := a.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		expr.Else = result
+		results = append(results, result)
+	}
+	if err := a.expect(END); err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		if column, ok := result.(*types.ColumnRef); ok {
+			for _, other := range results {
+				a.linkParam(column, other)
+			}
+			break
+		}
+	}
+	return expr, nil
+}
+
+// parseColumnRef reads column, table.column or table.*
+func (a *Ast) parseColumnRef() (types.Expr, error) {
+	ref := &types.ColumnRef{Column: a.identName(), Quoted: a.currentToken.Type == QUOTED_IDENT}
+	a.NextToken()
+
+	if a.currentToken.Type != DOT {
+		return ref, nil
+	}
+	a.NextToken()
+	if a.currentToken.Type == ASTERIK {
+		ref.Table, ref.TableQuoted = ref.Column, ref.Quoted
+		ref.Column, ref.Quoted = "*", false
+		a.NextToken()
+		return ref, nil
+	}
+	if !a.isIdent() {
+		return nil, a.errorf("expected column name after %s., got %s", ref.Column, a.currentToken.Literal)
+	}
+	ref.Table, ref.TableQuoted = ref.Column, ref.Quoted
+	ref.Column, ref.Quoted = a.identName(), a.currentToken.Type == QUOTED_IDENT
+	a.NextToken()
+	return ref, nil
+}
+
+// arrayParam returns the param of ANY($1) or ALL($1)
+func arrayParam(e types.Expr) *types.Param {
+	call, ok := e.(*types.FuncCall)
+	if !ok || len(call.Args) != 1 {
+		return nil
+	}
+	if !strings.EqualFold(call.Name, "any") && !strings.EqualFold(call.Name, "all") {
+		return nil
+	}
+	param, _ := call.Args[0].(*types.Param)
+	return param
+}
+
+// checkSliceParams reports a slice param used anywhere but as the list of an
+// IN or the array of ANY or ALL
+func checkSliceParams(e types.Expr) error {
+	allowed := make(map[*types.Param]bool)
+	types.WalkExpr(e, func(node types.Expr) {
+		if in, ok := node.(*types.InExpr); ok && len(in.List) == 1 {
+			if param, ok := in.List[0].(*types.Param); ok {
+				allowed[param] = true
+			}
+		}
+		if param := arrayParam(node); param != nil {
+			allowed[param] = true
+		}
+	})
+
+	var err error
+	types.WalkExpr(e, func(node types.Expr) {
+		if param, ok := node.(*types.Param); ok && param.Slice && !allowed[param] && err == nil {
+			err = types.Errorf(param.Span, "sqlc.slice(%s) can only be used as the list of an IN", param.Name)
+		}
+	})
+	return err
+}
+
+// linkParam ties a param compared with a column to that column, so its type
+// can be taken from the schema
+func (a *Ast) linkParam(left, right types.Expr) {
+	if param := arrayParam(right); param != nil {
+		right = param
+	}
+	if param := arrayParam(left); param != nil {
+		left = param
+	}
+
+	if column, ok := left.(*types.ColumnRef); ok {
+		if param, ok := right.(*types.Param); ok && param.Column == "" {
+			param.Column, param.Table = column.Column, column.Table
+		}
+	}
+	if column, ok := right.(*types.ColumnRef); ok {
+		if param, ok := left.(*types.Param); ok && param.Column == "" {
+			param.Column, param.Table = column.Column, column.Table
+		}
+	}
+
+	if call, ok := left.(*types.FuncCall); ok {
+		if param, ok := right.(*types.Param); ok {
+			a.linkCallParam(call, param)
+		}
+	}
+	if call, ok := right.(*types.FuncCall); ok {
+		if param, ok := left.(*types.Param); ok {
+			a.linkCallParam(call, param)
+		}
+	}
+}
+
+// linkCallParam types a param compared with a function from the dialect's
+// catalog, COUNT(*) > $1 makes an int64 named count and MIN(price) > $1 ties
+// the param to price
+func (a *Ast) linkCallParam(call *types.FuncCall, param *types.Param) {
+	fn, ok := a.dialect.Function(call.Name)
+	if !ok || param.Column != "" || param.Type != "" {
+		return
+	}
+
+	if fn.Type == "" {
+		if len(call.Args) == 1 {
+			a.linkParam(call.Args[0], param)
+		}
+		return
+	}
+	param.Type = fn.Type
+	if param.Name == "" {
+		param.Name = fn.Name
+	}
+}
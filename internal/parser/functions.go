@@ -0,0 +1,101 @@
+package parser
+
+import "strings"
+
+// Nullability is when the result of a function is NULL
+type Nullability int
+
+const (
+	NullIfAnyArg  Nullability = iota // NULL when any argument is, as LOWER
+	NeverNull                        // Never NULL, as COUNT or NOW
+	Nullable                         // Can be NULL whatever its arguments are, as SUM over no rows
+	NullIfAllArgs                    // NULL only when every argument is, as COALESCE
+)
+
+// Function is a signature of the function catalog
+type Function struct {
+	Name      string
+	Args      []string // Go type of each argument, empty for any type. A variadic function repeats the last one.
+	Optional  int      // How many of the last Args can be left out
+	Variadic  bool
+	Type      string // Go type of the result, empty when it is the type of the first argument that is not NULL
+	Nulls     Nullability
+	Aggregate bool // Folds rows into one value, it can also be called with OVER
+	Window    bool // Can only be called with OVER
+}
+
+// Functions every dialect knows, keyed by lower case name
+var sharedFunctions = map[string]Function{
+	"count":    {Name: "count", Args: []string{""}, Type: "int64", Nulls: NeverNull, Aggregate: true},
+	"sum":      {Name: "sum", Args: []string{""}, Type: "float64", Nulls: Nullable, Aggregate: true},
+	"avg":      {Name: "avg", Args: []string{""}, Type: "float64", Nulls: Nullable, Aggregate: true},
+	"min":      {Name: "min", Args: []string{""}, Nulls: Nullable, Aggregate: true},
+	"max":      {Name: "max", Args: []string{""}, Nulls: Nullable, Aggregate: true},
+	"coalesce": {Name: "coalesce", Args: []string{""}, Variadic: true, Nulls: NullIfAllArgs},
+	"lower":    {Name: "lower", Args: []string{"string"}, Type: "string"},
+	"upper":    {Name: "upper", Args: []string{"string"}, Type: "string"},
+	"length":   {Name: "length", Args: []string{"string"}, Type: "int64"},
+
+	// Window functions, lag and lead are NULL when the row they look at is
+	// outside the partition and the value functions when the frame is empty
+	"row_number":   {Name: "row_number", Type: "int64", Nulls: NeverNull, Window: true},
+	"rank":         {Name: "rank", Type: "int64", Nulls: NeverNull, Window: true},
+	"dense_rank":   {Name: "dense_rank", Type: "int64", Nulls: NeverNull, Window: true},
+	"percent_rank": {Name: "percent_rank", Type: "float64", Nulls: NeverNull, Window: true},
+	"cume_dist":    {Name: "cume_dist", Type: "float64", Nulls: NeverNull, Window: true},
+	"ntile":        {Name: "ntile", Args: []string{"int64"}, Type: "int64", Nulls: NeverNull, Window: true},
+	"lag":          {Name: "lag", Args: []string{"", "int64", ""}, Optional: 2, Nulls: Nullable, Window: true},
+	"lead":         {Name: "lead", Args: []string{"", "int64", ""}, Optional: 2, Nulls: Nullable, Window: true},
+	"first_value":  {Name: "first_value", Args: []string{""}, Nulls: Nullable, Window: true},
+	"last_value":   {Name: "last_value", Args: []string{""}, Nulls: Nullable, Window: true},
+	"nth_value":    {Name: "nth_value", Args: []string{"", "int64"}, Nulls: Nullable, Window: true},
+}
+
+var postgresFunctions = map[string]Function{
+	"now":  {Name: "now", Type: "time.Time", Nulls: NeverNull},
+	"date": {Name: "date", Args: []string{"time.Time"}, Type: "time.Time"},
+}
+
+// sqlite returns dates as text and NULL for a value it cannot read as one
+var sqliteFunctions = map[string]Function{
+	"date":         {Name: "date", Args: []string{""}, Variadic: true, Type: "string", Nulls: Nullable},
+	"json_extract": {Name: "json_extract", Args: []string{"string", "string"}, Variadic: true, Type: "any", Nulls: Nullable},
+}
+
+// Function finds name in the dialect's function catalog, names are case
+// insensitive. The generic dialect only knows the functions every dialect has.
+func (d Dialect) Function(name string) (Function, bool) {
+	name = strings.ToLower(name)
+	if fn, ok := sharedFunctions[name]; ok {
+		return fn, true
+	}
+
+	var fn Function
+	var ok bool
+	switch d {
+	case Postgres:
+		fn, ok = postgresFunctions[name]
+	case SQLite:
+		fn, ok = sqliteFunctions[name]
+	}
+	return fn, ok
+}
+
+// ArgType returns the Go type of argument i, empty when any type goes
+func (f Function) ArgType(i int) string {
+	if i < len(f.Args) {
+		return f.Args[i]
+	}
+	if f.Variadic && len(f.Args) > 0 {
+		return f.Args[len(f.Args)-1]
+	}
+	return ""
+}
+
+// Accepts reports whether the function can be called with n arguments
+func (f Function) Accepts(n int) bool {
+	if f.Variadic {
+		return n >= len(f.Args)
+	}
+	return n <= len(f.Args) && n >= len(f.Args)-f.Optional
+}
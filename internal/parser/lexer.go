@@ -1,38 +1,78 @@
 package parser
 
 import (
+	"shogunc/internal/types"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
+// Lexer reads its input one rune at a time. position and readPosition are
+// byte offsets into input, column counts runes.
 type Lexer struct {
 	input        string
-	position     int // Current position
-	readPosition int // Next Position
-	ch           byte
+	position     int            // Current position
+	readPosition int            // Next Position
+	ch           rune           // Current char, 0 at the end of input
+	base         types.Position // Where input starts in its file
+	line         int            // Line of the current char
+	column       int            // Column of the current char
 }
 
 func NewLexer(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewLexerAt(input, types.Position{Line: 1, Column: 1})
+}
+
+// NewLexerAt creates a lexer for input that starts at base within its file,
+// so token positions point into the original file
+func NewLexerAt(input string, base types.Position) *Lexer {
+	l := &Lexer{input: input, base: base, line: base.Line, column: base.Column - 1}
 	l.ReadChar()
 	return l
 }
 
 func (l *Lexer) ReadChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+
+	width := 0
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		// Invalid UTF-8 decodes to utf8.RuneError and is lexed as ILLEGAL
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += width
+	l.column++
+}
+
+// pos returns the position of the current char
+func (l *Lexer) pos() types.Position {
+	return types.Position{
+		File:   l.base.File,
+		Line:   l.line,
+		Column: l.column,
+		Offset: l.base.Offset + l.position,
+	}
 }
 
 func (l *Lexer) NextToken() Token {
-	var tok Token
 	l.skipWhiteSpaces()
 
+	start := l.pos()
+	tok := l.scanToken()
+	tok.Pos = start
+	tok.End = l.pos()
+	return tok
+}
+
+func (l *Lexer) scanToken() Token {
+	var tok Token
+
 	switch l.ch {
 	case '=':
 		tok = CreateToken(ASSIGN, '=')
@@ -47,25 +87,99 @@ func (l *Lexer) NextToken() Token {
 	case '*':
 		tok = CreateToken(ASTERIK, '*')
 	case '$':
+		if literal, ok := l.readDollarString(); ok {
+			tok.Type = STRING
+			tok.Literal = literal
+			return tok
+		}
 		tok = CreateToken(BINDPARAM, '$')
+	case '?':
+		return l.readPlaceholder()
+	case '+':
+		tok = CreateToken(PLUS, '+')
+	case '<':
+		switch l.peekChar() {
+		case '=':
+			tok = l.twoCharToken(LTE)
+		case '>':
+			tok = l.twoCharToken(NOT_EQ)
+		default:
+			tok = CreateToken(LT, l.ch)
+		}
+	case '>':
+		if l.peekChar() == '=' {
+			tok = l.twoCharToken(GTE)
+		} else {
+			tok = CreateToken(GT, l.ch)
+		}
+	case '!':
+		if l.peekChar() == '=' {
+			tok = l.twoCharToken(NOT_EQ)
+		} else {
+			tok = CreateToken(ILLEGAL, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.twoCharToken(CONCAT)
+		} else {
+			tok = CreateToken(ILLEGAL, l.ch)
+		}
+	case ':':
+		if l.peekChar() == ':' {
+			tok = l.twoCharToken(DOUBLECOLON)
+		} else if isLetter(l.peekChar()) {
+			return l.readNamedParam()
+		} else {
+			tok = CreateToken(ILLEGAL, l.ch)
+		}
+	case '@':
+		if isLetter(l.peekChar()) {
+			return l.readNamedParam()
+		}
+		tok = CreateToken(ILLEGAL, l.ch)
+	case '-':
+		if l.peekChar() == '-' {
+			tok.Type = COMMENT
+			tok.Literal = l.readLineComment()
+			return tok
+		}
+		tok = CreateToken(MINUS, l.ch)
+	case '/':
+		if l.peekChar() == '*' {
+			literal, ok := l.readBlockComment()
+			tok.Type = COMMENT
+			if !ok {
+				tok.Type = ILLEGAL // Unterminated comment
+			}
+			tok.Literal = literal
+			return tok
+		}
+		tok = CreateToken(SLASH, l.ch)
+	case '%':
+		tok = CreateToken(PERCENT, l.ch)
 	case 0:
 		tok.Literal = ""
 		tok.Type = EOF
 	default:
 		if isString(l.ch) {
-			tok.Type = STRING
-			tok.Literal = l.readString()
-			// fmt.Printf("Token STRING: %+v\n", tok)
-			return tok
+			return l.quotedToken(STRING, l.readString)
+		} else if isQuotedIdent(l.ch) {
+			return l.quotedToken(QUOTED_IDENT, l.readQuotedIdent)
+		} else if (l.ch == 'E' || l.ch == 'e') && l.peekChar() == '\'' {
+			return l.quotedToken(STRING, func() (string, bool) {
+				l.ReadChar() // skip E
+				return l.readEscapeString()
+			})
 		} else if isLetter(l.ch) {
 			tok.Literal = l.readIdentifer()
 			tok.Type = LookupIdent(strings.ToUpper(tok.Literal))
 			// fmt.Printf("Token CHAR: %+v\n", tok)
 			return tok
-		} else if isDigit(l.ch) {
-			tok.Type = INT
-			tok.Literal = l.readNumber()
+		} else if isDigit(l.ch) || (l.ch == '.' && isDigit(l.peekChar())) {
+			tok.Literal, tok.Type = l.readNumber()
 			return tok
+		} else if l.ch == '.' {
+			tok = CreateToken(DOT, l.ch)
 		} else {
 			tok = CreateToken(ILLEGAL, l.ch)
 		}
@@ -74,18 +188,125 @@ func (l *Lexer) NextToken() Token {
 	return tok
 }
 
-func (l *Lexer) readNumber() string {
+func (l *Lexer) peekChar() rune {
+	return l.peekCharAt(l.readPosition)
+}
+
+// peekCharAt returns the rune starting at byte offset i without reading it
+func (l *Lexer) peekCharAt(i int) rune {
+	if i >= len(l.input) {
+		return 0
+	}
+	ch, _ := utf8.DecodeRuneInString(l.input[i:])
+	return ch
+}
+
+// twoCharToken consumes the current char and the next one as a single token
+func (l *Lexer) twoCharToken(tokType TokenType) Token {
+	ch := l.ch
+	l.ReadChar()
+	return Token{Type: tokType, Literal: string(ch) + string(l.ch)}
+}
+
+// readNamedParam reads a @name or :name param, the literal drops the sigil
+func (l *Lexer) readNamedParam() Token {
+	l.ReadChar() // skip @ or :
+	return Token{Type: NAMED_PARAM, Literal: l.readIdentifer()}
+}
+
+// readPlaceholder reads a ? or ?NNN param, the literal is the number after
+// the ? and is empty for a bare ?
+func (l *Lexer) readPlaceholder() Token {
+	l.ReadChar() // skip ?
 	position := l.position
 	for isDigit(l.ch) {
 		l.ReadChar()
 	}
+	return Token{Type: PLACEHOLDER, Literal: l.input[position:l.position]}
+}
 
-	return l.input[position:l.position]
+// readLineComment reads a -- comment up to the end of the line
+func (l *Lexer) readLineComment() string {
+	position := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.ReadChar()
+	}
+
+	return strings.TrimRight(l.input[position:l.position], "\r")
+}
+
+// readBlockComment reads a /* */ comment, ok is false when it never closes
+func (l *Lexer) readBlockComment() (string, bool) {
+	position := l.position
+	l.ReadChar() // skip /
+	l.ReadChar() // skip *
+	for l.ch != 0 {
+		if l.ch == '*' && l.peekChar() == '/' {
+			l.ReadChar()
+			l.ReadChar()
+			return l.input[position:l.position], true
+		}
+		l.ReadChar()
+	}
+
+	return l.input[position:l.position], false
+}
+
+// CommentText strips the comment markers from a COMMENT literal
+func CommentText(literal string) string {
+	if text, ok := strings.CutPrefix(literal, "--"); ok {
+		return strings.TrimSpace(text)
+	}
+
+	text := strings.TrimPrefix(literal, "/*")
+	text = strings.TrimSuffix(text, "*/")
+	return strings.TrimSpace(text)
+}
+
+// readNumber reads an integer, hex, decimal or exponent literal
+func (l *Lexer) readNumber() (string, TokenType) {
+	position := l.position
+	tokType := INT
+
+	if l.ch == '0' && (l.peekChar() == 'x' || l.peekChar() == 'X') {
+		l.ReadChar()
+		l.ReadChar()
+		for isHexDigit(l.ch) {
+			l.ReadChar()
+		}
+		return l.input[position:l.position], INT
+	}
+
+	for isDigit(l.ch) {
+		l.ReadChar()
+	}
+	if l.ch == '.' {
+		tokType = FLOAT
+		l.ReadChar()
+		for isDigit(l.ch) {
+			l.ReadChar()
+		}
+	}
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || ((next == '+' || next == '-') && isDigit(l.peekCharAt(l.readPosition+1))) {
+			tokType = FLOAT
+			l.ReadChar() // skip e
+			if l.ch == '+' || l.ch == '-' {
+				l.ReadChar()
+			}
+			for isDigit(l.ch) {
+				l.ReadChar()
+			}
+		}
+	}
+
+	return l.input[position:l.position], tokType
 }
 
 func (l *Lexer) readIdentifer() string {
 	position := l.position
-	for isLetter(l.ch) || l.ch == '_' {
+	for isLetter(l.ch) || unicode.IsDigit(l.ch) {
 		l.ReadChar()
 	}
 
@@ -98,26 +319,142 @@ func (l *Lexer) skipWhiteSpaces() {
 	}
 }
 
-func isLetter(ch byte) bool {
-	return unicode.IsLetter(rune(ch))
+// isLetter reports whether ch can start an identifier
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
-func isDigit(ch byte) bool {
-	return unicode.IsDigit(rune(ch))
+// isDigit only accepts ASCII digits, other scripts' digits are not numbers in SQL
+func isDigit(ch rune) bool {
+	return '0' <= ch && ch <= '9'
 }
 
-func isString(ch byte) bool {
-	return ch == '\'' || ch == '"'
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || ('a' <= ch && ch <= 'f') || ('A' <= ch && ch <= 'F')
 }
 
-func (l *Lexer) readString() string {
-	quote := l.ch
+func isString(ch rune) bool {
+	return ch == '\''
+}
+
+func isQuotedIdent(ch rune) bool {
+	return ch == '"' || ch == '`'
+}
+
+// quotedToken reads a quoted literal with read. A literal that never closes
+// is ILLEGAL and holds the rest of the input, like an unterminated comment.
+func (l *Lexer) quotedToken(tokType TokenType, read func() (string, bool)) Token {
+	position := l.position
+	literal, ok := read()
+	if !ok {
+		return Token{Type: ILLEGAL, Literal: l.input[position:l.position]}
+	}
+	return Token{Type: tokType, Literal: literal}
+}
+
+// readString reads a '...' string, a doubled quote stands for itself. ok is
+// false when it never closes.
+func (l *Lexer) readString() (string, bool) {
+	l.ReadChar() // skip opening '
+
+	var sb strings.Builder
+	for l.ch != 0 {
+		if l.ch == '\'' {
+			if l.peekChar() != '\'' {
+				l.ReadChar() // skip closing '
+				return sb.String(), true
+			}
+			l.ReadChar() // skip the escaping '
+		}
+		sb.WriteRune(l.ch)
+		l.ReadChar()
+	}
+	return sb.String(), false
+}
+
+// readEscapeString reads a Postgres E'...' string with backslash escapes
+func (l *Lexer) readEscapeString() (string, bool) {
 	l.ReadChar() // skip opening '
-	start := l.position
-	for l.ch != quote && l.ch != 0 {
+
+	var sb strings.Builder
+	for l.ch != 0 {
+		switch {
+		case l.ch == '\'' && l.peekChar() == '\'':
+			l.ReadChar()
+		case l.ch == '\'':
+			l.ReadChar() // skip closing '
+			return sb.String(), true
+		case l.ch == '\\' && l.peekChar() != 0:
+			l.ReadChar()
+			switch l.ch {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			default:
+				sb.WriteRune(l.ch)
+			}
+			l.ReadChar()
+			continue
+		}
+		sb.WriteRune(l.ch)
+		l.ReadChar()
+	}
+	return sb.String(), false
+}
+
+// readDollarString reads a Postgres $$...$$ or $tag$...$tag$ string. ok is
+// false when the $ does not open one, so it is left for a bind param.
+func (l *Lexer) readDollarString() (string, bool) {
+	end := l.readPosition
+	for end < len(l.input) {
+		ch, width := utf8.DecodeRuneInString(l.input[end:])
+		if !isLetter(ch) && (end == l.readPosition || !unicode.IsDigit(ch)) {
+			break
+		}
+		end += width
+	}
+	if l.peekCharAt(end) != '$' {
+		return "", false
+	}
+
+	delim := l.input[l.position : end+1]
+	closing := strings.Index(l.input[end+1:], delim)
+	if closing < 0 {
+		return "", false
+	}
+	body := l.input[end+1 : end+1+closing]
+
+	stop := end + 1 + closing + len(delim)
+	for l.position < stop {
+		l.ReadChar()
+	}
+	return body, true
+}
+
+// readQuotedIdent reads a "name" or `name`, a doubled quote stands for
+// itself. ok is false when it never closes.
+func (l *Lexer) readQuotedIdent() (string, bool) {
+	quote := l.ch
+	l.ReadChar() // skip opening quote
+
+	var sb strings.Builder
+	for l.ch != 0 {
+		if l.ch == quote {
+			if l.peekChar() != quote {
+				l.ReadChar() // skip closing quote
+				return sb.String(), true
+			}
+			l.ReadChar() // skip the escaping quote
+		}
+		sb.WriteRune(l.ch)
 		l.ReadChar()
 	}
-	str := l.input[start:l.position]
-	l.ReadChar() // skip closing '
-	return str
+	return sb.String(), false
 }
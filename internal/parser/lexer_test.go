@@ -0,0 +1,283 @@
+package parser
+
+import (
+	"shogunc/internal/types"
+	"testing"
+)
+
+// expectTokens lexes input and compares the type and literal of each token
+func expectTokens(t *testing.T, input string, expected []Token) {
+	t.Helper()
+
+	l := NewLexer(input)
+	for i, want := range expected {
+		tok := l.NextToken()
+		if tok.Type != want.Type || tok.Literal != want.Literal {
+			t.Fatalf("token %d: expected %s %q, got %s %q", i, want.Type, want.Literal, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "SELECT id\n  FROM users;"
+	lexer := NewLexerAt(input, types.Position{File: "queries/users.sql", Line: 3, Column: 1, Offset: 40})
+
+	expected := []struct {
+		literal string
+		line    int
+		column  int
+		offset  int
+	}{
+		{"SELECT", 3, 1, 40},
+		{"id", 3, 8, 47},
+		{"FROM", 4, 3, 52},
+		{"users", 4, 8, 57},
+		{";", 4, 13, 62},
+	}
+
+	for i, want := range expected {
+		tok := lexer.NextToken()
+		if tok.Literal != want.literal {
+			t.Fatalf("token %d: expected literal %q, got %q", i, want.literal, tok.Literal)
+		}
+		if tok.Pos.File != "queries/users.sql" || tok.Pos.Line != want.line || tok.Pos.Column != want.column || tok.Pos.Offset != want.offset {
+			t.Errorf("token %q: expected %d:%d (offset %d), got %d:%d (offset %d)",
+				tok.Literal, want.line, want.column, want.offset, tok.Pos.Line, tok.Pos.Column, tok.Pos.Offset)
+		}
+		if tok.End.Offset-tok.Pos.Offset != len(want.literal) {
+			t.Errorf("token %q: expected span of %d bytes, got %d", tok.Literal, len(want.literal), tok.End.Offset-tok.Pos.Offset)
+		}
+	}
+}
+
+func TestLexComments(t *testing.T) {
+	input := `SELECT id -- trailing note
+/* block
+   comment */ FROM users; /* open`
+
+	expected := []Token{
+		{Type: SELECT, Literal: "SELECT"},
+		{Type: IDENT, Literal: "id"},
+		{Type: COMMENT, Literal: "-- trailing note"},
+		{Type: COMMENT, Literal: "/* block\n   comment */"},
+		{Type: FROM, Literal: "FROM"},
+		{Type: IDENT, Literal: "users"},
+		{Type: SEMICOLON, Literal: ";"},
+		{Type: ILLEGAL, Literal: "/* open"},
+		{Type: EOF, Literal: ""},
+	}
+
+	expectTokens(t, input, expected)
+}
+
+func TestLexUnterminated(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Token
+	}{
+		{"name = 'open", Token{Type: ILLEGAL, Literal: "'open"}},
+		{"name = 'it''s", Token{Type: ILLEGAL, Literal: "'it''s"}},
+		{"name = E'line\\n", Token{Type: ILLEGAL, Literal: "E'line\\n"}},
+		{"name = \"Open", Token{Type: ILLEGAL, Literal: "\"Open"}},
+		{"name = `open", Token{Type: ILLEGAL, Literal: "`open"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			expectTokens(t, tt.input, []Token{
+				{Type: IDENT, Literal: "name"},
+				{Type: ASSIGN, Literal: "="},
+				tt.want,
+				{Type: EOF, Literal: ""},
+			})
+		})
+	}
+}
+
+func TestLexOperators(t *testing.T) {
+	input := `= < > <= >= != <> || :: ! |`
+
+	expected := []Token{
+		{Type: ASSIGN, Literal: "="},
+		{Type: LT, Literal: "<"},
+		{Type: GT, Literal: ">"},
+		{Type: LTE, Literal: "<="},
+		{Type: GTE, Literal: ">="},
+		{Type: NOT_EQ, Literal: "!="},
+		{Type: NOT_EQ, Literal: "<>"},
+		{Type: CONCAT, Literal: "||"},
+		{Type: DOUBLECOLON, Literal: "::"},
+		{Type: ILLEGAL, Literal: "!"},
+		{Type: ILLEGAL, Literal: "|"},
+		{Type: EOF, Literal: ""},
+	}
+
+	expectTokens(t, input, expected)
+}
+
+func TestLexPlaceholders(t *testing.T) {
+	input := `id = ? AND email = ?12`
+
+	expected := []Token{
+		{Type: IDENT, Literal: "id"},
+		{Type: ASSIGN, Literal: "="},
+		{Type: PLACEHOLDER, Literal: ""},
+		{Type: AND, Literal: "AND"},
+		{Type: IDENT, Literal: "email"},
+		{Type: ASSIGN, Literal: "="},
+		{Type: PLACEHOLDER, Literal: "12"},
+		{Type: EOF, Literal: ""},
+	}
+
+	expectTokens(t, input, expected)
+}
+
+func TestLexQuotedIdentifiers(t *testing.T) {
+	input := `"Users" 'users' ` + "`Order Items`" + ` "say ""hi"""`
+
+	expected := []Token{
+		{Type: QUOTED_IDENT, Literal: "Users"},
+		{Type: STRING, Literal: "users"},
+		{Type: QUOTED_IDENT, Literal: "Order Items"},
+		{Type: QUOTED_IDENT, Literal: `say "hi"`},
+		{Type: EOF, Literal: ""},
+	}
+
+	expectTokens(t, input, expected)
+}
+
+func TestLexLiterals(t *testing.T) {
+	input := `42 3.14 .5 1e6 2.5E-3 0x1F -5 'O''Brien' E'line\nnext\'s' $$it's $1$$ $tag$a $$ b$tag$ $2`
+
+	expected := []Token{
+		{Type: INT, Literal: "42"},
+		{Type: FLOAT, Literal: "3.14"},
+		{Type: FLOAT, Literal: ".5"},
+		{Type: FLOAT, Literal: "1e6"},
+		{Type: FLOAT, Literal: "2.5E-3"},
+		{Type: INT, Literal: "0x1F"},
+		{Type: MINUS, Literal: "-"},
+		{Type: INT, Literal: "5"},
+		{Type: STRING, Literal: "O'Brien"},
+		{Type: STRING, Literal: "line\nnext's"},
+		{Type: STRING, Literal: "it's $1"},
+		{Type: STRING, Literal: "a $$ b"},
+		{Type: BINDPARAM, Literal: "$"},
+		{Type: INT, Literal: "2"},
+		{Type: EOF, Literal: ""},
+	}
+
+	expectTokens(t, input, expected)
+}
+
+func TestLexUnicodeIdentifiers(t *testing.T) {
+	input := `SELECT café, größe, 名前, _private, col2 FROM données WHERE "Ñandú" = $1;`
+
+	expected := []Token{
+		{Type: SELECT, Literal: "SELECT"},
+		{Type: IDENT, Literal: "café"},
+		{Type: COMMA, Literal: ","},
+		{Type: IDENT, Literal: "größe"},
+		{Type: COMMA, Literal: ","},
+		{Type: IDENT, Literal: "名前"},
+		{Type: COMMA, Literal: ","},
+		{Type: IDENT, Literal: "_private"},
+		{Type: COMMA, Literal: ","},
+		{Type: IDENT, Literal: "col2"},
+		{Type: FROM, Literal: "FROM"},
+		{Type: IDENT, Literal: "données"},
+		{Type: WHERE, Literal: "WHERE"},
+		{Type: QUOTED_IDENT, Literal: "Ñandú"},
+		{Type: ASSIGN, Literal: "="},
+		{Type: BINDPARAM, Literal: "$"},
+		{Type: INT, Literal: "1"},
+		{Type: SEMICOLON, Literal: ";"},
+		{Type: EOF, Literal: ""},
+	}
+
+	expectTokens(t, input, expected)
+}
+
+func TestLexUnicodeLiterals(t *testing.T) {
+	input := `'héllo 🎉' 'it''s ✓' E'tab\t🙂' $$naïve 🚀$$ $résumé$ ünïcödé $résumé$ -- nötë
+/* blöck 💬 */ ３`
+
+	expected := []Token{
+		{Type: STRING, Literal: "héllo 🎉"},
+		{Type: STRING, Literal: "it's ✓"},
+		{Type: STRING, Literal: "tab\t🙂"},
+		{Type: STRING, Literal: "naïve 🚀"},
+		{Type: STRING, Literal: " ünïcödé "},
+		{Type: COMMENT, Literal: "-- nötë"},
+		{Type: COMMENT, Literal: "/* blöck 💬 */"},
+		{Type: ILLEGAL, Literal: "３"}, // Full width digits are not SQL numbers
+		{Type: EOF, Literal: ""},
+	}
+
+	expectTokens(t, input, expected)
+}
+
+func TestLexInvalidUTF8(t *testing.T) {
+	tok := NewLexer("\xff").NextToken()
+	if tok.Type != ILLEGAL {
+		t.Errorf("expected ILLEGAL for invalid UTF-8, got %s %q", tok.Type, tok.Literal)
+	}
+}
+
+func TestUnicodeTokenPositions(t *testing.T) {
+	input := "SELECT café, '🎉'\n  FROM größe;"
+	lexer := NewLexer(input)
+
+	expected := []struct {
+		literal string
+		line    int
+		column  int // in runes
+		offset  int // in bytes
+		end     int // end column
+	}{
+		{"SELECT", 1, 1, 0, 7},
+		{"café", 1, 8, 7, 12},
+		{",", 1, 12, 12, 13},
+		{"🎉", 1, 14, 14, 17},
+		{"FROM", 2, 3, 23, 7},
+		{"größe", 2, 8, 28, 13},
+		{";", 2, 13, 35, 14},
+	}
+
+	for i, want := range expected {
+		tok := lexer.NextToken()
+		if tok.Literal != want.literal {
+			t.Fatalf("token %d: expected literal %q, got %q", i, want.literal, tok.Literal)
+		}
+		if tok.Pos.Line != want.line || tok.Pos.Column != want.column || tok.Pos.Offset != want.offset {
+			t.Errorf("token %q: expected %d:%d (offset %d), got %d:%d (offset %d)",
+				tok.Literal, want.line, want.column, want.offset, tok.Pos.Line, tok.Pos.Column, tok.Pos.Offset)
+		}
+		if tok.End.Column != want.end {
+			t.Errorf("token %q: expected to end at column %d, got %d", tok.Literal, want.end, tok.End.Column)
+		}
+	}
+}
+
+func TestParseUnicodeSchema(t *testing.T) {
+	schema := `CREATE TABLE "cafés" (
+    "número" INT PRIMARY KEY,
+    nombre   TEXT DEFAULT 'José 🎉'
+);`
+
+	parser := NewAst(NewLexer(schema))
+	if err := parser.ParseSchema(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	table := parser.Statements[0].(*Table)
+	if table.Name != "cafés" || len(table.Fields) != 2 {
+		t.Fatalf("unexpected table: %+v", table)
+	}
+	if table.Fields[0].Name != "número" || table.Fields[1].Name != "nombre" {
+		t.Errorf("unexpected field names: %q, %q", table.Fields[0].Name, table.Fields[1].Name)
+	}
+	if d := table.Fields[1].Default; d == nil || *d != "José 🎉" {
+		t.Errorf("unexpected default: %v", d)
+	}
+}
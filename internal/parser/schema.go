@@ -1,120 +1,233 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"shogunc/internal/types"
 	"strings"
 )
 
 type Field struct {
 	Name      string  // "description"
+	Quoted    bool    // Name was a quoted identifier
 	DataType  Token   // "TEXT"
+	TypeBare  bool    // DataType is an ENUM written as a bare name, it has to be created in the schema
 	NotNull   bool    // true if NOT NULL, false if nullable
 	Default   *string // optional default value
 	IsPrimary bool    // true if PRIMARY KEY
 	IsUnique  bool    // true if UNIQUE
+	Comment   string  // comment written above the column
 }
 
 type Table struct {
-	Name   string  // Table name
-	Fields []Field // table Fields
+	Name    string  // Table name
+	Fields  []Field // table Fields
+	Comment string  // comment written above CREATE TABLE
 }
 
 type Enum struct {
-	Name   string
-	Values []string
+	Name    string
+	Values  []string
+	Comment string
 }
 
+// ParseSchema parses every statement in the schema. A bad statement or column
+// is recorded and parsing resumes at the next one, the returned error joins
+// everything that went wrong.
 func (a *Ast) ParseSchema() error {
 	a.NextToken()
 	a.NextToken()
 
 	for a.currentToken.Type != EOF {
-		switch a.currentToken.Type {
-		case CREATE:
+		if err := a.parseSchemaStatement(); err != nil {
+			if a.unterminated != nil {
+				// The rest of the schema is inside the open literal, errors
+				// past its opening quote only follow from it
+				a.errs = append(errorsBefore(a.errs, a.unterminated.Pos), a.unterminated)
+				break
+			}
+			a.errs = append(a.errs, err)
+			a.synchronize()
+		}
+	}
+	a.resolveEnumTypes()
+
+	return errors.Join(a.errs...)
+}
+
+// resolveEnumTypes checks every column typed with a bare name the dialect
+// does not know refers to a CREATE TYPE of the schema, a column that does not
+// is reported and dropped from its table
+func (a *Ast) resolveEnumTypes() {
+	enums := make(map[string]bool)
+	for _, stmt := range a.Statements {
+		if enum, ok := stmt.(*Enum); ok {
+			enums[enum.Name] = true
+		}
+	}
+
+	for _, stmt := range a.Statements {
+		table, ok := stmt.(*Table)
+		if !ok {
+			continue
+		}
+		fields := table.Fields[:0]
+		for _, field := range table.Fields {
+			if field.TypeBare && !enums[field.DataType.Literal] {
+				a.errs = append(a.errs, a.errorAt(field.DataType, "[PARSER_TABLE] unknown type %s for column %s, wanted a database type or a CREATE TYPE", field.DataType.Literal, field.Name))
+				continue
+			}
+			fields = append(fields, field)
+		}
+		table.Fields = fields
+	}
+}
+
+// errorsBefore keeps the errors reported before pos
+func errorsBefore(errs []error, pos types.Position) []error {
+	var kept []error
+	for _, err := range errs {
+		var diag *types.Diagnostic
+		if errors.As(err, &diag) && diag.Pos.Offset >= pos.Offset {
+			continue
+		}
+		kept = append(kept, err)
+	}
+	return kept
+}
+
+func (a *Ast) parseSchemaStatement() error {
+	switch a.currentToken.Type {
+	case CREATE:
+		comment := a.leadingComment()
+		a.NextToken()
+		if a.currentToken.Type == TABLE {
+			if err := a.parseTable(comment); err != nil {
+				return err
+			}
 			a.NextToken()
-			if a.currentToken.Type == TABLE {
-				if err := a.parseTable(); err != nil {
-					return err
-				}
-				a.NextToken()
-			} else if a.currentToken.Type == TYPE {
-				if err := a.parseType(); err != nil {
-					return err
-				}
-				a.NextToken()
-			} else {
-				return fmt.Errorf("[SCHEMA_PARSER] unexpected token: %s", a.currentToken.Literal)
+		} else if a.currentToken.Type == TYPE {
+			if err := a.parseType(comment); err != nil {
+				return err
 			}
-		case SEMICOLON:
 			a.NextToken()
-		default:
-			return fmt.Errorf("unexpected token: %s", a.currentToken.Literal)
+		} else {
+			return a.errorf("[SCHEMA_PARSER] unexpected token: %s", a.currentToken.Literal)
 		}
+	case SEMICOLON:
+		a.NextToken()
+	default:
+		return a.errorf("unexpected token: %s", a.currentToken.Literal)
 	}
 
 	return nil
 }
 
-func (a *Ast) parseTable() error {
-	stmt := &Table{}
+func (a *Ast) parseTable(comment string) error {
+	stmt := &Table{Comment: comment}
+	a.NextToken() // consume TABLE
 
-	for a.currentToken.Type != STRING && a.currentToken.Type != EOF {
+	if a.currentToken.Type == IDENT && strings.EqualFold(a.currentToken.Literal, "IF") {
 		a.NextToken()
+		if err := a.expect(NOT); err != nil {
+			return err
+		}
+		if err := a.expect(EXISTS); err != nil {
+			return err
+		}
 	}
 
-	if a.currentToken.Type != STRING {
-		return fmt.Errorf("[PARSER_TABLE] unexpected token: %s wanted STRING", a.currentToken.Literal)
+	if !a.isIdent() {
+		return a.errorf("[PARSER_TABLE] unexpected token: %s wanted table name", a.currentToken.Literal)
 	}
-	stmt.Name = a.currentToken.Literal
+	stmt.Name = a.identName()
 	a.NextToken()
 
 	if a.currentToken.Type != LPAREN {
-		return fmt.Errorf("[PARSER_TABLE] unexpected token: %s wanted LPAREN", a.currentToken.Literal)
+		return a.errorf("[PARSER_TABLE] unexpected token: %s wanted LPAREN", a.currentToken.Literal)
 	}
 	a.NextToken()
 
 	var fields []Field
 	idx := 0 // debugging
-	for a.currentToken.Type != SEMICOLON && a.currentToken.Type != EOF {
+	for a.currentToken.Type != RPAREN {
 		field, err := a.parseTableField(idx)
 		if err != nil {
-			return err
+			// Keep the rest of the table so every bad column gets reported
+			a.errs = append(a.errs, err)
+			a.skipTableField()
+		} else {
+			fields = append(fields, *field)
 		}
 		idx = idx + 1
-		fields = append(fields, *field)
-		a.NextToken()
+
+		if a.currentToken.Type == SEMICOLON || a.currentToken.Type == EOF {
+			return a.errorf("[PARSER_TABLE] expected ) to close table %s, got %s", stmt.Name, a.currentToken.Type)
+		}
+		if a.currentToken.Type == COMMA {
+			a.NextToken()
+		}
 	}
+	a.NextToken() // consume )
 
 	stmt.Fields = fields
 	a.Statements = append(a.Statements, stmt)
 	return nil
 }
 
+// skipTableField moves to the comma or closing paren that ends the current column
+func (a *Ast) skipTableField() {
+	depth := 0
+	for a.currentToken.Type != SEMICOLON && a.currentToken.Type != EOF {
+		switch a.currentToken.Type {
+		case LPAREN:
+			depth++
+		case RPAREN:
+			if depth == 0 {
+				return
+			}
+			depth--
+		case COMMA:
+			if depth == 0 {
+				return
+			}
+		}
+		a.NextToken()
+	}
+}
+
 func (a *Ast) parseTableField(idx int) (*Field, error) {
-	var field Field
-	if a.currentToken.Type != STRING {
-		return nil, fmt.Errorf("[PARSER_TABLE] unexpected token wanted columns name STRING got: %s field_idx: %d", a.currentToken.Literal, idx)
+	field := Field{Comment: a.leadingComment()}
+	if !a.isIdent() {
+		return nil, a.errorf("[PARSER_TABLE] unexpected token wanted column name got: %s field_idx: %d", a.currentToken.Literal, idx)
 	}
-	field.Name = a.currentToken.Literal
+	field.Name = a.identName()
+	field.Quoted = a.currentToken.Type == QUOTED_IDENT
 	a.NextToken()
 
-	if IsDatabaseTypeToken(a.currentToken.Type) {
-		// Built-in database type tokens (BIGINT, TEXT, etc.)
-		field.DataType = a.currentToken
-		a.NextToken()
-	} else if a.currentToken.Type == IDENT && IsDatabaseType(a.currentToken.Literal) {
-		// Custom database types as identifiers
-		field.DataType = a.currentToken
-		a.NextToken()
-	} else if a.currentToken.Type == STRING {
-		// ENUM type
-		field.DataType = Token{Type: ENUM, Literal: a.currentToken.Literal}
-		a.NextToken()
+	if !a.isIdent() && !IsDatabaseTypeToken(a.currentToken.Type) {
+		return nil, a.errorf("[PARSER_TABLE] expected datatype, got: %s field_idx: %d", a.currentToken.Literal, idx)
+	}
+	span := a.currentToken.Span
+	typ, quoted, err := a.parseTypeName(field.Name)
+	if err != nil {
+		return nil, err
+	}
+	if tokType, ok := a.dialect.DatabaseType(typ); ok && !quoted {
+		// A type of the dialect such as TEXT, INTEGER or DOUBLE PRECISION
+		field.DataType = Token{Type: tokType, Literal: typ, Span: span}
 	} else {
-		return nil, fmt.Errorf("[PARSER_TABLE] expected datatype, got: %s field_idx: %d", a.currentToken.Literal, idx)
+		// ENUM type, quoted names keep their case and bare ones fold to lower
+		// case. resolveEnumTypes checks a bare one was created once the schema
+		// is read.
+		if !quoted {
+			typ = strings.ToLower(typ)
+		}
+		field.DataType = Token{Type: ENUM, Literal: typ, Span: span}
+		field.TypeBare = !quoted
 	}
 
-	for a.currentToken.Type != COMMA && a.currentToken.Type != RPAREN && a.currentToken.Type != EOF {
+	for a.currentToken.Type != COMMA && a.currentToken.Type != RPAREN && a.currentToken.Type != SEMICOLON && a.currentToken.Type != EOF {
 		switch a.currentToken.Type {
 		case PRIMARY:
 			if a.peekToken.Type == KEY {
@@ -122,7 +235,7 @@ func (a *Ast) parseTableField(idx int) (*Field, error) {
 				a.NextToken() // consume PRIMARY
 				a.NextToken() // consume KEY
 			} else {
-				return nil, fmt.Errorf("[PARSER_TABLE] expected KEY got: %s field_idx: %d", a.currentToken.Literal, idx)
+				return nil, a.errorf("[PARSER_TABLE] expected KEY got: %s field_idx: %d", a.currentToken.Literal, idx)
 			}
 		case NOT:
 			if a.peekToken.Type == NULL {
@@ -130,7 +243,7 @@ func (a *Ast) parseTableField(idx int) (*Field, error) {
 				a.NextToken() // consume NOT
 				a.NextToken() // consume NULL
 			} else {
-				return nil, fmt.Errorf("[PARSER_TABLE] expected NULL got: %s field_idx: %d", a.currentToken.Literal, idx)
+				return nil, a.errorf("[PARSER_TABLE] expected NULL got: %s field_idx: %d", a.currentToken.Literal, idx)
 			}
 		case NULL:
 			a.NextToken()
@@ -157,7 +270,7 @@ func (a *Ast) parseTableField(idx int) (*Field, error) {
 			}
 
 			// Handle literals like: "Status" 'open'
-			if (a.currentToken.Type == IDENT || a.currentToken.Type == STRING) &&
+			if a.isIdent() &&
 				a.isPrimitiveLiteral(a.peekToken.Type) {
 				a.NextToken() // Skip the prefix "Status"
 				val := a.currentToken.Literal
@@ -166,45 +279,49 @@ func (a *Ast) parseTableField(idx int) (*Field, error) {
 				continue
 			}
 
-			// Handle plain literals (STRING, INT, TRUE, FALSE)
-			if a.isPrimitiveLiteral(a.currentToken.Type) {
-				val := a.currentToken.Literal
-				field.Default = &val
+			// Handle plain literals (STRING, INT, FLOAT, TRUE, FALSE, NULL)
+			if val, kind, ok := a.parseLiteral(); ok {
+				if kind != types.NullLiteral {
+					field.Default = &val
+				}
 				a.NextToken()
 				continue
 			}
 
-			return nil, fmt.Errorf(
+			return nil, a.errorf(
 				"[PARSER_TABLE] invalid DEFAULT value: %s TYPE: %v field_idx: %d",
 				a.currentToken.Literal, a.currentToken.Type, idx,
 			)
 		default:
-			return nil, fmt.Errorf("[PARSER_TABLE] unexpected token in field definition: %s TYPE: %v field_idx: %d", a.currentToken.Literal, a.currentToken.Type, idx)
+			return nil, a.errorf("[PARSER_TABLE] unexpected token in field definition: %s TYPE: %v field_idx: %d", a.currentToken.Literal, a.currentToken.Type, idx)
 		}
 	}
 	return &field, nil
 }
 
 func (a Ast) isPrimitiveLiteral(t TokenType) bool {
-	return t == STRING || t == INT || t == TRUE || t == FALSE
+	return t == STRING || t == INT || t == FLOAT || t == TRUE || t == FALSE
 }
 
-func (a *Ast) parseType() error {
-	stmt := &Enum{}
+func (a *Ast) parseType(comment string) error {
+	stmt := &Enum{Comment: comment}
 	a.NextToken()
 
-	if a.currentToken.Type != STRING {
-		return fmt.Errorf("unexpected token: %s WANTED STRING", a.currentToken.Literal)
+	if !a.isIdent() {
+		return a.errorf("unexpected token: %s WANTED type name", a.currentToken.Literal)
 	}
-	stmt.Name = a.currentToken.Literal
+	stmt.Name = a.identName()
 	a.NextToken()
 
 	if a.currentToken.Type != AS && a.peekToken.Type != ENUM {
-		return fmt.Errorf("failed parsing ENUM invalid got: %s", a.currentToken.Literal)
+		return a.errorf("failed parsing ENUM invalid got: %s", a.currentToken.Literal)
 	}
 	a.NextToken()
 
 	for a.currentToken.Type != RPAREN {
+		if a.currentToken.Type == SEMICOLON || a.currentToken.Type == EOF {
+			return a.errorf("expected ) to close ENUM %s, got %s", stmt.Name, a.currentToken.Type)
+		}
 		if a.currentToken.Type == STRING {
 			stmt.Values = append(stmt.Values, a.currentToken.Literal)
 		}
@@ -219,18 +336,21 @@ func (a *Ast) parseIndex() error {
 	stmt := &Enum{}
 	a.NextToken()
 
-	if a.currentToken.Type != STRING {
-		return fmt.Errorf("unexpected token: %s WANTED STRING", a.currentToken.Literal)
+	if !a.isIdent() {
+		return a.errorf("unexpected token: %s WANTED type name", a.currentToken.Literal)
 	}
-	stmt.Name = a.currentToken.Literal
+	stmt.Name = a.identName()
 	a.NextToken()
 
 	if a.currentToken.Type != AS && a.peekToken.Type != ENUM {
-		return fmt.Errorf("failed parsing ENUM invalid got: %s", a.currentToken.Literal)
+		return a.errorf("failed parsing ENUM invalid got: %s", a.currentToken.Literal)
 	}
 	a.NextToken()
 
 	for a.currentToken.Type != RPAREN {
+		if a.currentToken.Type == SEMICOLON || a.currentToken.Type == EOF {
+			return a.errorf("expected ) to close ENUM %s, got %s", stmt.Name, a.currentToken.Type)
+		}
 		if a.currentToken.Type == STRING {
 			stmt.Values = append(stmt.Values, a.currentToken.Literal)
 		}
@@ -265,7 +385,7 @@ func stringifyTableType(t *Table) string {
 		}
 		if field.Default != nil {
 			sb.WriteString(" DEFAULT ")
-			sb.WriteString(fmt.Sprintf("'%s'", *field.Default))
+			sb.WriteString(fmt.Sprintf("'%s'", strings.ReplaceAll(*field.Default, "'", "''")))
 		}
 
 		if i < len(t.Fields)-1 {
@@ -284,7 +404,7 @@ func stringifyEnumType(e *Enum) string {
 	sb.WriteString(fmt.Sprintf("CREATE TYPE \"%s\" AS ENUM (", e.Name))
 
 	for i, val := range e.Values {
-		sb.WriteString(fmt.Sprintf("'%s'", val))
+		sb.WriteString(fmt.Sprintf("'%s'", strings.ReplaceAll(val, "'", "''")))
 		if i < len(e.Values)-1 {
 			sb.WriteString(",")
 		}
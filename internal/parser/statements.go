@@ -1,163 +1,528 @@
 package parser
 
 import (
-	"errors"
 	"fmt"
 	"shogunc/internal/types"
 	"strconv"
 	"strings"
 )
 
+// Parse parses one query. A literal left open is reported over the error it
+// causes, as it swallowed the rest of the query.
 func (a *Ast) Parse() error {
 	a.NextToken()
 	a.NextToken()
 
+	err := a.parseStatement()
+	if a.unterminated != nil {
+		return a.unterminated
+	}
+	return err
+}
+
+func (a *Ast) parseStatement() error {
 	switch a.currentToken.Type {
-	case SELECT:
+	case SELECT, WITH:
 		return a.parseSelect()
 	case INSERT:
 		return a.parseInsert()
+	case UPDATE:
+		return a.parseUpdate()
+	case DELETE:
+		return a.parseDelete()
 	default:
-		return fmt.Errorf("unexpected token: %s", a.currentToken.Literal)
+		return a.errorf("unexpected token: %s", a.currentToken.Literal)
 	}
 }
 
 func (a *Ast) parseSelect() error {
+	stmt, err := a.parseSelectStatement()
+	if err != nil {
+		return err
+	}
+	if err := a.expectEnd(); err != nil {
+		return err
+	}
+
+	a.Statements = append(a.Statements, stmt)
+	return nil
+}
+
+// parseSelectStatement reads a SELECT and the WITH before it up to the token
+// after its last clause, subqueries nested in it share its param numbering
+func (a *Ast) parseSelectStatement() (*types.SelectStatement, error) {
 	stmt := &types.SelectStatement{}
-	bindPositionCounter := 1 // Track automatic position assignment
-	a.NextToken()
+	if a.currentToken.Type == WITH {
+		if err := a.parseWith(stmt); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.parseSelectCore(stmt); err != nil {
+		return nil, err
+	}
+
+	// Parse UNION, INTERSECT and EXCEPT
+	for a.isCompound() {
+		compound, err := a.parseCompound()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Compounds = append(stmt.Compounds, compound)
+	}
+
+	// Parse ORDER BY
+	if a.currentToken.Type == ORDER {
+		orderBy, err := a.parseOrderBy()
+		if err != nil {
+			return nil, err
+		}
+		stmt.OrderBy = orderBy
+	}
+
+	// Parse LIMIT and OFFSET
+	if a.currentToken.Type == LIMIT {
+		limit, err := a.parseRowCount("limit")
+		if err != nil {
+			return nil, err
+		}
+		stmt.Limit = limit
+	}
+	if a.currentToken.Type == OFFSET {
+		offset, err := a.parseRowCount("offset")
+		if err != nil {
+			return nil, err
+		}
+		stmt.Offset = offset
+	}
+
+	return stmt, nil
+}
+
+// parseSelectCore reads a query from SELECT up to HAVING, what a compound
+// joins together
+func (a *Ast) parseSelectCore(stmt *types.SelectStatement) error {
+	if err := a.expect(SELECT); err != nil {
+		return err
+	}
+
+	if a.currentToken.Type == DISTINCT {
+		stmt.Distinct = true
+		a.NextToken()
+	}
 
 	// Parse SELECT
-	for a.currentToken.Type != FROM && a.currentToken.Type != EOF { // Extracting columns
-		switch a.currentToken.Type {
-		case ASTERIK, IDENT:
-			stmt.Columns = append(stmt.Columns, a.currentToken.Literal)
+	for {
+		column, err := a.parseResultColumn()
+		if err != nil {
+			return err
+		}
+		if err := checkSliceParams(column.Expr); err != nil {
+			return err
+		}
+		stmt.Columns = append(stmt.Columns, column)
+
+		if a.currentToken.Type != COMMA {
+			break
 		}
 		a.NextToken()
 	}
 
 	// Parse FROM
-	a.advanceAndExpect(FROM)
+	if err := a.expect(FROM); err != nil {
+		return err
+	}
 
 	// Parse table name
-	if a.currentToken.Type != IDENT {
-		return fmt.Errorf("expected table name (IDENT), got %s", a.currentToken.Type)
+	table, err := a.parseTableRef()
+	if err != nil {
+		return err
+	}
+	stmt.TableName, stmt.TableQuoted, stmt.TableSelect = table.Name, table.Quoted, table.Select
+	stmt.TableAlias, stmt.TableAliasQuoted = table.Alias, table.AliasQuoted
+	stmt.TableSpan = table.Span
+
+	// Parse JOIN
+	for a.isJoin() {
+		join, err := a.parseJoin()
+		if err != nil {
+			return err
+		}
+		stmt.Joins = append(stmt.Joins, join)
 	}
-	stmt.TableName = strings.ToLower(a.currentToken.Literal)
-	a.NextToken() // Advance past the table name
 
 	// Parse WHERE
-	var conditions []types.Condition
 	if a.currentToken.Type == WHERE {
-		for a.currentToken.Type != SEMICOLON && a.currentToken.Type != EOF {
-			if a.currentToken.Type == LIMIT || a.currentToken.Type == OFFSET {
-				break
-			}
-			cond := types.Condition{}
-			if len(cond.ChainOp) == 0 && IsLogicalOperator(a.currentToken.Literal) {
-				cond.ChainOp = types.ToLogicOp(a.currentToken.Literal)
-				a.NextToken()
-			}
+		where, err := a.parseWhere()
+		if err != nil {
+			return err
+		}
+		stmt.Where = where
+	}
 
-			if a.currentToken.Type == IDENT {
-				cond.Column = a.currentToken.Literal
-				a.NextToken()
-			}
+	// Parse GROUP BY and HAVING
+	if a.currentToken.Type == GROUP {
+		groupBy, err := a.parseGroupBy()
+		if err != nil {
+			return err
+		}
+		stmt.GroupBy = groupBy
+	}
+	if a.currentToken.Type == HAVING {
+		having, err := a.parseWhere()
+		if err != nil {
+			return err
+		}
+		stmt.Having = having
+	}
 
-			if cond.Operator == "" && IsConditional(a.currentToken.Literal) {
-				cond.Operator = types.ConditionOp(a.currentToken.Literal)
-				a.NextToken()
-			}
+	return nil
+}
 
-			switch a.currentToken.Type {
-			case BINDPARAM:
-				a.NextToken()
-				var position int // Bind position
-				if a.currentToken.Literal != "" {
-					// Explicit position like $1, $2
-					var err error
-					position, err = strconv.Atoi(a.currentToken.Literal)
-					if err != nil {
-						return fmt.Errorf("invalid bind param: %v", err)
-					}
-				} else {
-					// Automatic position assignment for ? placeholders
-					position = bindPositionCounter
-					bindPositionCounter++
-				}
-				bind, err := a.parseBindParam(cond.Column, position, nil)
-				if err != nil {
-					return err
-				}
-				cond.Value = bind
-			case STRING:
-				// Create a copy of the string to avoid pointer issues
-				literalValue := a.currentToken.Literal
-				bind, err := a.parseBindParam(cond.Column, 0, &literalValue)
-				if err != nil {
-					return err
+// parseWith reads WITH [RECURSIVE] name [(columns)] AS (query), ...
+func (a *Ast) parseWith(stmt *types.SelectStatement) error {
+	a.NextToken() // consume WITH
+	if a.currentToken.Type == RECURSIVE {
+		stmt.Recursive = true
+		a.NextToken()
+	}
+
+	for {
+		if !a.isIdent() {
+			return a.errorf("expected CTE name after WITH, got %s", a.currentToken.Literal)
+		}
+		cte := types.CTE{Name: a.identName(), NameQuoted: a.currentToken.Type == QUOTED_IDENT, Span: a.currentToken.Span}
+		a.NextToken()
+
+		if a.currentToken.Type == LPAREN {
+			a.NextToken()
+			for {
+				if !a.isIdent() {
+					return a.errorf("expected column name in %s (...), got %s", cte.Name, a.currentToken.Literal)
 				}
-				cond.Value = bind
-			case INT:
-				bind, err := a.parseBindParam(cond.Column, 0, &a.currentToken.Literal)
-				if err != nil {
-					return err
+				cte.Columns = append(cte.Columns, types.Ident{Name: a.identName(), Quoted: a.currentToken.Type == QUOTED_IDENT})
+				a.NextToken()
+				if a.currentToken.Type != COMMA {
+					break
 				}
-				cond.Value = bind
+				a.NextToken()
+			}
+			if err := a.expect(RPAREN); err != nil {
+				return err
 			}
+		}
+
+		if err := a.expect(AS); err != nil {
+			return err
+		}
+		if !a.isSubquery() {
+			return a.errorf("expected (SELECT after %s AS, got %s", cte.Name, a.currentToken.Literal)
+		}
+		subquery, err := a.parseSubquery()
+		if err != nil {
+			return err
+		}
+		cte.Select = subquery.Select
+		stmt.With = append(stmt.With, cte)
 
-			if cond.Column != "" && cond.Operator != "" && (cond.Value.Position != 0 || cond.Value.Value != nil) {
-				conditions = append(conditions, cond)
+		if a.currentToken.Type != COMMA {
+			return nil
+		}
+		a.NextToken()
+	}
+}
+
+// parseResultColumn reads one item of a SELECT list with its alias, * and
+// t.* are read as a ColumnRef with Column *
+func (a *Ast) parseResultColumn() (types.SelectColumn, error) {
+	if a.currentToken.Type == ASTERIK {
+		a.NextToken()
+		return types.SelectColumn{Expr: &types.ColumnRef{Column: "*"}}, nil
+	}
+
+	expr, err := a.parseExpr(precLowest)
+	if err != nil {
+		return types.SelectColumn{}, err
+	}
+	column := types.SelectColumn{Expr: expr}
+	if isStar(expr) {
+		return column, nil
+	}
+
+	column.Alias, column.AliasQuoted, err = a.parseAlias()
+	return column, err
+}
+
+// parseGroupBy reads GROUP BY expr, ...
+func (a *Ast) parseGroupBy() ([]types.Expr, error) {
+	a.NextToken() // consume GROUP
+	return a.parseByList()
+}
+
+// parseByList reads BY expr, ... after GROUP or PARTITION
+func (a *Ast) parseByList() ([]types.Expr, error) {
+	if err := a.expect(BY); err != nil {
+		return nil, err
+	}
+
+	var exprs []types.Expr
+	for {
+		expr, err := a.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSliceParams(expr); err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+
+		if a.currentToken.Type != COMMA {
+			return exprs, nil
+		}
+		a.NextToken()
+	}
+}
+
+// parseOrderBy reads ORDER BY expr [ASC | DESC] [NULLS FIRST | LAST], ...
+func (a *Ast) parseOrderBy() ([]types.OrderTerm, error) {
+	a.NextToken() // consume ORDER
+	if err := a.expect(BY); err != nil {
+		return nil, err
+	}
+
+	var terms []types.OrderTerm
+	for {
+		expr, err := a.parseExpr(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSliceParams(expr); err != nil {
+			return nil, err
+		}
+		term := types.OrderTerm{Expr: expr}
+
+		if a.currentToken.Type == ASC || a.currentToken.Type == DESC {
+			term.Direction = strings.ToUpper(a.currentToken.Literal)
+			a.NextToken()
+		}
+		if a.currentToken.Type == NULLS {
+			a.NextToken()
+			if a.currentToken.Type != FIRST && a.currentToken.Type != LAST {
+				return nil, a.errorf("expected FIRST or LAST after NULLS, got %s", a.currentToken.Literal)
 			}
+			term.Nulls = strings.ToUpper(a.currentToken.Literal)
 			a.NextToken()
 		}
+		terms = append(terms, term)
+
+		if a.currentToken.Type != COMMA {
+			return terms, nil
+		}
+		a.NextToken()
 	}
+}
 
-	// fmt.Println("before LIMIT:", a.currentToken.Type, a.currentToken.Literal)
-	if a.currentToken.Type == LIMIT {
+// parseRowCount reads the count after LIMIT or OFFSET. A param there is an
+// int64 named after the clause unless it was given a name.
+func (a *Ast) parseRowCount(clause string) (types.Expr, error) {
+	a.NextToken() // consume LIMIT or OFFSET
+	count, err := a.parseExpr(precLowest)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSliceParams(count); err != nil {
+		return nil, err
+	}
+
+	if param, ok := count.(*types.Param); ok {
+		if param.Name == "" {
+			param.Name = clause
+		}
+		param.Type = "int64"
+	}
+	return count, nil
+}
+
+// parseTableRef reads the name of a table in FROM or JOIN with its alias
+func (a *Ast) parseTableRef() (types.TableRef, error) {
+	if a.currentToken.Type == LPAREN {
+		return a.parseDerivedTable()
+	}
+	if !a.isIdent() {
+		return types.TableRef{}, a.errorf("expected table name (IDENT), got %s", a.currentToken.Type)
+	}
+	table := types.TableRef{Name: a.identName(), Quoted: a.currentToken.Type == QUOTED_IDENT, Span: a.currentToken.Span}
+	a.NextToken()
+
+	var err error
+	table.Alias, table.AliasQuoted, err = a.parseAlias()
+	return table, err
+}
+
+// parseDerivedTable reads (SELECT ...) alias, the alias names the table its
+// columns are qualified with so it cannot be left out
+func (a *Ast) parseDerivedTable() (types.TableRef, error) {
+	table := types.TableRef{Span: a.currentToken.Span}
+	subquery, err := a.parseSubquery()
+	if err != nil {
+		return types.TableRef{}, err
+	}
+	table.Select = subquery.Select
+
+	table.Alias, table.AliasQuoted, err = a.parseAlias()
+	if err != nil {
+		return types.TableRef{}, err
+	}
+	if table.Alias == "" {
+		return types.TableRef{}, types.Errorf(table.Span, "subquery in FROM needs an alias")
+	}
+	return table, nil
+}
+
+// parseAlias reads AS name or a bare name, it returns an empty alias when
+// neither follows
+func (a *Ast) parseAlias() (string, bool, error) {
+	if a.currentToken.Type == AS {
 		a.NextToken()
-		if a.currentToken.Type == INT {
-			val, err := strconv.Atoi(a.currentToken.Literal)
-			if err != nil {
-				return fmt.Errorf("invalid bind param: %v", err)
-			}
-			stmt.Limit = val
+		if !a.isIdent() {
+			return "", false, a.errorf("expected alias after AS, got %s", a.currentToken.Literal)
+		}
+	} else if !a.isIdent() {
+		return "", false, nil
+	}
+
+	alias, quoted := a.identName(), a.currentToken.Type == QUOTED_IDENT
+	a.NextToken()
+	return alias, quoted, nil
+}
+
+// isStar reports whether e is * or t.*
+func isStar(e types.Expr) bool {
+	ref, ok := e.(*types.ColumnRef)
+	return ok && ref.Column == "*"
+}
+
+// isCompound reports whether UNION, INTERSECT or EXCEPT starts at the current
+// token
+func (a *Ast) isCompound() bool {
+	switch a.currentToken.Type {
+	case UNION, INTERSECT, EXCEPT:
+		return true
+	}
+	return false
+}
+
+// parseCompound reads UNION [ALL], INTERSECT [ALL] or EXCEPT [ALL] and the
+// query after it up to HAVING
+func (a *Ast) parseCompound() (types.Compound, error) {
+	compound := types.Compound{Op: string(a.currentToken.Type), Span: a.currentToken.Span}
+	a.NextToken()
+	if a.currentToken.Type == ALL {
+		compound.Op += " ALL"
+		a.NextToken()
+	}
+
+	if a.currentToken.Type != SELECT {
+		return types.Compound{}, a.errorf("expected SELECT after %s, got %s", compound.Op, a.currentToken.Literal)
+	}
+	compound.Select = &types.SelectStatement{}
+	if err := a.parseSelectCore(compound.Select); err != nil {
+		return types.Compound{}, err
+	}
+	return compound, nil
+}
+
+// isJoin reports whether a JOIN clause starts at the current token
+func (a *Ast) isJoin() bool {
+	switch a.currentToken.Type {
+	case JOIN, INNER, LEFT, RIGHT, FULL, CROSS:
+		return true
+	}
+	return false
+}
+
+// parseJoin reads [INNER | LEFT | RIGHT | FULL [OUTER] | CROSS] JOIN table
+// followed by ON condition or USING (columns), a CROSS JOIN takes neither
+func (a *Ast) parseJoin() (types.Join, error) {
+	join := types.Join{Type: types.InnerJoin}
+	switch a.currentToken.Type {
+	case INNER:
+		a.NextToken()
+	case LEFT, RIGHT, FULL:
+		join.Type = types.JoinType(strings.ToUpper(a.currentToken.Literal))
+		a.NextToken()
+		if a.currentToken.Type == OUTER {
 			a.NextToken()
 		}
+	case CROSS:
+		join.Type = types.CrossJoin
+		a.NextToken()
+	}
+	if err := a.expect(JOIN); err != nil {
+		return join, err
 	}
 
-	if a.currentToken.Type == OFFSET {
+	table, err := a.parseTableRef()
+	if err != nil {
+		return join, err
+	}
+	join.Table = table
+	if join.Type == types.CrossJoin {
+		return join, nil
+	}
+
+	switch a.currentToken.Type {
+	case ON:
 		a.NextToken()
-		if a.currentToken.Type == INT {
-			val, err := strconv.Atoi(a.currentToken.Literal)
-			if err != nil {
-				return fmt.Errorf("invalid bind param: %v", err)
+		on, err := a.parseExpr(precLowest)
+		if err != nil {
+			return join, err
+		}
+		if err := checkSliceParams(on); err != nil {
+			return join, err
+		}
+		join.On = on
+	case USING:
+		a.NextToken()
+		if err := a.expect(LPAREN); err != nil {
+			return join, err
+		}
+		for {
+			if !a.isIdent() {
+				return join, a.errorf("expected column name in USING, got %s", a.currentToken.Literal)
+			}
+			join.Using = append(join.Using, types.Ident{Name: a.identName(), Quoted: a.currentToken.Type == QUOTED_IDENT})
+			a.NextToken()
+			if a.currentToken.Type != COMMA {
+				break
 			}
-			stmt.Offset = val
 			a.NextToken()
 		}
+		if err := a.expect(RPAREN); err != nil {
+			return join, err
+		}
+	default:
+		return join, a.errorf("expected ON or USING after JOIN %s, got %s", table.Name, a.currentToken.Literal)
 	}
-	stmt.Conditions = conditions
-
-	a.Statements = append(a.Statements, stmt)
-	return nil
+	return join, nil
 }
 
 func (a *Ast) parseInsert() error {
 	stmt := &types.InsertStatement{}
-	bindPositionCounter := 1 // Track automatic position assignment
-	columnIndex := 0         // Track which column we're processing
+	columnIndex := 0 // Track which column we're processing
 	a.NextToken()
 
 	// Parse Insert
 	for a.currentToken.Type != VALUES && a.currentToken.Type != EOF {
-		if a.currentToken.Type == IDENT {
-			stmt.TableName = a.currentToken.Literal
+		if a.isIdent() {
+			stmt.TableName = a.identName()
+			stmt.TableQuoted = a.currentToken.Type == QUOTED_IDENT
+			stmt.TableSpan = a.currentToken.Span
 		}
 		if a.currentToken.Type == LPAREN {
-			for a.currentToken.Type != RPAREN {
-				if a.currentToken.Type == IDENT {
-					stmt.Columns = append(stmt.Columns, a.currentToken.Literal)
+			for a.currentToken.Type != RPAREN && a.currentToken.Type != EOF {
+				if a.isIdent() {
+					stmt.Columns = append(stmt.Columns, a.identName())
+					stmt.ColumnQuoted = append(stmt.ColumnQuoted, a.currentToken.Type == QUOTED_IDENT)
 				}
 				a.NextToken()
 			}
@@ -166,89 +531,88 @@ func (a *Ast) parseInsert() error {
 	}
 
 	// Parse VALUES
-	a.advanceAndExpect(VALUES)
-	// if a.currentToken.Type == VALUES {
-	// 	a.NextToken()
-	// }
+	if err := a.expect(VALUES); err != nil {
+		return err
+	}
 
 	// Parse Values
-	for a.currentToken.Type != SEMICOLON && a.currentToken.Type != EOF && a.currentToken.Type != RETURNING {
-		if a.currentToken.Type == LPAREN {
-			for a.currentToken.Type != RPAREN && a.currentToken.Type != EOF {
-				switch a.currentToken.Type {
-				case BINDPARAM:
-					a.NextToken()
-					var position int
-					if a.currentToken.Literal != "" {
-						// Explicit position like $1, $2
-						var err error
-						position, err = strconv.Atoi(a.currentToken.Literal)
-						if err != nil {
-							return fmt.Errorf("invalid bind param: %v", err)
-						}
-					} else {
-						// Automatic position assignment for ? placeholders
-						position = bindPositionCounter
-						bindPositionCounter++
-					}
-
-					// Get column name for this value
-					var columnName string
-					if columnIndex < len(stmt.Columns) {
-						columnName = stmt.Columns[columnIndex]
-					}
-
-					// Create Bind struct with column association
-					bindValue := types.Bind{
-						Column:   columnName,
-						Position: position,
-					}
-					stmt.Values = append(stmt.Values, bindValue)
-					columnIndex++
-				case STRING:
-					// Handle string literals like 'John', '25'
-					var columnName string
-					if columnIndex < len(stmt.Columns) {
-						columnName = stmt.Columns[columnIndex]
-					}
-
-					// Create Bind struct with literal value
-					literalValue := a.currentToken.Literal
-					bindValue := types.Bind{
-						Column: columnName,
-						Value:  &literalValue,
-					}
-					stmt.Values = append(stmt.Values, bindValue)
-					columnIndex++
-				case INT:
-					// Handle integer literals
-					var columnName string
-					if columnIndex < len(stmt.Columns) {
-						columnName = stmt.Columns[columnIndex]
-					}
-
-					// Create Bind struct with literal value
-					literalValue := a.currentToken.Literal
-					bindValue := types.Bind{
-						Column: columnName,
-						Value:  &literalValue,
-					}
-					stmt.Values = append(stmt.Values, bindValue)
-					columnIndex++
-				case COMMA:
-					// Skip commas between values
-				}
-				a.NextToken()
+	values := a.currentToken
+	if err := a.expect(LPAREN); err != nil {
+		return err
+	}
+	for a.currentToken.Type != RPAREN {
+		if a.currentToken.Type == SEMICOLON || a.currentToken.Type == EOF {
+			return a.errorf("expected ) to close VALUES, got %s", a.currentToken.Type)
+		}
+		switch {
+		case a.isParam():
+			param, err := a.parseParam()
+			if err != nil {
+				return err
+			}
+			if param.Slice {
+				return types.Errorf(param.Span, "sqlc.slice(%s) can only be used as the list of an IN", param.Name)
+			}
+
+			// Get column name for this value
+			var columnName string
+			if columnIndex < len(stmt.Columns) {
+				columnName = stmt.Columns[columnIndex]
+			}
+
+			// Create Bind struct with column association
+			bindValue := types.Bind{
+				Column:   columnName,
+				Name:     param.Name,
+				Position: param.Position,
 			}
+			stmt.Values = append(stmt.Values, bindValue)
+			columnIndex++
+		case a.currentToken.Type == COMMA:
+			// Skip commas between values
+		default:
+			// Handle literals like 'John', 25, -1.5, TRUE
+			literalValue, kind, ok := a.parseLiteral()
+			if !ok {
+				return a.errorf("unsupported value %s in VALUES, wanted a param or literal", a.currentToken.Literal)
+			}
+			var columnName string
+			if columnIndex < len(stmt.Columns) {
+				columnName = stmt.Columns[columnIndex]
+			}
+
+			// Create Bind struct with literal value
+			bindValue := types.Bind{
+				Column: columnName,
+				Value:  &literalValue,
+				Kind:   kind,
+			}
+			stmt.Values = append(stmt.Values, bindValue)
+			columnIndex++
 		}
 		a.NextToken()
 	}
+	a.NextToken() // consume )
+
+	// Without a column list the values follow the table's columns, the
+	// generator takes them from the schema
+	if len(stmt.Columns) > 0 && len(stmt.Values) != len(stmt.Columns) {
+		return a.errorAt(values, "VALUES has %d values for %d columns", len(stmt.Values), len(stmt.Columns))
+	}
+	if a.currentToken.Type == COMMA {
+		return a.errorf("INSERT with more than one VALUES row is not supported")
+	}
+
+	// Skip what follows the row up to RETURNING
+	for a.currentToken.Type != SEMICOLON && a.currentToken.Type != EOF && a.currentToken.Type != RETURNING {
+		a.NextToken()
+	}
 
 	if a.currentToken.Type == RETURNING {
 		a.NextToken()
 		for a.currentToken.Type != SEMICOLON && a.currentToken.Type != EOF {
-			if a.currentToken.Type == IDENT {
-				stmt.ReturningFields = append(stmt.ReturningFields, a.currentToken.Literal)
+			if a.isIdent() {
+				stmt.ReturningFields = append(stmt.ReturningFields, a.identName())
 			}
 			a.NextToken()
 		}
@@ -258,108 +622,268 @@ func (a *Ast) parseInsert() error {
 	return nil
 }
 
-func (a *Ast) parseBindParam(columnName string, positionCounter int, defaultValue *string) (types.Bind, error) {
-	if columnName == "" {
-		return types.Bind{}, errors.New("invalid bind params, no column name provided")
-	}
-	return types.Bind{
-		Column:   columnName,
-		Position: positionCounter,
-		Value:    defaultValue,
-	}, nil
-}
+func (a *Ast) parseUpdate() error {
+	stmt := &types.UpdateStatement{}
+	a.NextToken()
 
-func (a *Ast) advanceAndExpect(expected TokenType) error {
+	// Parse table name
+	if !a.isIdent() {
+		return a.errorf("expected table name (IDENT), got %s", a.currentToken.Type)
+	}
+	stmt.TableName = a.identName()
+	stmt.TableQuoted = a.currentToken.Type == QUOTED_IDENT
+	stmt.TableSpan = a.currentToken.Span
 	a.NextToken()
-	if a.currentToken.Type != expected {
-		return fmt.Errorf("expected %s, got %s", expected, a.currentToken.Type)
+
+	// Parse SET
+	if err := a.expect(SET); err != nil {
+		return err
 	}
+	for {
+		if !a.isIdent() {
+			return a.errorf("expected column name in SET, got %s", a.currentToken.Literal)
+		}
+		column := a.identName()
+		quoted := a.currentToken.Type == QUOTED_IDENT
+		a.NextToken()
+		if err := a.expect(ASSIGN); err != nil {
+			return err
+		}
 
-	return nil
-}
+		value, err := a.parseExpr(precLowest)
+		if err != nil {
+			return err
+		}
+		if err := checkSliceParams(value); err != nil {
+			return err
+		}
+		a.linkParam(&types.ColumnRef{Column: column}, value)
+		stmt.Sets = append(stmt.Sets, types.Assignment{Column: column, ColumnQuoted: quoted, Value: value})
 
-func (a *Ast) String() string {
-	var out strings.Builder
-	if len(a.Statements) == 0 {
-		return ""
+		if a.currentToken.Type != COMMA {
+			break
+		}
+		a.NextToken()
 	}
 
-	switch stmt := a.Statements[0].(type) {
-	case *types.SelectStatement:
-		out.WriteString(stringifySelectStatement(stmt))
-	case *types.InsertStatement:
-		out.WriteString(stringifyInsertStatement(stmt))
+	// Parse WHERE
+	if a.currentToken.Type == WHERE {
+		where, err := a.parseWhere()
+		if err != nil {
+			return err
+		}
+		stmt.Where = where
 	}
 
-	return out.String()
+	if err := a.expectEnd(); err != nil {
+		return err
+	}
+
+	a.Statements = append(a.Statements, stmt)
+	return nil
 }
 
-func stringifySelectStatement(stmt *types.SelectStatement) string {
-	var sb strings.Builder
+func (a *Ast) parseDelete() error {
+	stmt := &types.DeleteStatement{}
+	a.NextToken()
 
-	sb.WriteString("SELECT ")
-	if stmt.Distinct {
-		sb.WriteString("DISTINCT ")
+	if err := a.expect(FROM); err != nil {
+		return err
 	}
 
-	if len(stmt.Columns) == 1 && stmt.Columns[0] == "*" {
-		sb.WriteString("* ")
-	} else {
-		for i, f := range stmt.Columns {
-			sb.WriteString(f)
-			if i < len(stmt.Columns)-1 {
-				sb.WriteString(", ")
-			} else {
-				sb.WriteString(" ")
-			}
+	// Parse table name
+	if !a.isIdent() {
+		return a.errorf("expected table name (IDENT), got %s", a.currentToken.Type)
+	}
+	stmt.TableName = a.identName()
+	stmt.TableQuoted = a.currentToken.Type == QUOTED_IDENT
+	stmt.TableSpan = a.currentToken.Span
+	a.NextToken()
+
+	// Parse WHERE
+	if a.currentToken.Type == WHERE {
+		where, err := a.parseWhere()
+		if err != nil {
+			return err
 		}
+		stmt.Where = where
 	}
 
-	sb.WriteString("FROM ")
-	sb.WriteString(string(stmt.TableName))
-	sb.WriteString(" ")
+	if err := a.expectEnd(); err != nil {
+		return err
+	}
 
-	if len(stmt.Conditions) > 0 {
-		sb.WriteString("WHERE ")
-		for i, c := range stmt.Conditions {
-			if len(c.ChainOp) > 0 && i > 0 {
-				sb.WriteString(string(c.ChainOp))
-				sb.WriteString(" ")
-			}
+	a.Statements = append(a.Statements, stmt)
+	return nil
+}
 
-			sb.WriteString(string(c.Column))
-			sb.WriteString(" ")
-			sb.WriteString(string(c.Operator))
-			sb.WriteString(" ")
+// isParam reports whether a param starts at the current token
+func (a *Ast) isParam() bool {
+	switch a.currentToken.Type {
+	case BINDPARAM, PLACEHOLDER, NAMED_PARAM:
+		return true
+	case IDENT:
+		return strings.EqualFold(a.currentToken.Literal, "sqlc") && a.peekToken.Type == DOT
+	}
+	return false
+}
 
-			if c.Value.Position != 0 {
-				sb.WriteString(fmt.Sprintf("$%d", c.Value.Position))
-			} else if c.Value.Value != nil {
-				if num, err := strconv.Atoi(*c.Value.Value); err == nil {
-					sb.WriteString(fmt.Sprintf("%d", num))
-				} else {
-					sb.WriteString(fmt.Sprintf("'%s'", *c.Value.Value))
-				}
-			} else {
-				sb.WriteString("NULL")
-			}
+// parseParam reads $N, ?, ?NNN, @name, :name, sqlc.arg(name) or
+// sqlc.slice(name). A bare ? and named params are numbered after the highest
+// position seen so far like SQLite does, a name used again gets its first
+// position back. The current token is left on the last token of the param.
+func (a *Ast) parseParam() (*types.Param, error) {
+	start := a.currentToken
+	param := &types.Param{}
+	var err error
 
-			if i < len(stmt.Conditions)-1 {
-				sb.WriteString(" ")
+	switch a.currentToken.Type {
+	case BINDPARAM:
+		if a.peekToken.Type != INT {
+			return nil, a.errorf("expected position after $, got %s", a.peekToken.Literal)
+		}
+		a.NextToken()
+		param.Position, err = strconv.Atoi(a.currentToken.Literal)
+		if err != nil || param.Position < 1 {
+			return nil, a.errorf("invalid bind param: $%s", a.currentToken.Literal)
+		}
+		if len(a.namedParams) > 0 {
+			return nil, a.errorf("cannot mix $%d with named params", param.Position)
+		}
+		a.lastParam = max(a.lastParam, param.Position)
+	case PLACEHOLDER:
+		param.Position = a.lastParam + 1
+		if a.currentToken.Literal != "" {
+			param.Position, err = strconv.Atoi(a.currentToken.Literal)
+			if err != nil || param.Position < 1 {
+				return nil, a.errorf("invalid bind param: ?%s", a.currentToken.Literal)
 			}
 		}
-		sb.WriteString(" ")
+		if len(a.namedParams) > 0 {
+			return nil, a.errorf("cannot mix ?%s with named params", a.currentToken.Literal)
+		}
+		a.lastParam = max(a.lastParam, param.Position)
+	case NAMED_PARAM:
+		param.Name = a.currentToken.Literal
+		param.Position, err = a.namedParam(param.Name)
+	default:
+		// sqlc.arg(name) or sqlc.slice(name)
+		a.NextToken() // consume sqlc
+		a.NextToken() // consume .
+		macro := strings.ToLower(a.currentToken.Literal)
+		if macro != "arg" && macro != "slice" {
+			return nil, a.errorf("unknown sqlc macro sqlc.%s, wanted sqlc.arg or sqlc.slice", a.currentToken.Literal)
+		}
+		a.NextToken()
+		if err := a.expect(LPAREN); err != nil {
+			return nil, err
+		}
+		if !a.isIdent() && a.currentToken.Type != STRING {
+			return nil, a.errorf("expected param name in sqlc.%s, got %s", macro, a.currentToken.Literal)
+		}
+		param.Name = a.currentToken.Literal
+		param.Slice = macro == "slice"
+		if a.peekToken.Type != RPAREN {
+			return nil, a.errorAt(a.peekToken, "expected ) after sqlc.%s(%s, got %s", macro, param.Name, a.peekToken.Literal)
+		}
+		a.NextToken()
+		param.Position, err = a.namedParam(param.Name)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	param.Span = types.Span{Pos: start.Pos, End: a.currentToken.End}
+	return param, nil
+}
 
-	if stmt.Limit > 0 {
-		sb.WriteString(fmt.Sprintf("LIMIT %d ", stmt.Limit))
+// namedParam returns the position of a named param, giving it the next free
+// one the first time the name is seen
+func (a *Ast) namedParam(name string) (int, error) {
+	if position, ok := a.namedParams[name]; ok {
+		return position, nil
 	}
+	if a.lastParam > 0 && len(a.namedParams) == 0 {
+		return 0, a.errorf("cannot mix named param %s with positional params", name)
+	}
+	if a.namedParams == nil {
+		a.namedParams = make(map[string]int)
+	}
+	a.lastParam++
+	a.namedParams[name] = a.lastParam
+	return a.lastParam, nil
+}
 
-	if stmt.Offset > 0 {
-		sb.WriteString(fmt.Sprintf("OFFSET %d ", stmt.Offset))
+// parseLiteral reads the literal at the current token, a sign in front of a
+// number is folded into it. ok is false when there is no literal.
+func (a *Ast) parseLiteral() (string, types.LiteralKind, bool) {
+	switch a.currentToken.Type {
+	case STRING:
+		return a.currentToken.Literal, types.StringLiteral, true
+	case INT:
+		return a.currentToken.Literal, types.IntLiteral, true
+	case FLOAT:
+		return a.currentToken.Literal, types.FloatLiteral, true
+	case TRUE, FALSE:
+		return strings.ToLower(a.currentToken.Literal), types.BoolLiteral, true
+	case NULL:
+		return "NULL", types.NullLiteral, true
+	case MINUS, PLUS:
+		if a.peekToken.Type != INT && a.peekToken.Type != FLOAT {
+			return "", "", false
+		}
+		sign := ""
+		if a.currentToken.Type == MINUS {
+			sign = "-"
+		}
+		a.NextToken()
+		value, kind, _ := a.parseLiteral()
+		return sign + value, kind, true
 	}
 
-	return strings.TrimSpace(sb.String()) + ";"
+	return "", "", false
+}
+
+// expect checks the current token type and moves past it
+func (a *Ast) expect(expected TokenType) error {
+	if a.currentToken.Type != expected {
+		return a.errorf("expected %s, got %s", expected, a.currentToken.Type)
+	}
+	a.NextToken()
+
+	return nil
+}
+
+// expectEnd checks the statement ends at the current token
+func (a *Ast) expectEnd() error {
+	if a.currentToken.Type != SEMICOLON && a.currentToken.Type != EOF {
+		return a.errorf("unexpected %s at end of statement", a.currentToken.Literal)
+	}
+	return nil
+}
+
+func (a *Ast) String() string {
+	var out strings.Builder
+	if len(a.Statements) == 0 {
+		return ""
+	}
+
+	switch stmt := a.Statements[0].(type) {
+	case *types.SelectStatement:
+		out.WriteString(stringifySelectStatement(stmt))
+	case *types.InsertStatement:
+		out.WriteString(stringifyInsertStatement(stmt))
+	case *types.UpdateStatement:
+		out.WriteString(stringifyUpdateStatement(stmt))
+	case *types.DeleteStatement:
+		out.WriteString(stringifyDeleteStatement(stmt))
+	}
+
+	return out.String()
+}
+
+func stringifySelectStatement(stmt *types.SelectStatement) string {
+	return stmt.SQL(Generic.Placeholder) + ";"
 }
 
 func stringifyInsertStatement(stmt *types.InsertStatement) string {
@@ -371,13 +895,13 @@ func stringifyInsertStatement(stmt *types.InsertStatement) string {
 		sb.WriteString(" ")
 	}
 	sb.WriteString("INTO ")
-	sb.WriteString(string(stmt.TableName))
+	sb.WriteString(types.QuoteIdent(stmt.TableName, stmt.TableQuoted))
 	sb.WriteString(" ")
 
 	if len(stmt.Columns) > 0 {
 		sb.WriteString("(")
 		for i, col := range stmt.Columns {
-			sb.WriteString(string(col))
+			sb.WriteString(types.QuoteIdent(col, i < len(stmt.ColumnQuoted) && stmt.ColumnQuoted[i]))
 			if i < len(stmt.Columns)-1 {
 				sb.WriteString(", ")
 			}
@@ -403,12 +927,7 @@ func stringifyInsertStatement(stmt *types.InsertStatement) string {
 				sb.WriteString(fmt.Sprintf("$%d", bind.Position))
 			}
 		} else if bind.Value != nil {
-			// Quote strings but not numbers
-			if _, err := strconv.Atoi(*bind.Value); err == nil {
-				sb.WriteString(*bind.Value)
-			} else {
-				sb.WriteString(fmt.Sprintf("'%s'", *bind.Value))
-			}
+			sb.WriteString(literalSQL(bind))
 		} else {
 			sb.WriteString("NULL")
 		}
@@ -431,3 +950,51 @@ func stringifyInsertStatement(stmt *types.InsertStatement) string {
 
 	return strings.TrimSpace(sb.String()) + ";"
 }
+
+// literalSQL renders a literal bind, binds built without a kind quote
+// everything but integers
+func literalSQL(bind types.Bind) string {
+	if bind.Kind == "" {
+		if _, err := strconv.Atoi(*bind.Value); err == nil {
+			bind.Kind = types.IntLiteral
+		}
+	}
+	return bind.LiteralSQL()
+}
+
+func stringifyUpdateStatement(stmt *types.UpdateStatement) string {
+	var sb strings.Builder
+
+	sb.WriteString("UPDATE ")
+	sb.WriteString(stmt.TableName)
+	sb.WriteString(" SET ")
+	for i, set := range stmt.Sets {
+		sb.WriteString(set.Column)
+		sb.WriteString(" = ")
+		sb.WriteString(set.Value.SQL(Generic.Placeholder))
+		if i < len(stmt.Sets)-1 {
+			sb.WriteString(", ")
+		}
+	}
+
+	if stmt.Where != nil {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(stmt.Where.SQL(Generic.Placeholder))
+	}
+
+	return sb.String() + ";"
+}
+
+func stringifyDeleteStatement(stmt *types.DeleteStatement) string {
+	var sb strings.Builder
+
+	sb.WriteString("DELETE FROM ")
+	sb.WriteString(stmt.TableName)
+
+	if stmt.Where != nil {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(stmt.Where.SQL(Generic.Placeholder))
+	}
+
+	return sb.String() + ";"
+}
@@ -2,7 +2,7 @@ package parser
 
 import (
 	"fmt"
-	"strings"
+	"shogunc/internal/types"
 	"time"
 )
 
@@ -15,28 +15,43 @@ const (
 	COMMENT TokenType = "COMMENT"
 
 	// Identifiers + Literals
-	IDENT     TokenType = "IDENT" // foobar
-	DEFAULT   TokenType = "DEFAULT"
-	UUID      TokenType = "UUID"
-	INT       TokenType = "INT" // 12345
-	BIGINT    TokenType = "BIGINT"
-	SMALLINT  TokenType = "SMALLINT"
-	DECIMAL   TokenType = "DECIMAL"
-	VARCHAR   TokenType = "VARCHAR"
-	TEXT      TokenType = "TEXT"
-	BOOLEAN   TokenType = "BOOLEAN"
-	TIMESTAMP TokenType = "TIMESTAMP"
-	DATE      TokenType = "DATE"
+	IDENT        TokenType = "IDENT"        // foobar
+	QUOTED_IDENT TokenType = "QUOTED_IDENT" // "FooBar" or `FooBar`
+	DEFAULT      TokenType = "DEFAULT"
+	UUID         TokenType = "UUID"
+	INT          TokenType = "INT"   // 12345 or 0x1F
+	FLOAT        TokenType = "FLOAT" // 3.14 or 1e6
+	BIGINT       TokenType = "BIGINT"
+	SMALLINT     TokenType = "SMALLINT"
+	DECIMAL      TokenType = "DECIMAL"
+	VARCHAR      TokenType = "VARCHAR"
+	TEXT         TokenType = "TEXT"
+	BOOLEAN      TokenType = "BOOLEAN"
+	TIMESTAMP    TokenType = "TIMESTAMP"
+	DATE         TokenType = "DATE"
 	// Input Identifier
 	BINDPARAM   TokenType = "$"
 	PLACEHOLDER TokenType = "PLACEHOLDER"
+	NAMED_PARAM TokenType = "NAMED_PARAM" // @name or :name, the literal is the name
 
 	// Operators
-	ASSIGN  TokenType = "="
-	ASTERIK TokenType = "*"
+	ASSIGN      TokenType = "="
+	ASTERIK     TokenType = "*"
+	SLASH       TokenType = "/"
+	PERCENT     TokenType = "%"
+	PLUS        TokenType = "+"
+	MINUS       TokenType = "-"
+	LT          TokenType = "<"
+	GT          TokenType = ">"
+	LTE         TokenType = "<="
+	GTE         TokenType = ">="
+	NOT_EQ      TokenType = "!=" // != or <>, the literal keeps which one
+	CONCAT      TokenType = "||"
+	DOUBLECOLON TokenType = "::"
 
 	// Delimiters
 	COMMA     TokenType = ","
+	DOT       TokenType = "."
 	SEMICOLON TokenType = ";"
 	LPAREN    TokenType = "("
 	RPAREN    TokenType = ")"
@@ -78,39 +93,71 @@ const (
 	UNIQUE   TokenType = "UNIQUE"
 
 	// Condition & Logical
-	AND    TokenType = "AND"
-	OR     TokenType = "OR"
-	NOT    TokenType = "NOT"
-	NULL   TokenType = "NULL"
-	ASC    TokenType = "ASC"
-	DESC   TokenType = "DESC"
-	HAVING TokenType = "HAVING"
-	INNER  TokenType = "INNER"
-	LEFT   TokenType = "LEFT"
-	RIGHT  TokenType = "RIGHT"
-	ON     TokenType = "ON"
-	AS     TokenType = "AS"
-	IN     TokenType = "IN"
-	IS     TokenType = "IS"
-	TRUE   TokenType = "TRUE"
-	FALSE  TokenType = "FALSE"
-	UNION  TokenType = "UNION"
-	ALL    TokenType = "ALL"
-	EXISTS TokenType = "EXISTS"
-	CASE   TokenType = "CASE"
-	WHEN   TokenType = "WHEN"
-	THEN   TokenType = "THEN"
-	ELSE   TokenType = "ELSE"
-	END    TokenType = "END"
-	ADD    TokenType = "ADD"
+	AND     TokenType = "AND"
+	OR      TokenType = "OR"
+	NOT     TokenType = "NOT"
+	NULL    TokenType = "NULL"
+	ASC     TokenType = "ASC"
+	DESC    TokenType = "DESC"
+	NULLS   TokenType = "NULLS"
+	FIRST   TokenType = "FIRST"
+	LAST    TokenType = "LAST"
+	HAVING  TokenType = "HAVING"
+	INNER   TokenType = "INNER"
+	LEFT    TokenType = "LEFT"
+	RIGHT   TokenType = "RIGHT"
+	FULL    TokenType = "FULL"
+	CROSS   TokenType = "CROSS"
+	OUTER   TokenType = "OUTER"
+	ON      TokenType = "ON"
+	USING   TokenType = "USING"
+	AS      TokenType = "AS"
+	IN      TokenType = "IN"
+	IS      TokenType = "IS"
+	LIKE    TokenType = "LIKE"
+	ILIKE   TokenType = "ILIKE"
+	BETWEEN TokenType = "BETWEEN"
+	TRUE    TokenType = "TRUE"
+	FALSE   TokenType = "FALSE"
+	UNION   TokenType = "UNION"
+	ALL     TokenType = "ALL"
+	EXISTS  TokenType = "EXISTS"
+	CASE    TokenType = "CASE"
+	CAST    TokenType = "CAST"
+	WHEN    TokenType = "WHEN"
+	THEN    TokenType = "THEN"
+	ELSE    TokenType = "ELSE"
+	END     TokenType = "END"
+	ADD     TokenType = "ADD"
+
+	// Common table expressions
+	WITH      TokenType = "WITH"
+	RECURSIVE TokenType = "RECURSIVE"
+
+	// Compound selects, next to UNION
+	INTERSECT TokenType = "INTERSECT"
+	EXCEPT    TokenType = "EXCEPT"
+
+	// Window functions
+	OVER      TokenType = "OVER"
+	PARTITION TokenType = "PARTITION"
+	ROWS      TokenType = "ROWS"
+	RANGE     TokenType = "RANGE"
+	GROUPS    TokenType = "GROUPS"
+	UNBOUNDED TokenType = "UNBOUNDED"
+	PRECEDING TokenType = "PRECEDING"
+	FOLLOWING TokenType = "FOLLOWING"
+	CURRENT   TokenType = "CURRENT"
+	ROW       TokenType = "ROW"
 )
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	types.Span
 }
 
-func CreateToken(token TokenType, char byte) Token {
+func CreateToken(token TokenType, char rune) Token {
 	return Token{
 		Type:    token,
 		Literal: string(char),
@@ -146,21 +193,47 @@ var keyWords = map[string]TokenType{
 	"BY":        BY,
 	"ASC":       ASC,
 	"DESC":      DESC,
+	"NULLS":     NULLS,
+	"FIRST":     FIRST,
+	"LAST":      LAST,
 	"GROUP":     GROUP,
 	"HAVING":    HAVING,
 	"JOIN":      JOIN,
 	"INNER":     INNER,
 	"LEFT":      LEFT,
 	"RIGHT":     RIGHT,
+	"FULL":      FULL,
+	"CROSS":     CROSS,
+	"OUTER":     OUTER,
 	"ON":        ON,
+	"USING":     USING,
 	"AS":        AS,
 	"IN":        IN,
 	"IS":        IS,
+	"LIKE":      LIKE,
+	"ILIKE":     ILIKE,
+	"BETWEEN":   BETWEEN,
+	"DISTINCT":  DISTINCT,
 	"TRUE":      TRUE,
 	"FALSE":     FALSE,
 	"UNION":     UNION,
 	"ALL":       ALL,
+	"INTERSECT": INTERSECT,
+	"EXCEPT":    EXCEPT,
+	"OVER":      OVER,
+	"PARTITION": PARTITION,
+	"ROWS":      ROWS,
+	"RANGE":     RANGE,
+	"GROUPS":    GROUPS,
+	"UNBOUNDED": UNBOUNDED,
+	"PRECEDING": PRECEDING,
+	"FOLLOWING": FOLLOWING,
+	"CURRENT":   CURRENT,
+	"ROW":       ROW,
 	"EXISTS":    EXISTS,
+	"CAST":      CAST,
+	"WITH":      WITH,
+	"RECURSIVE": RECURSIVE,
 	"CASE":      CASE,
 	"WHEN":      WHEN,
 	"THEN":      THEN,
@@ -188,43 +261,58 @@ func LookupIdent(ident string) TokenType {
 	return IDENT
 }
 
-func IsConditional(conditional string) bool {
-	ops := map[string]struct{}{
-		"=":  {},
-		"!=": {},
-		"<>": {},
-		">":  {},
-		">=": {},
-		"<":  {},
-		"<=": {},
-	}
-
-	strFormat := strings.TrimSpace(conditional)
-	_, ok := ops[strFormat]
-	return ok
-}
-
-func IsLogicalOperator(op string) bool {
-	switch strings.ToUpper(strings.TrimSpace(op)) {
-	case "AND", "OR", "NOT", "IN", "LIKE", "BETWEEN", "IS":
+// IsComparisonToken reports whether tokType compares two values
+func IsComparisonToken(tokType TokenType) bool {
+	switch tokType {
+	case ASSIGN, LT, GT, LTE, GTE, NOT_EQ:
 		return true
-
 	default:
 		return false
 	}
 }
 
+// dbTypes maps the type names every dialect accepts to the database type
+// they are read as
 var dbTypes = map[string]TokenType{
-	"UUID":      UUID,
-	"TEXT":      TEXT,
-	"VARCHAR":   VARCHAR,
-	"INT":       INT,
-	"BIGINT":    BIGINT,
-	"SMALLINT":  SMALLINT,
-	"DECIMAL":   DECIMAL,
-	"BOOLEAN":   BOOLEAN,
-	"TIMESTAMP": TIMESTAMP,
-	"DATE":      DATE,
+	"UUID":              UUID,
+	"TEXT":              TEXT,
+	"VARCHAR":           VARCHAR,
+	"CHAR":              VARCHAR,
+	"CHARACTER":         VARCHAR,
+	"CHARACTER VARYING": VARCHAR,
+	"INT":               INT,
+	"INTEGER":           INT,
+	"BIGINT":            BIGINT,
+	"SMALLINT":          SMALLINT,
+	"DECIMAL":           DECIMAL,
+	"NUMERIC":           DECIMAL,
+	"REAL":              DECIMAL,
+	"FLOAT":             DECIMAL,
+	"DOUBLE PRECISION":  DECIMAL,
+	"BOOLEAN":           BOOLEAN,
+	"TIMESTAMP":         TIMESTAMP,
+	"DATE":              DATE,
+}
+
+var postgresTypes = map[string]TokenType{
+	"INT2":                        SMALLINT,
+	"INT4":                        INT,
+	"INT8":                        BIGINT,
+	"SMALLSERIAL":                 SMALLINT,
+	"SERIAL":                      INT,
+	"BIGSERIAL":                   BIGINT,
+	"FLOAT4":                      DECIMAL,
+	"FLOAT8":                      DECIMAL,
+	"BOOL":                        BOOLEAN,
+	"TIMESTAMPTZ":                 TIMESTAMP,
+	"TIMESTAMP WITH TIME ZONE":    TIMESTAMP,
+	"TIMESTAMP WITHOUT TIME ZONE": TIMESTAMP,
+}
+
+var sqliteTypes = map[string]TokenType{
+	"DOUBLE":   DECIMAL,
+	"CLOB":     TEXT,
+	"DATETIME": TIMESTAMP,
 }
 
 func IsDatabaseType(t string) bool {
@@ -244,6 +332,9 @@ func IsDatabaseTypeToken(tokType TokenType) bool {
 }
 
 func IsNowCompatible(tok Token) bool {
+	if tok.Type == TIMESTAMP || tok.Type == DATE {
+		return true
+	}
 	switch tok.Literal {
 	case "TIMESTAMP", "TIMESTAMPZ", "DATE", "TIME":
 		return true
@@ -263,17 +354,24 @@ func SqlNow(tok Token) string {
 	return time.Now().String()
 }
 
+// SqlToGoType maps a database type token, or a column type written as one of
+// the names every dialect accepts, to its Go type. An ENUM maps to its name.
 func SqlToGoType(tok Token) (string, error) {
-	switch tok.Literal {
-	case "TEXT", "VARCHAR", "UUID":
+	tokType := tok.Type
+	if !IsDatabaseTypeToken(tokType) {
+		tokType = dbTypes[tok.Literal]
+	}
+
+	switch tokType {
+	case TEXT, VARCHAR, UUID:
 		return "string", nil
-	case "INT", "BIGINT", "SMALLINT":
+	case INT, BIGINT, SMALLINT:
 		return "int", nil
-	case "DECIMAL":
+	case DECIMAL:
 		return "float64", nil
-	case "BOOLEAN":
+	case BOOLEAN:
 		return "bool", nil
-	case "TIMESTAMP", "DATE":
+	case TIMESTAMP, DATE:
 		return "time.Time", nil
 	}
 
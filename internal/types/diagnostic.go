@@ -0,0 +1,82 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Position is a location in a source file. Line and Column are 1-based and
+// Column counts runes, Offset is the byte offset from the start of the file.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+	Offset int
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return p.File
+	}
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Span is the source range covered by a token or node, End points just past it
+type Span struct {
+	Pos Position
+	End Position
+}
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// Diagnostic is an error or warning tied to a span of source text
+type Diagnostic struct {
+	Span
+	Severity Severity
+	Msg      string
+}
+
+func Errorf(span Span, format string, args ...any) *Diagnostic {
+	return &Diagnostic{Span: span, Msg: fmt.Sprintf(format, args...)}
+}
+
+func Warnf(span Span, format string, args ...any) *Diagnostic {
+	return &Diagnostic{Span: span, Severity: SeverityWarning, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (d *Diagnostic) Error() string {
+	if loc := d.Pos.String(); loc != "" {
+		return fmt.Sprintf("%s: %s", loc, d.Msg)
+	}
+	return d.Msg
+}
+
+// WrapError attaches span to err unless err already carries a position
+func WrapError(span Span, err error) error {
+	var diag *Diagnostic
+	if err == nil || errors.As(err, &diag) || !span.Pos.IsValid() {
+		return err
+	}
+	return &Diagnostic{Span: span, Msg: err.Error()}
+}
@@ -0,0 +1,558 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expr is a node of a parsed SQL expression, SQL renders it back the way it
+// was written with each param rendered by placeholder
+type Expr interface {
+	SQL(placeholder func(int) string) string
+}
+
+// BinaryExpr is Left Op Right, keyword operators are upper case
+type BinaryExpr struct {
+	Left  Expr
+	Op    string // = | <> | AND | OR | + | ||
+	Right Expr
+}
+
+// UnaryExpr is a prefix operator such as NOT or -
+type UnaryExpr struct {
+	Op      string
+	Operand Expr
+}
+
+// ParenExpr is an expression written inside parentheses
+type ParenExpr struct {
+	Inner Expr
+}
+
+// FuncCall is a call such as lower(email), Star is set for count(*)
+type FuncCall struct {
+	Name       string // As written
+	Quantifier string // DISTINCT or ALL written before the arguments, empty without
+	Args       []Expr
+	Star       bool
+	Over       *Window // Window of a window function call, nil without OVER
+	Span       Span    // Where the function name was written
+}
+
+// Window is the OVER (...) of a window function call
+type Window struct {
+	PartitionBy []Expr
+	OrderBy     []OrderTerm
+	Frame       *Frame // nil without a frame clause
+}
+
+// Frame is ROWS, RANGE or GROUPS Start, or BETWEEN Start AND End when End is
+// set
+type Frame struct {
+	Units string // ROWS, RANGE or GROUPS
+	Start FrameBound
+	End   *FrameBound
+}
+
+// FrameBound is UNBOUNDED PRECEDING, UNBOUNDED FOLLOWING, CURRENT ROW or
+// Offset PRECEDING and Offset FOLLOWING
+type FrameBound struct {
+	Offset Expr   // nil for UNBOUNDED and CURRENT ROW
+	Bound  string // UNBOUNDED PRECEDING, UNBOUNDED FOLLOWING, CURRENT ROW, PRECEDING or FOLLOWING
+}
+
+// SubqueryExpr is a parenthesized SELECT used as a value, or as the list of
+// an IN
+type SubqueryExpr struct {
+	Select *SelectStatement
+}
+
+// ExistsExpr is EXISTS (Select), NOT EXISTS is a UnaryExpr around it
+type ExistsExpr struct {
+	Select *SelectStatement
+}
+
+// CastExpr is CAST(Expr AS Type), or Expr::Type when Postfix is set
+type CastExpr struct {
+	Expr       Expr
+	Type       string // SQL type as written in upper case, VARCHAR(20), or a quoted name as written
+	TypeQuoted bool   // Type was a quoted identifier
+	TypeSpan   Span   // Where Type was written
+	Postfix    bool
+}
+
+// CaseExpr is CASE [Operand] WHEN ... THEN ... [ELSE Else] END, a searched
+// CASE has no Operand and each When is a condition
+type CaseExpr struct {
+	Operand Expr // nil for a searched CASE
+	Whens   []When
+	Else    Expr // nil without an ELSE
+	Span    Span // Where CASE was written
+}
+
+// When is WHEN Cond THEN Result, Cond is compared with the operand of a
+// simple CASE
+type When struct {
+	Cond   Expr
+	Result Expr
+}
+
+// ColumnRef is a column, Table is set when it was qualified
+type ColumnRef struct {
+	Table       string
+	Column      string
+	TableQuoted bool // Table was a quoted identifier
+	Quoted      bool // Column was a quoted identifier
+}
+
+// InExpr is Expr [NOT] IN (List...)
+type InExpr struct {
+	Expr Expr
+	List []Expr
+	Not  bool
+}
+
+// BetweenExpr is Expr [NOT] BETWEEN Low AND High
+type BetweenExpr struct {
+	Expr Expr
+	Low  Expr
+	High Expr
+	Not  bool
+}
+
+// IsNullExpr is Expr IS [NOT] NULL
+type IsNullExpr struct {
+	Expr Expr
+	Not  bool
+}
+
+// Literal is a constant written in the query
+type Literal struct {
+	Value string
+	Kind  LiteralKind
+}
+
+// Param is a bind param, Column is the column it is compared with or
+// assigned to
+type Param struct {
+	Column   string
+	Table    string // Table the column was qualified with
+	Name     string // Param name from @name, :name or sqlc.arg(name), or min_/max_ column for BETWEEN bounds
+	Position int
+	Slice    bool   // sqlc.slice(name) or the array of = ANY($1), bound to a Go slice
+	NotIn    bool   // Slice is the list of a NOT IN
+	Type     string // Go type of a param not compared with a column, int64 for LIMIT and OFFSET
+	Span     Span   // Where the param was written
+}
+
+func (e *BinaryExpr) SQL(placeholder func(int) string) string {
+	return fmt.Sprintf("%s %s %s", e.Left.SQL(placeholder), e.Op, e.Right.SQL(placeholder))
+}
+
+func (e *UnaryExpr) SQL(placeholder func(int) string) string {
+	operand := e.Operand.SQL(placeholder)
+	// - -5 written as --5 would start a comment
+	if e.Op == "NOT" || strings.HasPrefix(operand, e.Op) {
+		return e.Op + " " + operand
+	}
+	return e.Op + operand
+}
+
+func (e *ParenExpr) SQL(placeholder func(int) string) string {
+	return "(" + e.Inner.SQL(placeholder) + ")"
+}
+
+func (e *FuncCall) SQL(placeholder func(int) string) string {
+	var sql string
+	if e.Star {
+		sql = e.Name + "(*)"
+	} else {
+		args := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = arg.SQL(placeholder)
+		}
+		quantifier := ""
+		if e.Quantifier != "" {
+			quantifier = e.Quantifier + " "
+		}
+		sql = e.Name + "(" + quantifier + strings.Join(args, ", ") + ")"
+	}
+
+	if e.Over != nil {
+		sql += " OVER (" + e.Over.SQL(placeholder) + ")"
+	}
+	return sql
+}
+
+// SQL renders what is written inside the parentheses of OVER
+func (w *Window) SQL(placeholder func(int) string) string {
+	var clauses []string
+	if len(w.PartitionBy) > 0 {
+		exprs := make([]string, len(w.PartitionBy))
+		for i, expr := range w.PartitionBy {
+			exprs[i] = expr.SQL(placeholder)
+		}
+		clauses = append(clauses, "PARTITION BY "+strings.Join(exprs, ", "))
+	}
+	if len(w.OrderBy) > 0 {
+		terms := make([]string, len(w.OrderBy))
+		for i, term := range w.OrderBy {
+			terms[i] = term.SQL(placeholder)
+		}
+		clauses = append(clauses, "ORDER BY "+strings.Join(terms, ", "))
+	}
+	if w.Frame != nil {
+		frame := w.Frame.Units + " " + w.Frame.Start.SQL(placeholder)
+		if w.Frame.End != nil {
+			frame = w.Frame.Units + " BETWEEN " + w.Frame.Start.SQL(placeholder) + " AND " + w.Frame.End.SQL(placeholder)
+		}
+		clauses = append(clauses, frame)
+	}
+	return strings.Join(clauses, " ")
+}
+
+func (b FrameBound) SQL(placeholder func(int) string) string {
+	if b.Offset == nil {
+		return b.Bound
+	}
+	return b.Offset.SQL(placeholder) + " " + b.Bound
+}
+
+func (e *CastExpr) SQL(placeholder func(int) string) string {
+	typ := QuoteIdent(e.Type, e.TypeQuoted)
+	if e.Postfix {
+		return e.Expr.SQL(placeholder) + "::" + typ
+	}
+	return fmt.Sprintf("CAST(%s AS %s)", e.Expr.SQL(placeholder), typ)
+}
+
+func (e *CaseExpr) SQL(placeholder func(int) string) string {
+	var sb strings.Builder
+	sb.WriteString("CASE ")
+	if e.Operand != nil {
+		sb.WriteString(e.Operand.SQL(placeholder) + " ")
+	}
+	for _, when := range e.Whens {
+		sb.WriteString("WHEN " + when.Cond.SQL(placeholder) + " THEN " + when.Result.SQL(placeholder) + " ")
+	}
+	if e.Else != nil {
+		sb.WriteString("ELSE " + e.Else.SQL(placeholder) + " ")
+	}
+	sb.WriteString("END")
+	return sb.String()
+}
+
+func (e *SubqueryExpr) SQL(placeholder func(int) string) string {
+	return "(" + e.Select.SQL(placeholder) + ")"
+}
+
+func (e *ExistsExpr) SQL(placeholder func(int) string) string {
+	return "EXISTS (" + e.Select.SQL(placeholder) + ")"
+}
+
+// SQL renders the list, a subquery brings its own parentheses
+func (e *InExpr) SQL(placeholder func(int) string) string {
+	if len(e.List) == 1 {
+		if subquery, ok := e.List[0].(*SubqueryExpr); ok {
+			return fmt.Sprintf("%s %sIN %s", e.Expr.SQL(placeholder), notKeyword(e.Not), subquery.SQL(placeholder))
+		}
+	}
+
+	list := make([]string, len(e.List))
+	for i, item := range e.List {
+		list[i] = item.SQL(placeholder)
+	}
+	return fmt.Sprintf("%s %sIN (%s)", e.Expr.SQL(placeholder), notKeyword(e.Not), strings.Join(list, ", "))
+}
+
+func (e *BetweenExpr) SQL(placeholder func(int) string) string {
+	return fmt.Sprintf("%s %sBETWEEN %s AND %s", e.Expr.SQL(placeholder), notKeyword(e.Not), e.Low.SQL(placeholder), e.High.SQL(placeholder))
+}
+
+func (e *IsNullExpr) SQL(placeholder func(int) string) string {
+	return fmt.Sprintf("%s IS %sNULL", e.Expr.SQL(placeholder), notKeyword(e.Not))
+}
+
+// notKeyword returns the NOT written before a negated predicate
+func notKeyword(negated bool) string {
+	if negated {
+		return "NOT "
+	}
+	return ""
+}
+
+func (e *ColumnRef) SQL(placeholder func(int) string) string {
+	column := QuoteIdent(e.Column, e.Quoted)
+	if e.Table == "" {
+		return column
+	}
+	return QuoteIdent(e.Table, e.TableQuoted) + "." + column
+}
+
+// SQL renders the literal, strings are quoted with ' doubled
+func (e *Literal) SQL(placeholder func(int) string) string {
+	switch e.Kind {
+	case IntLiteral, FloatLiteral, NullLiteral:
+		return e.Value
+	case BoolLiteral:
+		return strings.ToUpper(e.Value)
+	default:
+		return "'" + strings.ReplaceAll(e.Value, "'", "''") + "'"
+	}
+}
+
+func (e *Param) SQL(placeholder func(int) string) string {
+	return placeholder(e.Position)
+}
+
+// QuoteIdent renders a name, quoted names are wrapped in " with " doubled
+func QuoteIdent(name string, quoted bool) string {
+	if !quoted {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// WalkExpr calls fn for e and every expression below it, parents first. The
+// query of EXISTS is walked as a SubqueryExpr.
+func WalkExpr(e Expr, fn func(Expr)) {
+	if e == nil {
+		return
+	}
+
+	fn(e)
+	switch node := e.(type) {
+	case *BinaryExpr:
+		WalkExpr(node.Left, fn)
+		WalkExpr(node.Right, fn)
+	case *UnaryExpr:
+		WalkExpr(node.Operand, fn)
+	case *ParenExpr:
+		WalkExpr(node.Inner, fn)
+	case *FuncCall:
+		for _, arg := range node.Args {
+			WalkExpr(arg, fn)
+		}
+		if node.Over != nil {
+			walkWindow(node.Over, fn)
+		}
+	case *CastExpr:
+		WalkExpr(node.Expr, fn)
+	case *CaseExpr:
+		WalkExpr(node.Operand, fn)
+		for _, when := range node.Whens {
+			WalkExpr(when.Cond, fn)
+			WalkExpr(when.Result, fn)
+		}
+		WalkExpr(node.Else, fn)
+	case *SubqueryExpr:
+		WalkSelect(node.Select, fn)
+	case *ExistsExpr:
+		WalkExpr(&SubqueryExpr{Select: node.Select}, fn)
+	case *InExpr:
+		WalkExpr(node.Expr, fn)
+		for _, item := range node.List {
+			WalkExpr(item, fn)
+		}
+	case *BetweenExpr:
+		WalkExpr(node.Expr, fn)
+		WalkExpr(node.Low, fn)
+		WalkExpr(node.High, fn)
+	case *IsNullExpr:
+		WalkExpr(node.Expr, fn)
+	}
+}
+
+func walkWindow(w *Window, fn func(Expr)) {
+	for _, expr := range w.PartitionBy {
+		WalkExpr(expr, fn)
+	}
+	for _, term := range w.OrderBy {
+		WalkExpr(term.Expr, fn)
+	}
+	if w.Frame != nil {
+		WalkExpr(w.Frame.Start.Offset, fn)
+		if w.Frame.End != nil {
+			WalkExpr(w.Frame.End.Offset, fn)
+		}
+	}
+}
+
+// WalkSelect calls WalkExpr for every expression of stmt in the order they
+// are written, the query of a CTE, a derived table or a compound is walked as
+// a SubqueryExpr
+func WalkSelect(stmt *SelectStatement, fn func(Expr)) {
+	for _, cte := range stmt.With {
+		WalkExpr(&SubqueryExpr{Select: cte.Select}, fn)
+	}
+	for _, column := range stmt.Columns {
+		WalkExpr(column.Expr, fn)
+	}
+	if stmt.TableSelect != nil {
+		WalkExpr(&SubqueryExpr{Select: stmt.TableSelect}, fn)
+	}
+	for _, join := range stmt.Joins {
+		if join.Table.Select != nil {
+			WalkExpr(&SubqueryExpr{Select: join.Table.Select}, fn)
+		}
+		WalkExpr(join.On, fn)
+	}
+	WalkExpr(stmt.Where, fn)
+	for _, expr := range stmt.GroupBy {
+		WalkExpr(expr, fn)
+	}
+	WalkExpr(stmt.Having, fn)
+	for _, compound := range stmt.Compounds {
+		WalkExpr(&SubqueryExpr{Select: compound.Select}, fn)
+	}
+	for _, term := range stmt.OrderBy {
+		WalkExpr(term.Expr, fn)
+	}
+	WalkExpr(stmt.Limit, fn)
+	WalkExpr(stmt.Offset, fn)
+}
+
+// RewriteExpr returns a copy of e rebuilt bottom up, fn gets each copied node
+// once its children are rewritten and returns the node to use in its place
+func RewriteExpr(e Expr, fn func(Expr) Expr) Expr {
+	if e == nil {
+		return nil
+	}
+
+	switch node := e.(type) {
+	case *BinaryExpr:
+		e = &BinaryExpr{Left: RewriteExpr(node.Left, fn), Op: node.Op, Right: RewriteExpr(node.Right, fn)}
+	case *UnaryExpr:
+		e = &UnaryExpr{Op: node.Op, Operand: RewriteExpr(node.Operand, fn)}
+	case *ParenExpr:
+		e = &ParenExpr{Inner: RewriteExpr(node.Inner, fn)}
+	case *FuncCall:
+		call := *node
+		call.Args = rewriteList(node.Args, fn)
+		call.Over = rewriteWindow(node.Over, fn)
+		e = &call
+	case *CastExpr:
+		e = &CastExpr{Expr: RewriteExpr(node.Expr, fn), Type: node.Type, TypeQuoted: node.TypeQuoted, TypeSpan: node.TypeSpan, Postfix: node.Postfix}
+	case *CaseExpr:
+		whens := make([]When, len(node.Whens))
+		for i, when := range node.Whens {
+			whens[i] = When{Cond: RewriteExpr(when.Cond, fn), Result: RewriteExpr(when.Result, fn)}
+		}
+		e = &CaseExpr{Operand: RewriteExpr(node.Operand, fn), Whens: whens, Else: RewriteExpr(node.Else, fn), Span: node.Span}
+	case *SubqueryExpr:
+		e = &SubqueryExpr{Select: RewriteSelect(node.Select, fn)}
+	case *ExistsExpr:
+		e = &ExistsExpr{Select: RewriteSelect(node.Select, fn)}
+	case *InExpr:
+		e = &InExpr{Expr: RewriteExpr(node.Expr, fn), List: rewriteList(node.List, fn), Not: node.Not}
+	case *BetweenExpr:
+		e = &BetweenExpr{Expr: RewriteExpr(node.Expr, fn), Low: RewriteExpr(node.Low, fn), High: RewriteExpr(node.High, fn), Not: node.Not}
+	case *IsNullExpr:
+		e = &IsNullExpr{Expr: RewriteExpr(node.Expr, fn), Not: node.Not}
+	}
+	return fn(e)
+}
+
+// RewriteSelect returns a copy of stmt with every expression rewritten by
+// RewriteExpr, CTEs, derived tables and compounds included
+func RewriteSelect(stmt *SelectStatement, fn func(Expr) Expr) *SelectStatement {
+	if stmt == nil {
+		return nil
+	}
+
+	rewritten := *stmt
+	rewritten.With = make([]CTE, len(stmt.With))
+	for i, cte := range stmt.With {
+		cte.Select = RewriteSelect(cte.Select, fn)
+		rewritten.With[i] = cte
+	}
+	rewritten.Columns = make([]SelectColumn, len(stmt.Columns))
+	for i, column := range stmt.Columns {
+		column.Expr = RewriteExpr(column.Expr, fn)
+		rewritten.Columns[i] = column
+	}
+	rewritten.TableSelect = RewriteSelect(stmt.TableSelect, fn)
+	rewritten.Joins = make([]Join, len(stmt.Joins))
+	for i, join := range stmt.Joins {
+		join.Table.Select = RewriteSelect(join.Table.Select, fn)
+		join.On = RewriteExpr(join.On, fn)
+		rewritten.Joins[i] = join
+	}
+	rewritten.Where = RewriteExpr(stmt.Where, fn)
+	rewritten.GroupBy = rewriteList(stmt.GroupBy, fn)
+	rewritten.Having = RewriteExpr(stmt.Having, fn)
+	rewritten.Compounds = make([]Compound, len(stmt.Compounds))
+	for i, compound := range stmt.Compounds {
+		compound.Select = RewriteSelect(compound.Select, fn)
+		rewritten.Compounds[i] = compound
+	}
+	rewritten.OrderBy = make([]OrderTerm, len(stmt.OrderBy))
+	for i, term := range stmt.OrderBy {
+		term.Expr = RewriteExpr(term.Expr, fn)
+		rewritten.OrderBy[i] = term
+	}
+	rewritten.Limit = RewriteExpr(stmt.Limit, fn)
+	rewritten.Offset = RewriteExpr(stmt.Offset, fn)
+	return &rewritten
+}
+
+func rewriteWindow(w *Window, fn func(Expr) Expr) *Window {
+	if w == nil {
+		return nil
+	}
+
+	rewritten := &Window{PartitionBy: rewriteList(w.PartitionBy, fn)}
+	for _, term := range w.OrderBy {
+		term.Expr = RewriteExpr(term.Expr, fn)
+		rewritten.OrderBy = append(rewritten.OrderBy, term)
+	}
+	if w.Frame != nil {
+		frame := *w.Frame
+		frame.Start.Offset = RewriteExpr(frame.Start.Offset, fn)
+		if frame.End != nil {
+			end := *frame.End
+			end.Offset = RewriteExpr(end.Offset, fn)
+			frame.End = &end
+		}
+		rewritten.Frame = &frame
+	}
+	return rewritten
+}
+
+func rewriteList(list []Expr, fn func(Expr) Expr) []Expr {
+	if list == nil {
+		return nil
+	}
+	rewritten := make([]Expr, len(list))
+	for i, e := range list {
+		rewritten[i] = RewriteExpr(e, fn)
+	}
+	return rewritten
+}
+
+// ExprBinds returns the params in e in the order they were written
+func ExprBinds(e Expr) []Bind {
+	var binds []Bind
+	WalkExpr(e, func(node Expr) {
+		if param, ok := node.(*Param); ok {
+			binds = append(binds, paramBind(param))
+		}
+	})
+	return binds
+}
+
+// SelectBinds returns the params of stmt and its subqueries in the order
+// they were written
+func SelectBinds(stmt *SelectStatement) []Bind {
+	var binds []Bind
+	WalkSelect(stmt, func(node Expr) {
+		if param, ok := node.(*Param); ok {
+			binds = append(binds, paramBind(param))
+		}
+	})
+	return binds
+}
+
+func paramBind(param *Param) Bind {
+	return Bind{Column: param.Column, Table: param.Table, Name: param.Name, Position: param.Position, Slice: param.Slice, NotIn: param.NotIn, Type: param.Type}
+}
@@ -1,66 +1,318 @@
 package types
 
-type LogicalOp string
+import "strings"
+
+// LiteralKind is the kind of literal a Bind holds, so codegen knows whether
+// to quote it
+type LiteralKind string
 
 const (
-	And     LogicalOp = "And"
-	Or      LogicalOp = "Or"
-	Illegal LogicalOp = ""
+	StringLiteral LiteralKind = "string"
+	IntLiteral    LiteralKind = "int"
+	FloatLiteral  LiteralKind = "float"
+	BoolLiteral   LiteralKind = "bool"
+	NullLiteral   LiteralKind = "null"
 )
 
-func ToLogicOp(op string) LogicalOp {
-	switch op {
-	case "AND":
-		return And
-	case "OR":
-		return Or
-	default:
-		return Illegal
+type Bind struct {
+	Column   string      // Column
+	Table    string      // Table the column was qualified with
+	Name     string      // Param name from @name, :name or sqlc.arg(name)
+	Position int         // $1 $2
+	Value    *string     // true | false
+	Kind     LiteralKind // Kind of Value, empty is treated as a string
+	Slice    bool        // Bound to a Go slice
+	NotIn    bool        // Slice is the list of a NOT IN
+	Type     string      // Go type of a param not compared with a column
+}
+
+// LiteralSQL renders Value as SQL text, strings are quoted with ' doubled
+func (b Bind) LiteralSQL() string {
+	if b.Value == nil {
+		return "NULL"
+	}
+	literal := Literal{Value: *b.Value, Kind: b.Kind}
+	return literal.SQL(nil)
+}
+
+type SelectStatement struct {
+	With             []CTE // Common table expressions of WITH, in the order they are written
+	Recursive        bool  // WITH RECURSIVE
+	Columns          []SelectColumn
+	Where            Expr // nil without a WHERE clause
+	GroupBy          []Expr
+	Having           Expr       // nil without a HAVING clause
+	Compounds        []Compound // Queries joined to this one with UNION, INTERSECT or EXCEPT
+	OrderBy          []OrderTerm
+	TableName        string           // First table of FROM
+	TableQuoted      bool             // TableName was a quoted identifier
+	TableSelect      *SelectStatement // First table of FROM when it is a derived table, TableName is empty
+	TableAlias       string           // Alias of the first table, empty when none was given
+	TableAliasQuoted bool
+	TableSpan        Span // Where the table name was written
+	Joins            []Join
+	Distinct         bool
+	Limit            Expr // nil without a LIMIT clause
+	Offset           Expr // nil without an OFFSET clause
+}
+
+// Compound is a query joined to the one before it with UNION, INTERSECT or
+// EXCEPT. ORDER BY, LIMIT and OFFSET belong to the first query and apply to
+// the whole result.
+type Compound struct {
+	Op     string // UNION, UNION ALL, INTERSECT, INTERSECT ALL, EXCEPT or EXCEPT ALL
+	Select *SelectStatement
+	Span   Span // Where the operator was written
+}
+
+// Ident is a name written in a list such as USING (...), Quoted when it was
+// a quoted identifier
+type Ident struct {
+	Name   string
+	Quoted bool
+}
+
+// SQL renders the name the way it was written
+func (i Ident) SQL() string {
+	return QuoteIdent(i.Name, i.Quoted)
+}
+
+// identList renders names separated by commas
+func identList(names []Ident) string {
+	sql := make([]string, len(names))
+	for i, name := range names {
+		sql[i] = name.SQL()
+	}
+	return strings.Join(sql, ", ")
+}
+
+// CTE is a common table expression, name [(columns)] AS (query)
+type CTE struct {
+	Name       string
+	NameQuoted bool
+	Columns    []Ident // Names given to the query's columns, empty when none were given
+	Select     *SelectStatement
+	Span       Span // Where the name was written
+}
+
+// SQL renders the CTE the way it was written
+func (c CTE) SQL(placeholder func(int) string) string {
+	sql := QuoteIdent(c.Name, c.NameQuoted)
+	if len(c.Columns) > 0 {
+		sql += " (" + identList(c.Columns) + ")"
+	}
+	return sql + " AS (" + c.Select.SQL(placeholder) + ")"
+}
+
+// SelectColumn is one item of a SELECT list
+type SelectColumn struct {
+	Expr        Expr   // * and t.* are a ColumnRef with Column *
+	Alias       string // Name given with AS, empty when none was given
+	AliasQuoted bool   // Alias was a quoted identifier
+}
+
+// SQL renders the column the way it was written, an alias always with AS
+func (c SelectColumn) SQL(placeholder func(int) string) string {
+	if c.Alias == "" {
+		return c.Expr.SQL(placeholder)
+	}
+	return c.Expr.SQL(placeholder) + " AS " + QuoteIdent(c.Alias, c.AliasQuoted)
+}
+
+// OrderTerm is one expression of ORDER BY
+type OrderTerm struct {
+	Expr      Expr
+	Direction string // ASC or DESC, empty when not written
+	Nulls     string // FIRST or LAST, empty when not written
+}
+
+// SQL renders the term the way it was written
+func (o OrderTerm) SQL(placeholder func(int) string) string {
+	sql := o.Expr.SQL(placeholder)
+	if o.Direction != "" {
+		sql += " " + o.Direction
+	}
+	if o.Nulls != "" {
+		sql += " NULLS " + o.Nulls
 	}
+	return sql
 }
 
-type ConditionOp string
+// JoinType is the kind of a JOIN, outer joins are written without OUTER
+type JoinType string
 
 const (
-	EQUAL       ConditionOp = "="
-	NOTEQUAL    ConditionOp = "!="
-	LESSTHAN    ConditionOp = "<"
-	GREATERTHAN ConditionOp = ">"
-	BETWEEN     ConditionOp = "BETWEEN"
-	ISNULL      ConditionOp = "IS NULL"
-	NOTNULL     ConditionOp = "IS NOT NULL"
+	InnerJoin JoinType = "INNER"
+	LeftJoin  JoinType = "LEFT"
+	RightJoin JoinType = "RIGHT"
+	FullJoin  JoinType = "FULL"
+	CrossJoin JoinType = "CROSS"
 )
 
-type Bind struct {
-	Column   string  // Column
-	Position int     // $1 $2
-	Value    *string // true | false
+// TableRef is a table named in FROM or JOIN
+type TableRef struct {
+	Name        string
+	Quoted      bool             // Name was a quoted identifier
+	Select      *SelectStatement // Query of a derived table, Name is empty
+	Alias       string           // Empty when no alias was given
+	AliasQuoted bool             // Alias was a quoted identifier
+	Span        Span             // Where the table name was written
 }
 
-type Condition struct {
-	Column   string      // Column
-	Operator ConditionOp // = | != | >
-	Value    Bind
-	ChainOp  LogicalOp // AND | OR | NOT
+// SQL renders the table, an alias always with AS
+func (t TableRef) SQL(placeholder func(int) string) string {
+	table := QuoteIdent(t.Name, t.Quoted)
+	if t.Select != nil {
+		table = "(" + t.Select.SQL(placeholder) + ")"
+	}
+	if t.Alias == "" {
+		return table
+	}
+	return table + " AS " + QuoteIdent(t.Alias, t.AliasQuoted)
 }
 
-type SelectStatement struct {
-	Columns    []string
-	Conditions []Condition
-	TableName  string
-	Distinct   bool
-	Limit      int
-	Offset     int
+// RefName is the name columns of the table are qualified with
+func (t TableRef) RefName() string {
+	if t.Alias != "" {
+		return t.Alias
+	}
+	return t.Name
+}
+
+// Join is a table joined to the ones before it in FROM
+type Join struct {
+	Type  JoinType
+	Table TableRef
+	On    Expr    // nil for CROSS and USING joins
+	Using []Ident // Columns of USING (...)
+}
+
+// SQL renders the join the way it was written
+func (j Join) SQL(placeholder func(int) string) string {
+	sql := string(j.Type) + " JOIN " + j.Table.SQL(placeholder)
+	switch {
+	case j.On != nil:
+		sql += " ON " + j.On.SQL(placeholder)
+	case len(j.Using) > 0:
+		sql += " USING (" + identList(j.Using) + ")"
+	}
+	return sql
+}
+
+// Table returns the first table of FROM
+func (s *SelectStatement) Table() TableRef {
+	return TableRef{Name: s.TableName, Quoted: s.TableQuoted, Select: s.TableSelect, Alias: s.TableAlias, AliasQuoted: s.TableAliasQuoted, Span: s.TableSpan}
+}
+
+// WithSQL renders the WITH clause, it is empty without one
+func (s *SelectStatement) WithSQL(placeholder func(int) string) string {
+	if len(s.With) == 0 {
+		return ""
+	}
+
+	ctes := make([]string, len(s.With))
+	for i, cte := range s.With {
+		ctes[i] = cte.SQL(placeholder)
+	}
+	if s.Recursive {
+		return "WITH RECURSIVE " + strings.Join(ctes, ", ")
+	}
+	return "WITH " + strings.Join(ctes, ", ")
+}
+
+// SQL renders the query the way it was written, without a trailing ;
+func (s *SelectStatement) SQL(placeholder func(int) string) string {
+	var sb strings.Builder
+
+	if with := s.WithSQL(placeholder); with != "" {
+		sb.WriteString(with + " ")
+	}
+	sb.WriteString("SELECT ")
+	if s.Distinct {
+		sb.WriteString("DISTINCT ")
+	}
+
+	columns := make([]string, len(s.Columns))
+	for i, column := range s.Columns {
+		columns[i] = column.SQL(placeholder)
+	}
+	sb.WriteString(strings.Join(columns, ", "))
+
+	sb.WriteString(" FROM " + s.Table().SQL(placeholder))
+	for _, join := range s.Joins {
+		sb.WriteString(" " + join.SQL(placeholder))
+	}
+
+	if s.Where != nil {
+		sb.WriteString(" WHERE " + s.Where.SQL(placeholder))
+	}
+
+	if len(s.GroupBy) > 0 {
+		groupBy := make([]string, len(s.GroupBy))
+		for i, expr := range s.GroupBy {
+			groupBy[i] = expr.SQL(placeholder)
+		}
+		sb.WriteString(" GROUP BY " + strings.Join(groupBy, ", "))
+	}
+
+	if s.Having != nil {
+		sb.WriteString(" HAVING " + s.Having.SQL(placeholder))
+	}
+
+	for _, compound := range s.Compounds {
+		sb.WriteString(" " + compound.Op + " " + compound.Select.SQL(placeholder))
+	}
+
+	if len(s.OrderBy) > 0 {
+		terms := make([]string, len(s.OrderBy))
+		for i, term := range s.OrderBy {
+			terms[i] = term.SQL(placeholder)
+		}
+		sb.WriteString(" ORDER BY " + strings.Join(terms, ", "))
+	}
+
+	if s.Limit != nil {
+		sb.WriteString(" LIMIT " + s.Limit.SQL(placeholder))
+	}
+	if s.Offset != nil {
+		sb.WriteString(" OFFSET " + s.Offset.SQL(placeholder))
+	}
+	return sb.String()
 }
 
 type InsertStatement struct {
 	TableName       string
+	TableQuoted     bool // TableName was a quoted identifier
+	TableSpan       Span // Where the table name was written
 	Columns         []string
+	ColumnQuoted    []bool // Whether each of Columns was a quoted identifier
 	Values          []Bind
 	ReturningFields []string
 	InsertMode      []byte
 }
 
+// Assignment is one col = value of an UPDATE's SET
+type Assignment struct {
+	Column       string
+	ColumnQuoted bool // Column was a quoted identifier
+	Value        Expr
+}
+
+type UpdateStatement struct {
+	TableName   string
+	TableQuoted bool // TableName was a quoted identifier
+	TableSpan   Span // Where the table name was written
+	Sets        []Assignment
+	Where       Expr
+}
+
+type DeleteStatement struct {
+	TableName   string
+	TableQuoted bool // TableName was a quoted identifier
+	TableSpan   Span // Where the table name was written
+	Where       Expr
+}
+
 type Type string // exec | one | many
 
 const (
@@ -77,7 +329,8 @@ type TagType struct {
 type QueryBlock struct {
 	Name     string // -name: GetUser
 	Type     Type
-	SQL      string // sql query statement
-	Filename string // for debug or error reporting
-	DataType any    // Infered type
+	SQL      string   // sql query statement
+	Filename string   // for debug or error reporting
+	Pos      Position // Where SQL starts in Filename
+	DataType any      // Infered type
 }